@@ -0,0 +1,61 @@
+// tsbs_compare compares the results files written with --results-file by the
+// loaders or the query runners. The first file is the baseline; for every
+// other file it reports the change of each metric per label, and exits with
+// a non-zero status if any got worse by more than --threshold percent.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+// Program option vars:
+var (
+	threshold float64
+	format    string
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <baseline results file> <results file>...\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+	pflag.Float64Var(&threshold, "threshold", 0,
+		"Report a regression and exit with status 1 if a metric is worse than the baseline by more than this many percent (0 = never)")
+	pflag.StringVar(&format, "format", formatTable, fmt.Sprintf("Report format: '%s' or '%s'", formatTable, formatMarkdown))
+	pflag.Parse()
+	if pflag.NArg() < 2 {
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	var runs []*run
+	for _, fileName := range pflag.Args() {
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			log.Fatalf("cannot read %s: %v", fileName, err)
+		}
+		r, err := parseRun(fileName, data)
+		if err != nil {
+			log.Fatalf("cannot parse %s: %v", fileName, err)
+		}
+		runs = append(runs, r)
+	}
+
+	rep, err := newReport(runs, threshold)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := rep.write(os.Stdout, format); err != nil {
+		log.Fatal(err)
+	}
+
+	if n := rep.regressions(); n > 0 {
+		fmt.Fprintf(os.Stderr, "%d metrics regressed by more than %.1f%%\n", n, threshold)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/query"
+)
+
+const (
+	kindLoad  = "load"
+	kindQuery = "query"
+
+	// labelTotals holds the overall rates of a run
+	labelTotals = "(totals)"
+	// labelBatchLatency holds the batch latencies of a load run
+	labelBatchLatency = "(batch latency)"
+
+	formatTable    = "table"
+	formatMarkdown = "markdown"
+)
+
+// metric is a single measurement of a run, e.g. the p99 latency of a label
+type metric struct {
+	label string
+	name  string
+	// higherIsBetter is set for rates; for latencies lower is better
+	higherIsBetter bool
+	// zeroIfMissing is set for counts only added when non-zero, e.g. the
+	// errors of a label
+	zeroIfMissing bool
+}
+
+// run holds the metrics read from one results file
+type run struct {
+	name   string
+	kind   string
+	values map[metric]float64
+}
+
+// hasLabel returns whether the run has any metric of label
+func (r *run) hasLabel(label string) bool {
+	for m := range r.values {
+		if m.label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRun reads a results file written with --results-file by a loader or
+// a query runner
+func parseRun(name string, data []byte) (*run, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	r := &run{name: name, values: make(map[metric]float64)}
+	switch {
+	case fields["Labels"] != nil:
+		var res query.QueryTestResult
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, err
+		}
+		r.kind = kindQuery
+		r.addQueryResult(&res)
+	case fields["BatchLatency"] != nil:
+		var res load.LoaderTestResult
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, err
+		}
+		r.kind = kindLoad
+		r.addLoadResult(&res)
+	default:
+		return nil, fmt.Errorf("not a load or query results file")
+	}
+	return r, nil
+}
+
+func (r *run) add(label, name string, higherIsBetter bool, v float64) {
+	r.values[metric{label: label, name: name, higherIsBetter: higherIsBetter}] = v
+}
+
+// errorKinds are the kinds of failed queries counted per label
+var errorKinds = []string{query.ErrorKindTimeout, query.ErrorKindServer, query.ErrorKindClient}
+
+func (r *run) addQueryResult(res *query.QueryTestResult) {
+	r.add(labelTotals, "queries/sec", true, res.Totals.OverallQueryRate)
+	// A run that fails more queries must not look better for it
+	r.add(labelTotals, "failed queries", false, float64(res.Totals.FailedQueries))
+	for label, ls := range res.Labels {
+		r.add(label, "mean", false, ls.Mean)
+		r.add(label, "p50", false, ls.P50)
+		r.add(label, "p95", false, ls.P95)
+		r.add(label, "p99", false, ls.P99)
+		for p, v := range ls.Percentiles {
+			r.add(label, strings.ToLower(p), false, v)
+		}
+		for _, kind := range errorKinds {
+			if n := ls.Errors[kind]; n > 0 {
+				r.values[metric{label: label, name: kind + " errors", zeroIfMissing: true}] = float64(n)
+			}
+		}
+	}
+}
+
+func (r *run) addLoadResult(res *load.LoaderTestResult) {
+	r.add(labelTotals, "metrics/sec", true, res.Totals.MetricRate)
+	r.add(labelTotals, "rows/sec", true, res.Totals.RowRate)
+	r.add(labelBatchLatency, "mean", false, res.BatchLatency.Mean)
+	r.add(labelBatchLatency, "p50", false, res.BatchLatency.P50)
+	r.add(labelBatchLatency, "p95", false, res.BatchLatency.P95)
+	r.add(labelBatchLatency, "p99", false, res.BatchLatency.P99)
+}
+
+// reportRow compares one metric of the baseline run with the other runs
+type reportRow struct {
+	metric
+	values  []float64
+	present []bool
+	// regressed marks the runs that are worse than the baseline by more
+	// than the threshold
+	regressed []bool
+}
+
+// report compares runs against the first of them, the baseline
+type report struct {
+	names []string
+	rows  []reportRow
+}
+
+// newReport compares the metrics of all runs, flagging a regression when a
+// metric of a later run is worse than the baseline by more than threshold
+// percent or missing from it, e.g. because a label was not run (0 disables
+// this)
+func newReport(runs []*run, threshold float64) (*report, error) {
+	if len(runs) < 2 {
+		return nil, fmt.Errorf("need at least two runs to compare")
+	}
+	rep := &report{}
+	metrics := make(map[metric]bool)
+	for _, r := range runs {
+		if r.kind != runs[0].kind {
+			return nil, fmt.Errorf("cannot compare %s run %s with %s run %s", r.kind, r.name, runs[0].kind, runs[0].name)
+		}
+		rep.names = append(rep.names, r.name)
+		for m := range r.values {
+			metrics[m] = true
+		}
+	}
+
+	for m := range metrics {
+		row := reportRow{
+			metric:    m,
+			values:    make([]float64, len(runs)),
+			present:   make([]bool, len(runs)),
+			regressed: make([]bool, len(runs)),
+		}
+		for i, r := range runs {
+			row.values[i], row.present[i] = r.values[m]
+			if !row.present[i] && m.zeroIfMissing && r.hasLabel(m.label) {
+				row.present[i] = true
+			}
+			if i > 0 && threshold > 0 && row.present[0] {
+				row.regressed[i] = !row.present[i] || row.worsePercent(i) > threshold
+			}
+		}
+		rep.rows = append(rep.rows, row)
+	}
+	sort.Slice(rep.rows, func(i, j int) bool {
+		a, b := rep.rows[i], rep.rows[j]
+		if a.label != b.label {
+			return a.label < b.label
+		}
+		return a.name < b.name
+	})
+	return rep, nil
+}
+
+// deltaPercent is the change of run i relative to the baseline in percent
+func (r reportRow) deltaPercent(i int) float64 {
+	if r.values[0] == 0 {
+		if r.values[i] == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (r.values[i] - r.values[0]) / r.values[0] * 100
+}
+
+// worsePercent is how much worse run i is than the baseline in percent,
+// negative if it is better
+func (r reportRow) worsePercent(i int) float64 {
+	if r.higherIsBetter {
+		return -r.deltaPercent(i)
+	}
+	return r.deltaPercent(i)
+}
+
+// regressions returns the number of regressed metrics over all runs
+func (rep *report) regressions() int {
+	n := 0
+	for _, row := range rep.rows {
+		for _, r := range row.regressed {
+			if r {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// cells returns the cells of a row: label, metric, the baseline value and
+// the values of the other runs with their deltas
+func (r reportRow) cells() []string {
+	cells := []string{r.label, r.name}
+	for i := range r.values {
+		if !r.present[i] {
+			cell := "-"
+			if r.regressed[i] {
+				cell += " REGRESSION"
+			}
+			cells = append(cells, cell)
+			continue
+		}
+		cell := fmt.Sprintf("%.2f", r.values[i])
+		if i > 0 && r.present[0] {
+			cell += fmt.Sprintf(" (%+.1f%%)", r.deltaPercent(i))
+			if r.regressed[i] {
+				cell += " REGRESSION"
+			}
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+func (rep *report) header() []string {
+	return append([]string{"label", "metric"}, rep.names...)
+}
+
+// write writes the report as an aligned text table or a markdown table
+func (rep *report) write(w io.Writer, format string) error {
+	switch format {
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(rep.header(), "\t"))
+		for _, row := range rep.rows {
+			fmt.Fprintln(tw, strings.Join(row.cells(), "\t"))
+		}
+		return tw.Flush()
+	case formatMarkdown:
+		header := rep.header()
+		separator := make([]string, len(header))
+		for i := range separator {
+			separator[i] = "---"
+		}
+		lines := []string{markdownRow(header), markdownRow(separator)}
+		for _, row := range rep.rows {
+			lines = append(lines, markdownRow(row.cells()))
+		}
+		_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+		return err
+	default:
+		return fmt.Errorf("unknown format '%s': must be %s or %s", format, formatTable, formatMarkdown)
+	}
+}
+
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = strings.Replace(c, "|", "\\|", -1)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
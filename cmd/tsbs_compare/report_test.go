@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/query"
+)
+
+func queryRun(t *testing.T, name string, rate, p99 float64) *run {
+	res := query.QueryTestResult{
+		Totals: query.QueryTotals{OverallQueryRate: rate},
+		Labels: map[string]query.LatencySummary{
+			"lastpoint": {Mean: p99 / 2, P50: p99 / 2, P95: p99, P99: p99},
+		},
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := parseRun(name, data)
+	if err != nil {
+		t.Fatalf("cannot parse query run: %v", err)
+	}
+	return r
+}
+
+func TestParseRun(t *testing.T) {
+	r := queryRun(t, "a", 100, 10)
+	if r.kind != kindQuery {
+		t.Errorf("incorrect kind: got %s want %s", r.kind, kindQuery)
+	}
+	if got := r.values[metric{label: labelTotals, name: "queries/sec", higherIsBetter: true}]; got != 100 {
+		t.Errorf("incorrect query rate: got %f want %f", got, 100.0)
+	}
+	if got := r.values[metric{label: "lastpoint", name: "p99"}]; got != 10 {
+		t.Errorf("incorrect p99: got %f want %f", got, 10.0)
+	}
+
+	data, err := json.Marshal(load.LoaderTestResult{Totals: load.LoaderTotals{MetricRate: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = parseRun("b", data)
+	if err != nil {
+		t.Fatalf("cannot parse load run: %v", err)
+	}
+	if r.kind != kindLoad {
+		t.Errorf("incorrect kind: got %s want %s", r.kind, kindLoad)
+	}
+	if got := r.values[metric{label: labelTotals, name: "metrics/sec", higherIsBetter: true}]; got != 5 {
+		t.Errorf("incorrect metric rate: got %f want %f", got, 5.0)
+	}
+
+	if _, err := parseRun("c", []byte(`{"foo": 1}`)); err == nil {
+		t.Errorf("unexpected lack of error for unknown results")
+	}
+	if _, err := parseRun("c", []byte(`not json`)); err == nil {
+		t.Errorf("unexpected lack of error for invalid json")
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	base := queryRun(t, "base", 100, 10)
+	// slower queries, but only 5% lower rate
+	other := queryRun(t, "other", 95, 12)
+
+	rep, err := newReport([]*run{base, other}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	regressed := map[string]bool{}
+	for _, row := range rep.rows {
+		if row.regressed[1] {
+			regressed[row.name] = true
+		}
+	}
+	for _, name := range []string{"mean", "p50", "p95", "p99"} {
+		if !regressed[name] {
+			t.Errorf("%s did not regress", name)
+		}
+	}
+	if regressed["queries/sec"] {
+		t.Errorf("queries/sec regressed below the threshold")
+	}
+	if got := rep.regressions(); got != 4 {
+		t.Errorf("incorrect number of regressions: got %d want %d", got, 4)
+	}
+
+	// no regressions without a threshold
+	rep, err = newReport([]*run{base, other}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := rep.regressions(); got != 0 {
+		t.Errorf("regressions without threshold: got %d", got)
+	}
+
+	// a faster run does not regress
+	rep, err = newReport([]*run{base, queryRun(t, "faster", 200, 5)}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := rep.regressions(); got != 0 {
+		t.Errorf("regressions for faster run: got %d", got)
+	}
+
+	if _, err := newReport([]*run{base}, 10); err == nil {
+		t.Errorf("unexpected lack of error for a single run")
+	}
+	loadRun := &run{name: "load", kind: kindLoad}
+	if _, err := newReport([]*run{base, loadRun}, 10); err == nil {
+		t.Errorf("unexpected lack of error for different kinds of runs")
+	}
+}
+
+func TestNewReportFailuresAndMissingLabels(t *testing.T) {
+	base := queryRun(t, "base", 100, 10)
+	base.add("high-cpu-1", "p99", false, 20)
+
+	// faster, but fails queries and does not run high-cpu-1 at all
+	res := query.QueryTestResult{
+		Totals: query.QueryTotals{OverallQueryRate: 200, FailedQueries: 3},
+		Labels: map[string]query.LatencySummary{
+			"lastpoint": {
+				Mean: 5, P50: 5, P95: 10, P99: 10,
+				Errors: map[string]int64{query.ErrorKindTimeout: 3},
+			},
+		},
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := parseRun("other", data)
+	if err != nil {
+		t.Fatalf("cannot parse query run: %v", err)
+	}
+
+	rep, err := newReport([]*run{base, other}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		label string
+		name  string
+		want  bool
+	}{
+		{label: labelTotals, name: "queries/sec", want: false},
+		{label: labelTotals, name: "failed queries", want: true},
+		{label: "lastpoint", name: "timeout errors", want: true},
+		{label: "lastpoint", name: "p99", want: false},
+		{label: "high-cpu-1", name: "p99", want: true},
+	}
+	// Rows of errors exist only if a run has any
+	for _, name := range []string{"server errors", "client errors"} {
+		for _, row := range rep.rows {
+			if row.label == "lastpoint" && row.name == name {
+				t.Errorf("unexpected row of %s without any", name)
+			}
+		}
+	}
+	for _, c := range cases {
+		found := false
+		for _, row := range rep.rows {
+			if row.label != c.label || row.name != c.name {
+				continue
+			}
+			found = true
+			if got := row.regressed[1]; got != c.want {
+				t.Errorf("%s %s: incorrect regression: got %v want %v", c.label, c.name, got, c.want)
+			}
+		}
+		if !found {
+			t.Errorf("%s %s: missing row", c.label, c.name)
+		}
+	}
+	if got := rep.regressions(); got != 3 {
+		t.Errorf("incorrect number of regressions: got %d want %d", got, 3)
+	}
+
+	// The baseline without timeouts has none, one that timed out improves
+	rep, err = newReport([]*run{other, base}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, row := range rep.rows {
+		if row.label == "lastpoint" && row.name == "timeout errors" {
+			if !row.present[1] || row.values[1] != 0 || row.regressed[1] {
+				t.Errorf("incorrect timeouts of a run without any: got %v present %v regressed %v", row.values[1], row.present[1], row.regressed[1])
+			}
+		}
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	base := queryRun(t, "base", 100, 10)
+	other := queryRun(t, "other", 100, 20)
+	delete(other.values, metric{label: "lastpoint", name: "p95"})
+	rep, err := newReport([]*run{base, other}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.write(&buf, formatMarkdown); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want := "| label | metric | base | other |"; lines[0] != want {
+		t.Errorf("incorrect header: got %s want %s", lines[0], want)
+	}
+	wantLines := []string{
+		"| (totals) | queries/sec | 100.00 | 100.00 (+0.0%) |",
+		"| lastpoint | p95 | 10.00 | - REGRESSION |",
+		"| lastpoint | p99 | 10.00 | 20.00 (+100.0%) REGRESSION |",
+	}
+	for _, want := range wantLines {
+		found := false
+		for _, l := range lines {
+			if l == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing line %s in:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := rep.write(&buf, formatTable); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "label") {
+		t.Errorf("incorrect table:\n%s", buf.String())
+	}
+
+	if err := rep.write(&buf, "html"); err == nil {
+		t.Errorf("unexpected lack of error for unknown format")
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"bytes"
+	"math/rand"
 	"testing"
 )
 
@@ -20,9 +21,10 @@ func TestRandomByteSliceChoice(t *testing.T) {
 		[]byte("bar"),
 		[]byte("baz"),
 	}
+	r := rand.New(rand.NewSource(123))
 	// One million attempts ought to catch it?
 	for i := 0; i < 1000000; i++ {
-		choice := RandomByteStringSliceChoice(arr)
+		choice := RandomByteStringSliceChoice(r, arr)
 		testIfInByteStringSlice(t, arr, choice)
 	}
 }
@@ -38,9 +40,10 @@ func testIfInInt64Slice(t *testing.T, arr []int64, choice int64) {
 
 func TestRandomInt64Choice(t *testing.T) {
 	arr := []int64{0, 10000, 9999}
+	r := rand.New(rand.NewSource(123))
 	// One million attempts ought to catch it?
 	for i := 0; i < 1000000; i++ {
-		choice := RandomInt64SliceChoice(arr)
+		choice := RandomInt64SliceChoice(r, arr)
 		testIfInInt64Slice(t, arr, choice)
 	}
 }
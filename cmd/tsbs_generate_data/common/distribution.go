@@ -15,6 +15,8 @@ type Distribution interface {
 type NormalDistribution struct {
 	Mean   float64
 	StdDev float64
+	// Rand is the source of random numbers, the global one if nil
+	Rand *rand.Rand
 
 	value float64
 }
@@ -27,10 +29,26 @@ func ND(mean, stddev float64) *NormalDistribution {
 	}
 }
 
+// WithRand returns a copy of the distribution drawing from r, so that one
+// declared for a measurement can be used by many entities.
+func (d *NormalDistribution) WithRand(r *rand.Rand) *NormalDistribution {
+	return &NormalDistribution{
+		Mean:   d.Mean,
+		StdDev: d.StdDev,
+		Rand:   r,
+	}
+}
+
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
 func (d *NormalDistribution) Advance() {
-	d.value = rand.NormFloat64()*d.StdDev + d.Mean
+	var x float64
+	if d.Rand != nil {
+		x = d.Rand.NormFloat64()
+	} else {
+		x = rand.NormFloat64()
+	}
+	d.value = x*d.StdDev + d.Mean
 }
 
 // Get returns the last computed value for this distribution.
@@ -42,6 +60,8 @@ func (d *NormalDistribution) Get() float64 {
 type UniformDistribution struct {
 	Low  float64
 	High float64
+	// Rand is the source of random numbers, the global one if nil
+	Rand *rand.Rand
 
 	value float64
 }
@@ -54,10 +74,25 @@ func UD(low, high float64) *UniformDistribution {
 	}
 }
 
+// WithRand returns a copy of the distribution drawing from r, so that one
+// declared for a measurement can be used by many entities.
+func (d *UniformDistribution) WithRand(r *rand.Rand) *UniformDistribution {
+	return &UniformDistribution{
+		Low:  d.Low,
+		High: d.High,
+		Rand: r,
+	}
+}
+
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
 func (d *UniformDistribution) Advance() {
-	x := rand.Float64() // uniform
+	var x float64 // uniform
+	if d.Rand != nil {
+		x = d.Rand.Float64()
+	} else {
+		x = rand.Float64()
+	}
 	x *= d.High - d.Low
 	x += d.Low
 	d.value = x
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"math/rand"
 	"time"
 
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
@@ -21,11 +22,11 @@ func NewSubsystemMeasurement(start time.Time, numDistributions int) *SubsystemMe
 }
 
 // NewSubsystemMeasurementWithDistributionMakers creates a new SubsystemMeasurement with start time and distribution makers
-// which are used to create the necessary distributions.
-func NewSubsystemMeasurementWithDistributionMakers(start time.Time, makers []LabeledDistributionMaker) *SubsystemMeasurement {
+// which are used to create the necessary distributions, drawing from r.
+func NewSubsystemMeasurementWithDistributionMakers(start time.Time, makers []LabeledDistributionMaker, r *rand.Rand) *SubsystemMeasurement {
 	m := NewSubsystemMeasurement(start, len(makers))
 	for i := 0; i < len(makers); i++ {
-		m.Distributions[i] = makers[i].DistributionMaker()
+		m.Distributions[i] = makers[i].DistributionMaker(r)
 	}
 	return m
 }
@@ -61,8 +62,9 @@ func (m *SubsystemMeasurement) ToPointAllInt64(p *serialize.Point, measurementNa
 	}
 }
 
-// LabeledDistributionMaker combines a distribution maker with a label.
+// LabeledDistributionMaker combines a distribution maker with a label. The
+// maker creates a distribution drawing its random numbers from r.
 type LabeledDistributionMaker struct {
 	Label             []byte
-	DistributionMaker func() Distribution
+	DistributionMaker func(r *rand.Rand) Distribution
 }
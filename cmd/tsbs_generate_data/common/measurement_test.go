@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"math/rand"
 	"strconv"
 	"strings"
 	"testing"
@@ -81,11 +82,11 @@ func TestNewSubsystemMeasurement(t *testing.T) {
 
 func TestNewSubsystemMeasurementWithDistributionMakers(t *testing.T) {
 	makers := []LabeledDistributionMaker{
-		{[]byte("foo"), func() Distribution { return &monotonicDistribution{state: 0.0} }},
-		{[]byte("bar"), func() Distribution { return &monotonicDistribution{state: 1.0} }},
+		{[]byte("foo"), func(*rand.Rand) Distribution { return &monotonicDistribution{state: 0.0} }},
+		{[]byte("bar"), func(*rand.Rand) Distribution { return &monotonicDistribution{state: 1.0} }},
 	}
 	now := time.Now()
-	m := NewSubsystemMeasurementWithDistributionMakers(now, makers)
+	m := NewSubsystemMeasurementWithDistributionMakers(now, makers, NewRand(123))
 	if !m.Timestamp.Equal(now) {
 		t.Errorf("incorrect timestamp set: got %v want %v", m.Timestamp, now)
 	}
@@ -144,9 +145,9 @@ func TestToPointAllInt64(t *testing.T) {
 
 func setupToPoint(start time.Time) (*SubsystemMeasurement, []LabeledDistributionMaker) {
 	makers := []LabeledDistributionMaker{
-		{[]byte(toPointFieldLabel), func() Distribution { return &monotonicDistribution{state: toPointState} }},
+		{[]byte(toPointFieldLabel), func(*rand.Rand) Distribution { return &monotonicDistribution{state: toPointState} }},
 	}
-	m := NewSubsystemMeasurementWithDistributionMakers(start, makers)
+	m := NewSubsystemMeasurementWithDistributionMakers(start, makers, NewRand(123))
 	m.Tick(time.Nanosecond)
 	return m, makers
 }
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// Every entity (host, truck, ...) of a simulation draws its random numbers
+// from a source of its own, seeded with EntitySeed, and every measurement of
+// an entity from one seeded with the seed of the entity and the name of the
+// measurement. So the data of an entity does not depend on the number of
+// other entities, the order they are simulated in or their other
+// measurements.
+
+// EntitySeed returns the seed of entity i of a simulation with the given seed.
+func EntitySeed(seed int64, i int) int64 {
+	return int64(mix64(uint64(seed) ^ mix64(uint64(i)+1)))
+}
+
+// NamedSeed returns the seed of the part of an entity with the given name,
+// e.g. a measurement, from the seed of the entity.
+func NamedSeed(seed int64, name []byte) int64 {
+	h := fnv.New64a()
+	h.Write(name)
+	return int64(mix64(uint64(seed) ^ h.Sum64()))
+}
+
+// NewRand returns a source of random numbers with the given seed. Unlike the
+// one of rand.NewSource it only needs a few bytes of state, so every entity
+// and measurement can have its own.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(&splitMix64{state: uint64(seed)})
+}
+
+// MeasurementRand returns the source of random numbers of the measurement
+// with the given name of an entity with the given seed.
+func MeasurementRand(seed int64, name []byte) *rand.Rand {
+	return NewRand(NamedSeed(seed, name))
+}
+
+// splitMix64 is the SplitMix64 generator, a rand.Source64
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	return mix64(s.state)
+}
+
+func (s *splitMix64) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// mix64 is the finalizer of SplitMix64, which scrambles the bits of x
+func mix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
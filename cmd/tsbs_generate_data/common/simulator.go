@@ -22,8 +22,11 @@ type BaseSimulatorConfig struct {
 	InitGeneratorScale uint64
 	// GeneratorScale is the total number of Generators to have in the last reporting period
 	GeneratorScale uint64
-	// GeneratorConstructor is the function used to create a new Generator given an id number and start time
-	GeneratorConstructor func(i int, start time.Time) Generator
+	// GeneratorConstructor is the function used to create a new Generator given an id number, start time
+	// and seed, see EntitySeed
+	GeneratorConstructor func(i int, start time.Time, seed int64) Generator
+	// Seed is the seed of the simulation from which the Generators derive theirs
+	Seed int64
 }
 
 func calculateEpochs(duration time.Duration, interval time.Duration) uint64 {
@@ -34,7 +37,7 @@ func calculateEpochs(duration time.Duration, interval time.Duration) uint64 {
 func (sc *BaseSimulatorConfig) NewSimulator(interval time.Duration, limit uint64) Simulator {
 	generators := make([]Generator, sc.GeneratorScale)
 	for i := 0; i < len(generators); i++ {
-		generators[i] = sc.GeneratorConstructor(i, sc.Start)
+		generators[i] = sc.GeneratorConstructor(i, sc.Start, EntitySeed(sc.Seed, i))
 	}
 
 	epochs := calculateEpochs(sc.End.Sub(sc.Start), interval)
@@ -47,8 +50,9 @@ func (sc *BaseSimulatorConfig) NewSimulator(interval time.Duration, limit uint64
 		madePoints: 0,
 		maxPoints:  maxPoints,
 
-		generatorIndex: 0,
-		generators:     generators,
+		generatorIndex:  0,
+		generators:      generators,
+		totalGenerators: sc.GeneratorScale,
 
 		epoch:           0,
 		epochs:          epochs,
@@ -73,6 +77,46 @@ type Simulator interface {
 	TagTypes() []reflect.Type
 }
 
+// PartitionedSimulator is a Simulator whose entities can be simulated in
+// parts, e.g. on several goroutines.
+type PartitionedSimulator interface {
+	Simulator
+	// Partition splits the entities of a simulator that has not made any
+	// points yet into at most n parts and returns a Simulator for each, with
+	// the number of points it makes per round. The points of the simulator
+	// are those of every round of the parts, in the order of the parts; the
+	// last round may end at any part. Only the parts may be used afterwards.
+	Partition(n int) (parts []Simulator, roundPoints []int)
+}
+
+// PartBounds splits count entities into at most n parts of about the same
+// size, returning the index of the first entity of every part followed by
+// count.
+func PartBounds(count uint64, n int) []uint64 {
+	if uint64(n) > count {
+		n = int(count)
+	}
+	bounds := make([]uint64, n+1)
+	for i := range bounds {
+		bounds[i] = count * uint64(i) / uint64(n)
+	}
+	return bounds
+}
+
+// PartMaxPoints returns how many of the first maxPoints points of a
+// simulation, which makes a point of each of its count entities in turn,
+// are points of the entities [lo, hi).
+func PartMaxPoints(maxPoints, count, lo, hi uint64) uint64 {
+	points := maxPoints / count * (hi - lo)
+	if rest := maxPoints % count; rest > lo {
+		if rest > hi {
+			rest = hi
+		}
+		points += rest - lo
+	}
+	return points
+}
+
 // BaseSimulator generates data similar to truck readings.
 type BaseSimulator struct {
 	madePoints uint64
@@ -80,6 +124,10 @@ type BaseSimulator struct {
 
 	generatorIndex uint64
 	generators     []Generator
+	// firstGenerator is the index of generators[0] among the totalGenerators
+	// of the simulation, which differ for the parts of a Partition
+	firstGenerator  uint64
+	totalGenerators uint64
 
 	epoch           uint64
 	epochs          uint64
@@ -125,7 +173,7 @@ func (s *BaseSimulator) Next(p *serialize.Point) bool {
 	// Populate measurement-specific tags and fields:
 	generator.Measurements()[s.simulatedMeasurementIndex].ToPoint(p)
 
-	ret := s.generatorIndex < s.epochGenerators
+	ret := s.firstGenerator+s.generatorIndex < s.epochGenerators
 	s.madePoints++
 	s.generatorIndex++
 	return ret
@@ -147,6 +195,24 @@ func (s *BaseSimulator) Fields() map[string][][]byte {
 	return data
 }
 
+// Partition splits the Generators into parts, see PartitionedSimulator.
+// Every round makes one measurement of each Generator.
+func (s *BaseSimulator) Partition(n int) ([]Simulator, []int) {
+	bounds := PartBounds(s.totalGenerators, n)
+	parts := make([]Simulator, 0, len(bounds)-1)
+	roundPoints := make([]int, 0, len(bounds)-1)
+	for i := 1; i < len(bounds); i++ {
+		lo, hi := bounds[i-1], bounds[i]
+		part := *s
+		part.generators = s.generators[lo:hi]
+		part.firstGenerator = lo
+		part.maxPoints = PartMaxPoints(s.maxPoints, s.totalGenerators, lo, hi)
+		parts = append(parts, &part)
+		roundPoints = append(roundPoints, int(hi-lo))
+	}
+	return parts, roundPoints
+}
+
 // TagKeys returns all the tag keys for the device.
 func (s *BaseSimulator) TagKeys() [][]byte {
 	if len(s.generators) <= 0 {
@@ -188,7 +254,7 @@ func (s *BaseSimulator) TagTypes() []reflect.Type {
 // we check whether the point should be recorded by the calling process.
 func (s *BaseSimulator) adjustNumHostsForEpoch() {
 	s.epoch++
-	missingScale := float64(uint64(len(s.generators)) - s.initGenerators)
+	missingScale := float64(s.totalGenerators - s.initGenerators)
 	s.epochGenerators = s.initGenerators + uint64(missingScale*float64(s.epoch)/float64(s.epochs-1))
 }
 
@@ -62,7 +62,7 @@ func (d dummyGenerator) Tags() []Tag {
 func (d dummyGenerator) TickAll(duration time.Duration) {
 }
 
-func dummyGeneratorConstructor(i int, start time.Time) Generator {
+func dummyGeneratorConstructor(i int, start time.Time, seed int64) Generator {
 	return &dummyGenerator{}
 }
 
@@ -101,6 +101,79 @@ func TestBaseSimulatorNext(t *testing.T) {
 	runFn(3)
 }
 
+// indexedGenerator is a dummyGenerator tagged with its index
+type indexedGenerator struct {
+	dummyGenerator
+	i int
+}
+
+func (g indexedGenerator) Tags() []Tag {
+	return []Tag{{Key: []byte("key"), Value: g.i}}
+}
+
+func TestBaseSimulatorPartition(t *testing.T) {
+	conf := *testBaseConf
+	conf.GeneratorConstructor = func(i int, start time.Time, seed int64) Generator {
+		return &indexedGenerator{i: i}
+	}
+	// points returns the generator index of the points of sim, negative if
+	// they are not written
+	points := func(sim Simulator, n int) []int {
+		var idx []int
+		p := serialize.NewPoint()
+		for i := 0; i < n && !sim.Finished(); i++ {
+			write := sim.Next(p)
+			v := p.TagValues()[0].(int)
+			if !write {
+				v = -1 - v
+			}
+			idx = append(idx, v)
+			p.Reset()
+		}
+		return idx
+	}
+
+	for _, limit := range []uint64{0, 1234} {
+		want := points(conf.NewSimulator(time.Second, limit), int(^uint(0)>>1))
+		for _, n := range []int{1, 3, 7, testGeneratorScale, 2 * testGeneratorScale} {
+			parts, roundPoints := conf.NewSimulator(time.Second, limit).(*BaseSimulator).Partition(n)
+			wantParts := n
+			if wantParts > testGeneratorScale {
+				wantParts = testGeneratorScale
+			}
+			if len(parts) != wantParts {
+				t.Errorf("limit %d, %d parts: incorrect number of parts: got %d want %d", limit, n, len(parts), wantParts)
+			}
+			var got []int
+			for made := -1; made < len(got); {
+				made = len(got)
+				for i, part := range parts {
+					got = append(got, points(part, roundPoints[i])...)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("limit %d, %d parts: points differ from the whole simulation", limit, n)
+			}
+		}
+	}
+}
+
+func TestPartMaxPoints(t *testing.T) {
+	const count = 10
+	for _, maxPoints := range []uint64{0, 3, 10, 27, 100} {
+		total := uint64(0)
+		for _, b := range [][2]uint64{{0, 3}, {3, 4}, {4, 10}} {
+			total += PartMaxPoints(maxPoints, count, b[0], b[1])
+		}
+		if total != maxPoints {
+			t.Errorf("max points %d: incorrect sum of parts: got %d", maxPoints, total)
+		}
+	}
+	if got := PartMaxPoints(27, count, 3, 9); got != 2*6+4 {
+		t.Errorf("incorrect max points: got %d want %d", got, 2*6+4)
+	}
+}
+
 func TestBaseSimulatorTagKeys(t *testing.T) {
 	s := testBaseConf.NewSimulator(time.Second, 0).(*BaseSimulator)
 
@@ -0,0 +1,110 @@
+package custom
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+)
+
+// NewSimulatorConfig returns the config of a simulator for the entities of
+// the schema between start and end, with the given seed.
+func (s *Schema) NewSimulatorConfig(start, end time.Time, seed int64) *common.BaseSimulatorConfig {
+	return &common.BaseSimulatorConfig{
+		Start: start,
+		End:   end,
+
+		InitGeneratorScale:   s.InitialEntities,
+		GeneratorScale:       s.Entities,
+		GeneratorConstructor: s.newEntity,
+		Seed:                 seed,
+	}
+}
+
+// entity is a single simulated entity of a custom use case
+type entity struct {
+	tags         []common.Tag
+	measurements []common.SimulatedMeasurement
+}
+
+// newEntity creates entity i, picking its tag values at random
+func (s *Schema) newEntity(i int, start time.Time, seed int64) common.Generator {
+	r := common.NewRand(seed)
+	e := &entity{}
+	if len(s.EntityTag) > 0 {
+		e.tags = append(e.tags, common.Tag{
+			Key:   []byte(s.EntityTag),
+			Value: fmt.Sprintf("%s_%d", s.EntityTag, i),
+		})
+	}
+	for _, t := range s.Tags {
+		e.tags = append(e.tags, common.Tag{Key: []byte(t.Key), Value: t.randomValue(r)})
+	}
+	for i := range s.Measurements {
+		spec := &s.Measurements[i]
+		mr := common.MeasurementRand(seed, []byte(spec.Name))
+		e.measurements = append(e.measurements, newMeasurement(spec, start, mr))
+	}
+	return e
+}
+
+func (t *TagSpec) randomValue(r *rand.Rand) string {
+	if len(t.Values) > 0 {
+		return common.RandomStringSliceChoice(r, t.Values)
+	}
+	return fmt.Sprintf("%s_%d", t.Key, r.Int63n(int64(t.Cardinality)))
+}
+
+// Measurements returns the measurements of the entity.
+func (e *entity) Measurements() []common.SimulatedMeasurement {
+	return e.measurements
+}
+
+// Tags returns the tags of the entity.
+func (e *entity) Tags() []common.Tag {
+	return e.tags
+}
+
+// TickAll advances all measurements of the entity.
+func (e *entity) TickAll(d time.Duration) {
+	for _, m := range e.measurements {
+		m.Tick(d)
+	}
+}
+
+// measurement simulates a measurement of a custom use case
+type measurement struct {
+	*common.SubsystemMeasurement
+	spec   *MeasurementSpec
+	name   []byte
+	fields [][]byte
+}
+
+func newMeasurement(spec *MeasurementSpec, start time.Time, r *rand.Rand) *measurement {
+	m := &measurement{
+		SubsystemMeasurement: common.NewSubsystemMeasurement(start, len(spec.Fields)),
+		spec:                 spec,
+		name:                 []byte(spec.Name),
+		fields:               make([][]byte, len(spec.Fields)),
+	}
+	for i, f := range spec.Fields {
+		m.fields[i] = []byte(f.Name)
+		m.Distributions[i] = f.Distribution.newDistribution(r)
+	}
+	return m
+}
+
+// ToPoint fills in a serialize.Point with the current values of the fields.
+func (m *measurement) ToPoint(p *serialize.Point) {
+	p.SetMeasurementName(m.name)
+	p.SetTimestamp(&m.Timestamp)
+	for i, d := range m.Distributions {
+		if m.spec.Fields[i].Type == FieldInt {
+			p.AppendField(m.fields[i], int64(d.Get()))
+		} else {
+			p.AppendField(m.fields[i], d.Get())
+		}
+	}
+}
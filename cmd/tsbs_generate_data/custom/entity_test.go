@@ -0,0 +1,91 @@
+package custom
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+)
+
+func TestNewEntity(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := s.newEntity(7, start, common.EntitySeed(42, 7))
+
+	tags := e.Tags()
+	if len(tags) != 3 {
+		t.Fatalf("incorrect number of tags: got %d want 3", len(tags))
+	}
+	if string(tags[0].Key) != "device" || tags[0].Value != "device_7" {
+		t.Errorf("incorrect entity tag: got %s=%v", tags[0].Key, tags[0].Value)
+	}
+	if v := tags[1].Value.(string); v != "eu-west-1" && v != "us-east-1" {
+		t.Errorf("region not from the values: %s", v)
+	}
+	if v := tags[2].Value.(string); !strings.HasPrefix(v, "rack_") {
+		t.Errorf("incorrect rack value: %s", v)
+	}
+	if got := len(e.Measurements()); got != 2 {
+		t.Fatalf("incorrect number of measurements: got %d want 2", got)
+	}
+
+	e.TickAll(time.Minute)
+	p := serialize.NewPoint()
+	e.Measurements()[1].ToPoint(p)
+	if got := string(p.MeasurementName()); got != "status" {
+		t.Errorf("incorrect measurement name: got %s want status", got)
+	}
+	var buf bytes.Buffer
+	if err := (&serialize.InfluxSerializer{}).Serialize(p, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "status code=3i,uptime=") {
+		t.Errorf("incorrect int fields: %s", got)
+	}
+	if got, want := buf.String(), fmt.Sprintf(" %d\n", start.Add(time.Minute).UnixNano()); !strings.HasSuffix(got, want) {
+		t.Errorf("incorrect timestamp: got %s want suffix %s", got, want)
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := s.NewSimulatorConfig(start, start.Add(time.Hour), 42).NewSimulator(10*time.Minute, 0)
+
+	fields := sim.Fields()
+	if got := len(fields["sensor"]); got != 2 {
+		t.Errorf("incorrect number of sensor fields: got %d want 2", got)
+	}
+	if got := string(bytes.Join(sim.TagKeys(), []byte(","))); got != "device,region,rack" {
+		t.Errorf("incorrect tag keys: got %s", got)
+	}
+
+	written := 0
+	total := 0
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		if sim.Next(p) {
+			written++
+		}
+		total++
+		p.Reset()
+	}
+	// 6 intervals of 10 entities with 2 measurements each
+	if want := 6 * 10 * 2; total != want {
+		t.Errorf("incorrect number of points: got %d want %d", total, want)
+	}
+	// only 5 entities report in the first interval
+	if written >= total || written == 0 {
+		t.Errorf("incorrect number of written points: %d of %d", written, total)
+	}
+}
@@ -0,0 +1,275 @@
+// Package custom simulates a use case described by a schema file instead of
+// Go code: a number of entities with tags, each reporting measurements whose
+// fields follow the distributions of the common package.
+package custom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"gopkg.in/yaml.v2"
+)
+
+// Distribution types of a DistributionSpec, matching the constructors of the
+// common package
+const (
+	DistNormal            = "ND"
+	DistUniform           = "UD"
+	DistRandomWalk        = "WD"
+	DistClampedRandomWalk = "CWD"
+	DistMonotonicWalk     = "MWD"
+	DistFloatPrecision    = "FP"
+	DistConstant          = "CONSTANT"
+)
+
+// Field types of a FieldSpec
+const (
+	FieldFloat = "float"
+	FieldInt   = "int"
+)
+
+var distributionTypes = []string{
+	DistNormal,
+	DistUniform,
+	DistRandomWalk,
+	DistClampedRandomWalk,
+	DistMonotonicWalk,
+	DistFloatPrecision,
+	DistConstant,
+}
+
+// Schema describes a custom use case.
+type Schema struct {
+	// Entities is the number of simulated entities, like the hosts of devops
+	Entities uint64 `yaml:"entities"`
+	// InitialEntities is the number of entities reporting in the first
+	// interval, growing to Entities by the end. Defaults to Entities.
+	InitialEntities uint64 `yaml:"initial_entities"`
+	// EntityTag is the key of a tag with a unique value per entity, like
+	// "device_12" for the key "device". No such tag is added if empty.
+	EntityTag string `yaml:"entity_tag"`
+
+	Tags         []TagSpec         `yaml:"tags"`
+	Measurements []MeasurementSpec `yaml:"measurements"`
+}
+
+// TagSpec describes a tag every entity has. Its value is picked at random
+// once per entity, either from Values or as "<key>_<n>" with n below
+// Cardinality.
+type TagSpec struct {
+	Key         string   `yaml:"key"`
+	Values      []string `yaml:"values"`
+	Cardinality uint64   `yaml:"cardinality"`
+}
+
+// MeasurementSpec describes a measurement every entity reports each interval.
+type MeasurementSpec struct {
+	Name   string      `yaml:"name"`
+	Fields []FieldSpec `yaml:"fields"`
+}
+
+// FieldSpec describes a field of a measurement and the distribution of its
+// values. Values of int fields are truncated.
+type FieldSpec struct {
+	Name         string           `yaml:"name"`
+	Type         string           `yaml:"type"`
+	Distribution DistributionSpec `yaml:"distribution"`
+}
+
+// DistributionSpec describes a common.Distribution. Which parameters apply
+// depends on the type:
+//
+//	ND:       mean, stddev
+//	UD:       low, high
+//	WD:       step, state
+//	CWD:      step, min, max, state
+//	MWD:      step, state
+//	FP:       step, precision
+//	constant: value
+type DistributionSpec struct {
+	Type      string            `yaml:"type"`
+	Mean      float64           `yaml:"mean"`
+	StdDev    float64           `yaml:"stddev"`
+	Low       float64           `yaml:"low"`
+	High      float64           `yaml:"high"`
+	Min       float64           `yaml:"min"`
+	Max       float64           `yaml:"max"`
+	State     float64           `yaml:"state"`
+	Precision int               `yaml:"precision"`
+	Value     float64           `yaml:"value"`
+	Step      *DistributionSpec `yaml:"step"`
+}
+
+// LoadSchema reads and validates the schema in the YAML file fileName.
+func LoadSchema(fileName string) (*Schema, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read schema: %v", err)
+	}
+	s, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", fileName, err)
+	}
+	return s, nil
+}
+
+// ParseSchema parses and validates a schema in YAML. Unknown keys are
+// rejected so that typos don't silently fall back to defaults.
+func ParseSchema(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// validate checks the schema and fills in the defaults
+func (s *Schema) validate() error {
+	if s.Entities == 0 {
+		return fmt.Errorf("entities must be positive")
+	}
+	if s.InitialEntities == 0 {
+		s.InitialEntities = s.Entities
+	} else if s.InitialEntities > s.Entities {
+		return fmt.Errorf("initial_entities %d is larger than entities %d", s.InitialEntities, s.Entities)
+	}
+
+	tagKeys := make(map[string]bool)
+	if len(s.EntityTag) > 0 {
+		tagKeys[s.EntityTag] = true
+	}
+	for _, t := range s.Tags {
+		if len(t.Key) == 0 {
+			return fmt.Errorf("tag without key")
+		}
+		if tagKeys[t.Key] {
+			return fmt.Errorf("duplicate tag '%s'", t.Key)
+		}
+		tagKeys[t.Key] = true
+		if (len(t.Values) > 0) == (t.Cardinality > 0) {
+			return fmt.Errorf("tag '%s' needs either values or a cardinality", t.Key)
+		}
+	}
+
+	if len(s.Measurements) == 0 {
+		return fmt.Errorf("no measurements")
+	}
+	names := make(map[string]bool)
+	for i := range s.Measurements {
+		m := &s.Measurements[i]
+		if len(m.Name) == 0 {
+			return fmt.Errorf("measurement without name")
+		}
+		if names[m.Name] {
+			return fmt.Errorf("duplicate measurement '%s'", m.Name)
+		}
+		names[m.Name] = true
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("measurement '%s': %v", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *MeasurementSpec) validate() error {
+	if len(m.Fields) == 0 {
+		return fmt.Errorf("no fields")
+	}
+	names := make(map[string]bool)
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		if len(f.Name) == 0 {
+			return fmt.Errorf("field without name")
+		}
+		if names[f.Name] {
+			return fmt.Errorf("duplicate field '%s'", f.Name)
+		}
+		names[f.Name] = true
+		if len(f.Type) == 0 {
+			f.Type = FieldFloat
+		}
+		if f.Type != FieldFloat && f.Type != FieldInt {
+			return fmt.Errorf("field '%s': unknown type '%s': must be %s or %s", f.Name, f.Type, FieldFloat, FieldInt)
+		}
+		if err := f.Distribution.validate(); err != nil {
+			return fmt.Errorf("field '%s': %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the distribution and its steps, normalizing the type to
+// upper case
+func (d *DistributionSpec) validate() error {
+	d.Type = strings.ToUpper(d.Type)
+	switch d.Type {
+	case DistNormal:
+		if d.StdDev < 0 {
+			return fmt.Errorf("%s: stddev must not be negative", d.Type)
+		}
+		return nil
+	case DistUniform:
+		if d.Low > d.High {
+			return fmt.Errorf("%s: low %v is larger than high %v", d.Type, d.Low, d.High)
+		}
+		return nil
+	case DistConstant:
+		return nil
+	case DistClampedRandomWalk:
+		if d.Min > d.Max {
+			return fmt.Errorf("%s: min %v is larger than max %v", d.Type, d.Min, d.Max)
+		}
+	case DistFloatPrecision:
+		if d.Precision < 0 || d.Precision > 5 {
+			return fmt.Errorf("%s: precision must be between 0 and 5", d.Type)
+		}
+	case DistRandomWalk, DistMonotonicWalk:
+	case "":
+		return fmt.Errorf("distribution without type")
+	default:
+		return fmt.Errorf("unknown distribution type '%s': must be one of %s", d.Type, strings.Join(distributionTypes, ", "))
+	}
+
+	// The remaining types wrap a step distribution
+	if d.Step == nil {
+		return fmt.Errorf("%s: missing step distribution", d.Type)
+	}
+	if err := d.Step.validate(); err != nil {
+		return fmt.Errorf("%s step: %v", d.Type, err)
+	}
+	return nil
+}
+
+// newDistribution creates the distribution described by a validated spec,
+// drawing random numbers from r. Stateless distributions draw their first
+// value right away, walks start at their state.
+func (d *DistributionSpec) newDistribution(r *rand.Rand) common.Distribution {
+	switch d.Type {
+	case DistNormal:
+		nd := common.ND(d.Mean, d.StdDev).WithRand(r)
+		nd.Advance()
+		return nd
+	case DistUniform:
+		ud := common.UD(d.Low, d.High).WithRand(r)
+		ud.Advance()
+		return ud
+	case DistRandomWalk:
+		return common.WD(d.Step.newDistribution(r), d.State)
+	case DistClampedRandomWalk:
+		return common.CWD(d.Step.newDistribution(r), d.Min, d.Max, d.State)
+	case DistMonotonicWalk:
+		return common.MWD(d.Step.newDistribution(r), d.State)
+	case DistFloatPrecision:
+		return common.FP(d.Step.newDistribution(r), d.Precision)
+	case DistConstant:
+		return &common.ConstantDistribution{State: d.Value}
+	default:
+		panic(fmt.Sprintf("unknown distribution type '%s'", d.Type))
+	}
+}
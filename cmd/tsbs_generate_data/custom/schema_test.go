@@ -0,0 +1,161 @@
+package custom
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+)
+
+const testSchema = `
+entities: 10
+initial_entities: 5
+entity_tag: device
+tags:
+  - key: region
+    values: [eu-west-1, us-east-1]
+  - key: rack
+    cardinality: 50
+measurements:
+  - name: sensor
+    fields:
+      - name: temperature
+        distribution:
+          type: cwd
+          step: {type: ND, mean: 0, stddev: 0.5}
+          min: -20
+          max: 50
+          state: 20
+      - name: humidity
+        distribution:
+          type: FP
+          precision: 1
+          step: {type: UD, low: 0, high: 100}
+  - name: status
+    fields:
+      - name: code
+        type: int
+        distribution: {type: constant, value: 3}
+      - name: uptime
+        type: int
+        distribution:
+          type: MWD
+          step: {type: ND, mean: 10, stddev: 1}
+`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Entities != 10 || s.InitialEntities != 5 {
+		t.Errorf("incorrect entities: got %d/%d want 10/5", s.Entities, s.InitialEntities)
+	}
+	if got := len(s.Measurements); got != 2 {
+		t.Fatalf("incorrect number of measurements: got %d want 2", got)
+	}
+	temp := s.Measurements[0].Fields[0]
+	if temp.Type != FieldFloat {
+		t.Errorf("incorrect default field type: got %s want %s", temp.Type, FieldFloat)
+	}
+	if temp.Distribution.Type != DistClampedRandomWalk {
+		t.Errorf("distribution type not normalized: got %s want %s", temp.Distribution.Type, DistClampedRandomWalk)
+	}
+
+	s, err = ParseSchema([]byte("entities: 3\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: constant}}]}]"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.InitialEntities != 3 {
+		t.Errorf("incorrect default initial entities: got %d want 3", s.InitialEntities)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	const measurements = "\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: constant}}]}]"
+	cases := []struct {
+		desc   string
+		schema string
+		want   string
+	}{
+		{"no entities", "entities: 0" + measurements, "entities must be positive"},
+		{"too many initial entities", "entities: 1\ninitial_entities: 2" + measurements, "initial_entities 2 is larger"},
+		{"unknown key", "entities: 1\nentites: 2" + measurements, "entites"},
+		{"no measurements", "entities: 1", "no measurements"},
+		{"tag without values", "entities: 1\ntags: [{key: a}]" + measurements, "either values or a cardinality"},
+		{"tag with both", "entities: 1\ntags: [{key: a, values: [x], cardinality: 2}]" + measurements, "either values or a cardinality"},
+		{"duplicate tag", "entities: 1\nentity_tag: a\ntags: [{key: a, values: [x]}]" + measurements, "duplicate tag 'a'"},
+		{"duplicate measurement", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: constant}}]}, {name: m, fields: [{name: f, distribution: {type: constant}}]}]", "duplicate measurement 'm'"},
+		{"no fields", "entities: 1\nmeasurements: [{name: m}]", "measurement 'm': no fields"},
+		{"bad field type", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, type: string, distribution: {type: constant}}]}]", "unknown type 'string'"},
+		{"unknown distribution", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: zipf}}]}]", "unknown distribution type 'ZIPF'"},
+		{"missing step", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: WD}}]}]", "WD: missing step distribution"},
+		{"bad step", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: CWD, max: 1, step: {type: UD, low: 2, high: 1}}}]}]", "CWD step: UD: low 2 is larger than high 1"},
+		{"bad precision", "entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: FP, precision: 9, step: {type: constant}}}]}]", "precision must be between 0 and 5"},
+	}
+	for _, c := range cases {
+		_, err := ParseSchema([]byte(c.schema))
+		if err == nil {
+			t.Errorf("%s: unexpected lack of error", c.desc)
+		} else if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: incorrect error: got %q want it to contain %q", c.desc, err.Error(), c.want)
+		}
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	f, err := ioutil.TempFile("", "schema*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := LoadSchema(f.Name()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := LoadSchema(f.Name() + ".missing"); err == nil {
+		t.Errorf("unexpected lack of error for missing file")
+	}
+}
+
+func TestNewDistribution(t *testing.T) {
+	cases := []struct {
+		spec DistributionSpec
+		want common.Distribution
+	}{
+		{DistributionSpec{Type: DistNormal}, &common.NormalDistribution{}},
+		{DistributionSpec{Type: DistUniform}, &common.UniformDistribution{}},
+		{DistributionSpec{Type: DistRandomWalk, Step: &DistributionSpec{Type: DistConstant}}, &common.RandomWalkDistribution{}},
+		{DistributionSpec{Type: DistClampedRandomWalk, Step: &DistributionSpec{Type: DistConstant}}, &common.ClampedRandomWalkDistribution{}},
+		{DistributionSpec{Type: DistMonotonicWalk, Step: &DistributionSpec{Type: DistConstant}}, &common.MonotonicRandomWalkDistribution{}},
+		{DistributionSpec{Type: DistFloatPrecision, Step: &DistributionSpec{Type: DistConstant}}, &common.FloatPrecision{}},
+		{DistributionSpec{Type: DistConstant}, &common.ConstantDistribution{}},
+	}
+	for _, c := range cases {
+		got := c.spec.newDistribution(common.NewRand(42))
+		if gotType, wantType := reflect.TypeOf(got), reflect.TypeOf(c.want); gotType != wantType {
+			t.Errorf("%s: incorrect distribution: got %v want %v", c.spec.Type, gotType, wantType)
+		}
+	}
+
+	// Stateless distributions start with a drawn value, walks at their state
+	ud := (&DistributionSpec{Type: DistUniform, Low: 10, High: 20}).newDistribution(common.NewRand(42))
+	if v := ud.Get(); v < 10 || v > 20 {
+		t.Errorf("uniform value out of range: %v", v)
+	}
+	walk := (&DistributionSpec{Type: DistRandomWalk, State: 5, Step: &DistributionSpec{Type: DistConstant, Value: 1}}).newDistribution(common.NewRand(42))
+	if v := walk.Get(); v != 5 {
+		t.Errorf("incorrect initial walk value: got %v want 5", v)
+	}
+	walk.Advance()
+	if v := walk.Get(); v != 6 {
+		t.Errorf("incorrect walk value after advance: got %v want 6", v)
+	}
+}
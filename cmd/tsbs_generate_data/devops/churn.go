@@ -0,0 +1,123 @@
+package devops
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+)
+
+// Distributions of the lifetime of a host when hosts churn:
+const (
+	// LifetimeExponential hosts are replaced at random, independent of how
+	// long they have been running
+	LifetimeExponential = "exponential"
+	// LifetimeUniform hosts live between 0 and twice the mean lifetime
+	LifetimeUniform = "uniform"
+	// LifetimeFixed hosts all live exactly the mean lifetime
+	LifetimeFixed = "fixed"
+
+	// churnHostFmt is the hostname of restart k of host i
+	churnHostFmt = "host_%d-%d"
+)
+
+// LifetimeChoices are the supported distributions of host lifetimes
+var LifetimeChoices = []string{
+	LifetimeExponential,
+	LifetimeUniform,
+	LifetimeFixed,
+}
+
+var labelLifetime = []byte("lifetime")
+
+// hostChurn replaces hosts at the end of their lifetime, like pods of a
+// Kubernetes deployment being restarted. A replacement runs the same service
+// in the same place as the host it replaces, so it keeps its tags but gets a
+// new hostname and measurements. The number of active hosts stays the same
+// while the total number of series keeps growing.
+type hostChurn struct {
+	constructor  func(i int, start time.Time, seed int64) Host
+	lifetime     string
+	meanLifetime time.Duration
+
+	// first is the index of the first host slot, which differs for the
+	// parts of a partitioned simulation
+	first int
+	// Per host slot, derived from the seed of its first host so the churn of
+	// a host does not depend on the number of hosts
+	seeds    []int64
+	rands    []*rand.Rand
+	restarts []int
+	ends     []time.Time
+}
+
+// newHostChurn returns the churn of the hosts of a simulation with the given
+// config, or nil if its hosts do not churn
+func newHostChurn(c commonDevopsSimulatorConfig) *hostChurn {
+	if c.ChurnRate <= 0 {
+		return nil
+	}
+
+	hc := &hostChurn{
+		constructor:  c.HostConstructor,
+		lifetime:     c.ChurnLifetime,
+		meanLifetime: time.Duration(float64(time.Hour) / c.ChurnRate),
+		seeds:        make([]int64, c.HostCount),
+		rands:        make([]*rand.Rand, c.HostCount),
+		restarts:     make([]int, c.HostCount),
+		ends:         make([]time.Time, c.HostCount),
+	}
+	for i := range hc.seeds {
+		hc.seeds[i] = common.EntitySeed(c.Seed, i)
+		hc.rands[i] = common.NewRand(common.NamedSeed(hc.seeds[i], labelLifetime))
+		// The hosts running at the start have already lived part of their
+		// lifetime, otherwise with a fixed one they would all be replaced at once
+		life := hc.nextLifetime(hc.rands[i])
+		hc.ends[i] = c.Start.Add(time.Duration(hc.rands[i].Float64() * float64(life)))
+	}
+
+	return hc
+}
+
+// nextLifetime draws the lifetime of a new host from r
+func (hc *hostChurn) nextLifetime(r *rand.Rand) time.Duration {
+	mean := float64(hc.meanLifetime)
+	switch hc.lifetime {
+	case LifetimeUniform:
+		return time.Duration(2 * mean * r.Float64())
+	case LifetimeFixed:
+		return hc.meanLifetime
+	default:
+		return time.Duration(mean * r.ExpFloat64())
+	}
+}
+
+// part returns the churn of the host slots [lo, hi), or nil without churn
+func (hc *hostChurn) part(lo, hi int) *hostChurn {
+	if hc == nil {
+		return nil
+	}
+	part := *hc
+	part.first = hc.first + lo
+	part.seeds = hc.seeds[lo:hi]
+	part.rands = hc.rands[lo:hi]
+	part.restarts = hc.restarts[lo:hi]
+	part.ends = hc.ends[lo:hi]
+	return &part
+}
+
+// replaceHosts replaces the hosts whose lifetime has ended by now
+func (hc *hostChurn) replaceHosts(hosts []Host, now time.Time) {
+	for i := range hosts {
+		if now.Before(hc.ends[i]) {
+			continue
+		}
+		hc.restarts[i]++
+		slot := hc.first + i
+		h := hc.constructor(slot, now, common.EntitySeed(hc.seeds[i], hc.restarts[i]))
+		hosts[i].Name = fmt.Sprintf(churnHostFmt, slot, hc.restarts[i])
+		hosts[i].SimulatedMeasurements = h.SimulatedMeasurements
+		hc.ends[i] = now.Add(hc.nextLifetime(hc.rands[i]))
+	}
+}
@@ -0,0 +1,116 @@
+package devops
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+)
+
+func TestNewHostChurn(t *testing.T) {
+	c := commonDevopsSimulatorConfig{
+		Start:           testTime,
+		End:             testTime.Add(time.Hour),
+		HostCount:       100,
+		HostConstructor: NewHostCPUOnly,
+		Seed:            123,
+	}
+	if hc := newHostChurn(c); hc != nil {
+		t.Errorf("unexpected churn without churn rate")
+	}
+
+	c.ChurnRate = 2
+	c.ChurnLifetime = LifetimeFixed
+	hc := newHostChurn(c)
+	if hc == nil {
+		t.Fatalf("unexpected lack of churn with churn rate")
+	}
+	if hc.meanLifetime != 30*time.Minute {
+		t.Errorf("incorrect mean lifetime: got %v want %v", hc.meanLifetime, 30*time.Minute)
+	}
+	for i, end := range hc.ends {
+		if end.Before(testTime) || !end.Before(testTime.Add(hc.meanLifetime)) {
+			t.Errorf("end of host %d out of range: got %v", i, end)
+		}
+	}
+}
+
+func TestHostChurnNextLifetime(t *testing.T) {
+	const draws = 10000
+	mean := time.Hour
+	r := common.NewRand(123)
+	for _, lifetime := range LifetimeChoices {
+		hc := &hostChurn{lifetime: lifetime, meanLifetime: mean}
+		var sum float64
+		for i := 0; i < draws; i++ {
+			d := hc.nextLifetime(r)
+			if d < 0 {
+				t.Fatalf("%s: negative lifetime: %v", lifetime, d)
+			}
+			if lifetime == LifetimeUniform && d >= 2*mean {
+				t.Fatalf("%s: lifetime out of range: %v", lifetime, d)
+			}
+			if lifetime == LifetimeFixed && d != mean {
+				t.Fatalf("%s: incorrect lifetime: got %v want %v", lifetime, d, mean)
+			}
+			sum += float64(d)
+		}
+		if got := sum / draws; math.Abs(got-float64(mean)) > 0.05*float64(mean) {
+			t.Errorf("%s: incorrect mean lifetime: got %v want %v", lifetime, time.Duration(got), mean)
+		}
+	}
+}
+
+func TestCPUOnlySimulatorChurn(t *testing.T) {
+	const hostCount = 10
+	conf := &CPUOnlySimulatorConfig{
+		Start:           testTime,
+		End:             testTime.Add(time.Hour),
+		InitHostCount:   hostCount,
+		HostCount:       hostCount,
+		HostConstructor: NewHostCPUOnly,
+		Seed:            123,
+		ChurnRate:       4,
+		ChurnLifetime:   LifetimeExponential,
+	}
+	s := conf.NewSimulator(time.Minute, 0).(*CPUOnlySimulator)
+	regions := make([]string, hostCount)
+	for i, h := range s.hosts {
+		regions[i] = h.Region
+	}
+
+	names := make(map[string]bool)
+	for epoch := 0; !s.Finished(); epoch++ {
+		epochNames := make(map[string]bool)
+		for i := 0; i < hostCount; i++ {
+			p := serialize.NewPoint()
+			if !s.Next(p) {
+				t.Fatalf("epoch %d: point %d not written", epoch, i)
+			}
+			got := s.hosts[i].SimulatedMeasurements[0].(*CPUMeasurement).Timestamp
+			if want := testTime.Add(time.Duration(epoch) * time.Minute); !got.Equal(want) {
+				t.Errorf("epoch %d: incorrect timestamp of host %d: got %v want %v", epoch, i, got, want)
+			}
+			name := p.GetTagValue(MachineTagKeys[0]).(string)
+			if name != fmt.Sprintf(hostFmt, i) && name != fmt.Sprintf(churnHostFmt, i, s.churn.restarts[i]) {
+				t.Errorf("epoch %d: incorrect hostname of point %d: %s", epoch, i, name)
+			}
+			if got := p.GetTagValue(MachineTagKeys[1]).(string); got != regions[i] {
+				t.Errorf("epoch %d: region of %s changed: got %s want %s", epoch, name, got, regions[i])
+			}
+			epochNames[name] = true
+			names[name] = true
+		}
+		if len(epochNames) != hostCount {
+			t.Errorf("epoch %d: incorrect number of active hosts: got %d want %d", epoch, len(epochNames), hostCount)
+		}
+	}
+
+	// About 4 restarts per host during the hour
+	if len(names) < 3*hostCount || len(names) > 7*hostCount {
+		t.Errorf("incorrect total number of hosts: got %d", len(names))
+	}
+}
@@ -17,8 +17,14 @@ type commonDevopsSimulatorConfig struct {
 	InitHostCount uint64
 	// HostCount is the total number of hosts to have in the last reporting period
 	HostCount uint64
-	// HostConstructor is the function used to create a new Host given an id number and start time
-	HostConstructor func(i int, start time.Time) Host
+	// HostConstructor is the function used to create a new Host given an id number, start time and seed
+	HostConstructor func(i int, start time.Time, seed int64) Host
+	// Seed is the seed of the simulation from which the hosts derive theirs
+	Seed int64
+	// ChurnRate is the share of the hosts replaced per hour, 0 means hosts are never replaced
+	ChurnRate float64
+	// ChurnLifetime is the distribution of the lifetime of a host, one of LifetimeChoices
+	ChurnLifetime string
 }
 
 func calculateEpochs(c commonDevopsSimulatorConfig, interval time.Duration) uint64 {
@@ -31,6 +37,11 @@ type commonDevopsSimulator struct {
 
 	hostIndex uint64
 	hosts     []Host
+	churn     *hostChurn
+	// firstHost is the index of hosts[0] among the totalHosts of the
+	// simulation, which differ for the parts of a partition
+	firstHost  uint64
+	totalHosts uint64
 
 	epoch      uint64
 	epochs     uint64
@@ -77,6 +88,25 @@ func (s *commonDevopsSimulator) fields(measurements []common.SimulatedMeasuremen
 	return data
 }
 
+// partition splits the hosts into at most n parts, see
+// common.PartitionedSimulator. Every round makes one point of each host.
+func (s *commonDevopsSimulator) partition(n int) ([]*commonDevopsSimulator, []int) {
+	bounds := common.PartBounds(s.totalHosts, n)
+	parts := make([]*commonDevopsSimulator, 0, len(bounds)-1)
+	roundPoints := make([]int, 0, len(bounds)-1)
+	for i := 1; i < len(bounds); i++ {
+		lo, hi := bounds[i-1], bounds[i]
+		part := *s
+		part.hosts = s.hosts[lo:hi]
+		part.churn = s.churn.part(int(lo), int(hi))
+		part.firstHost = lo
+		part.maxPoints = common.PartMaxPoints(s.maxPoints, s.totalHosts, lo, hi)
+		parts = append(parts, &part)
+		roundPoints = append(roundPoints, int(hi-lo))
+	}
+	return parts, roundPoints
+}
+
 func (s *commonDevopsSimulator) populatePoint(p *serialize.Point, measureIdx int) bool {
 	host := &s.hosts[s.hostIndex]
 
@@ -95,7 +125,7 @@ func (s *commonDevopsSimulator) populatePoint(p *serialize.Point, measureIdx int
 	// Populate measurement-specific tags and fields:
 	host.SimulatedMeasurements[measureIdx].ToPoint(p)
 
-	ret := s.hostIndex < s.epochHosts
+	ret := s.firstHost+s.hostIndex < s.epochHosts
 	s.madePoints++
 	s.hostIndex++
 	return ret
@@ -110,8 +140,15 @@ func (s *commonDevopsSimulator) populatePoint(p *serialize.Point, measureIdx int
 // -- and add it in proportion to the percentage of epochs that have passed. This
 // way we simulate all items at each epoch, but at the end of the function
 // we check whether the point should be recorded by the calling process.
+//
+// With churn, the hosts whose lifetime has ended are replaced at the start of
+// the epoch as well.
 func (s *commonDevopsSimulator) adjustNumHostsForEpoch() {
 	s.epoch++
-	missingScale := float64(uint64(len(s.hosts)) - s.initHosts)
+	missingScale := float64(s.totalHosts - s.initHosts)
 	s.epochHosts = s.initHosts + uint64(missingScale*float64(s.epoch)/float64(s.epochs-1))
+
+	if s.churn != nil {
+		s.churn.replaceHosts(s.hosts, s.timestampStart.Add(time.Duration(s.epoch)*s.interval))
+	}
 }
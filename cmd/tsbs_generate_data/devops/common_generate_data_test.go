@@ -128,7 +128,7 @@ func TestCommonDevopsSimulatorFinished(t *testing.T) {
 func TestCommonDevopsSimulatorFields(t *testing.T) {
 	s := &commonDevopsSimulator{}
 	host := Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now(), common.NewRand(42))}
 	s.hosts = append(s.hosts, host)
 	fields := s.Fields()
 	if got := len(fields); got != 1 {
@@ -146,7 +146,7 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 	// because we assume each Host has the same set of simulated measurements.
 	// TODO - Examine whether this assumption should be refined.
 	host = Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(time.Now(), common.NewRand(42))}
 	s.hosts = append(s.hosts, host)
 	fields = s.Fields()
 	if got := len(fields); got != 1 {
@@ -155,7 +155,7 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 
 	// Add new measurement, this should change the result.
 	host = s.hosts[0]
-	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(time.Now()))
+	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(time.Now(), common.NewRand(42)))
 	s.hosts[0] = host
 	fields = s.Fields()
 	if got := len(fields); got != 2 {
@@ -196,7 +196,7 @@ func TestCommonDevopsSimulatorPopulatePoint(t *testing.T) {
 			ServiceVersion:     sprintf("%s%d", prefix[8], i),
 			ServiceEnvironment: sprintf("%s%d", prefix[9], i),
 		}
-		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now(), common.NewRand(42))}
 		s.hosts = append(s.hosts, host)
 	}
 	s.hostIndex = 0
@@ -280,7 +280,7 @@ func TestAdjustNumHostsForEpoch(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		s := &commonDevopsSimulator{}
+		s := &commonDevopsSimulator{totalHosts: uint64(totalHosts)}
 		for i := 0; i < totalHosts; i++ {
 			s.hosts = append(s.hosts, Host{})
 		}
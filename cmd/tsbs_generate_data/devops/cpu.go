@@ -11,38 +11,42 @@ import (
 var (
 	labelCPU  = []byte("cpu") // heap optimization
 	cpuFields = []common.LabeledDistributionMaker{
-		{[]byte("usage_user"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_system"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_idle"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_nice"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_iowait"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_irq"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_softirq"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_steal"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_guest"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{[]byte("usage_guest_nice"), func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
+		{[]byte("usage_user"), newCPUDistribution},
+		{[]byte("usage_system"), newCPUDistribution},
+		{[]byte("usage_idle"), newCPUDistribution},
+		{[]byte("usage_nice"), newCPUDistribution},
+		{[]byte("usage_iowait"), newCPUDistribution},
+		{[]byte("usage_irq"), newCPUDistribution},
+		{[]byte("usage_softirq"), newCPUDistribution},
+		{[]byte("usage_steal"), newCPUDistribution},
+		{[]byte("usage_guest"), newCPUDistribution},
+		{[]byte("usage_guest_nice"), newCPUDistribution},
 	}
 )
 
-// Reuse NormalDistributions as arguments to other distributions. This is
-// safe to do because the higher-level distribution advances the ND and
-// immediately uses its value and saves the state
+// NormalDistributions used as arguments to other distributions. Each of
+// them gets a copy drawing from the random numbers of its measurement,
+// see WithRand
 var cpuND = common.ND(0.0, 1.0)
 
+func newCPUDistribution(r *rand.Rand) common.Distribution {
+	return common.CWD(cpuND.WithRand(r), 0.0, 100.0, r.Float64()*100.0)
+}
+
 type CPUMeasurement struct {
 	*common.SubsystemMeasurement
 }
 
-func NewCPUMeasurement(start time.Time) *CPUMeasurement {
-	return newCPUMeasurementNumDistributions(start, len(cpuFields))
+func NewCPUMeasurement(start time.Time, r *rand.Rand) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(start, r, len(cpuFields))
 }
 
-func newSingleCPUMeasurement(start time.Time) *CPUMeasurement {
-	return newCPUMeasurementNumDistributions(start, 1)
+func newSingleCPUMeasurement(start time.Time, r *rand.Rand) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(start, r, 1)
 }
 
-func newCPUMeasurementNumDistributions(start time.Time, numDistributions int) *CPUMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, cpuFields[:numDistributions])
+func newCPUMeasurementNumDistributions(start time.Time, r *rand.Rand, numDistributions int) *CPUMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, cpuFields[:numDistributions], r)
 	return &CPUMeasurement{sub}
 }
 
@@ -34,6 +34,16 @@ func (d *CPUOnlySimulator) Next(p *serialize.Point) bool {
 	return d.populatePoint(p, 0)
 }
 
+// Partition implements common.PartitionedSimulator. Every round is an epoch.
+func (d *CPUOnlySimulator) Partition(n int) ([]common.Simulator, []int) {
+	commons, roundPoints := d.partition(n)
+	parts := make([]common.Simulator, len(commons))
+	for i, c := range commons {
+		parts[i] = &CPUOnlySimulator{c}
+	}
+	return parts, roundPoints
+}
+
 // CPUOnlySimulatorConfig is used to create a CPUOnlySimulator.
 type CPUOnlySimulatorConfig commonDevopsSimulatorConfig
 
@@ -41,7 +51,7 @@ type CPUOnlySimulatorConfig commonDevopsSimulatorConfig
 func (c *CPUOnlySimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	hostInfos := make([]Host, c.HostCount)
 	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = c.HostConstructor(i, c.Start)
+		hostInfos[i] = c.HostConstructor(i, c.Start, common.EntitySeed(c.Seed, i))
 	}
 
 	epochs := calculateEpochs(commonDevopsSimulatorConfig(*c), interval)
@@ -54,8 +64,10 @@ func (c *CPUOnlySimulatorConfig) NewSimulator(interval time.Duration, limit uint
 		madePoints: 0,
 		maxPoints:  maxPoints,
 
-		hostIndex: 0,
-		hosts:     hostInfos,
+		hostIndex:  0,
+		hosts:      hostInfos,
+		churn:      newHostChurn(commonDevopsSimulatorConfig(*c)),
+		totalHosts: c.HostCount,
 
 		epoch:          0,
 		epochs:         epochs,
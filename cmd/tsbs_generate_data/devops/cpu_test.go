@@ -2,7 +2,6 @@ package devops
 
 import (
 	"fmt"
-	"math/rand"
 	"testing"
 	"time"
 
@@ -34,7 +33,7 @@ func testDistributionsAreDifferent(oldVals map[string]float64, m *common.Subsyst
 
 func TestCPUMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	m := NewCPUMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields)
@@ -42,7 +41,6 @@ func TestCPUMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -57,7 +55,7 @@ func TestCPUMeasurementTick(t *testing.T) {
 
 func TestCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	m := NewCPUMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	m.Tick(duration)
 
@@ -76,7 +74,7 @@ func TestCPUMeasurementToPoint(t *testing.T) {
 
 func TestSingleCPUMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	m := newSingleCPUMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields[:1]) // only the first field in this use case
@@ -87,7 +85,6 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -102,7 +99,7 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 
 func TestSingleCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	m := newSingleCPUMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	fields := cpuFields[:1] // only the first field in this use case
 	m.Tick(duration)
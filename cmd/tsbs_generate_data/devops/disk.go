@@ -57,11 +57,11 @@ type DiskMeasurement struct {
 }
 
 // NewDiskMeasurement returns a new populated DiskMeasurement
-func NewDiskMeasurement(start time.Time) *DiskMeasurement {
-	path := fmt.Sprintf(pathFmt, rand.Intn(10))
-	fsType := common.RandomStringSliceChoice(diskFSTypeChoices)
+func NewDiskMeasurement(start time.Time, r *rand.Rand) *DiskMeasurement {
+	path := fmt.Sprintf(pathFmt, r.Intn(10))
+	fsType := common.RandomStringSliceChoice(r, diskFSTypeChoices)
 	sub := common.NewSubsystemMeasurement(start, 1)
-	sub.Distributions[0] = common.CWD(common.ND(50, 1), 0, oneTerabyte, oneTerabyte/2)
+	sub.Distributions[0] = common.CWD(common.ND(50, 1).WithRand(r), 0, oneTerabyte, oneTerabyte/2)
 
 	return &DiskMeasurement{
 		SubsystemMeasurement: sub,
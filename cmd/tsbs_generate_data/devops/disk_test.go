@@ -2,10 +2,10 @@ package devops
 
 import (
 	"bytes"
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
@@ -29,7 +29,7 @@ func testIfInStringSlice(t *testing.T, arr []string, choice string) {
 
 func TestDiskMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	m := NewDiskMeasurement(now, common.NewRand(42))
 	origPath := string(m.path)
 	origFS := string(m.fsType)
 	duration := time.Second
@@ -39,7 +39,6 @@ func TestDiskMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -67,7 +66,7 @@ func TestDiskMeasurementTick(t *testing.T) {
 
 func TestDiskMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	m := NewDiskMeasurement(now, common.NewRand(42))
 	origPath := m.path
 	origFS := m.fsType
 	testIfInStringSlice(t, diskFSTypeChoices, m.fsType)
@@ -17,21 +17,21 @@ var (
 	labelDiskIO       = []byte("diskio") // heap optimization
 	labelDiskIOSerial = []byte("serial")
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	opsND   = common.ND(50, 1)
 	bytesND = common.ND(100, 1)
 	timeND  = common.ND(5, 1)
 
 	diskIOFields = []common.LabeledDistributionMaker{
-		{[]byte("reads"), func() common.Distribution { return common.MWD(opsND, 0) }},
-		{[]byte("writes"), func() common.Distribution { return common.MWD(opsND, 0) }},
-		{[]byte("read_bytes"), func() common.Distribution { return common.MWD(bytesND, 0) }},
-		{[]byte("write_bytes"), func() common.Distribution { return common.MWD(bytesND, 0) }},
-		{[]byte("read_time"), func() common.Distribution { return common.MWD(timeND, 0) }},
-		{[]byte("write_time"), func() common.Distribution { return common.MWD(timeND, 0) }},
-		{[]byte("io_time"), func() common.Distribution { return common.MWD(timeND, 0) }},
+		{[]byte("reads"), func(r *rand.Rand) common.Distribution { return common.MWD(opsND.WithRand(r), 0) }},
+		{[]byte("writes"), func(r *rand.Rand) common.Distribution { return common.MWD(opsND.WithRand(r), 0) }},
+		{[]byte("read_bytes"), func(r *rand.Rand) common.Distribution { return common.MWD(bytesND.WithRand(r), 0) }},
+		{[]byte("write_bytes"), func(r *rand.Rand) common.Distribution { return common.MWD(bytesND.WithRand(r), 0) }},
+		{[]byte("read_time"), func(r *rand.Rand) common.Distribution { return common.MWD(timeND.WithRand(r), 0) }},
+		{[]byte("write_time"), func(r *rand.Rand) common.Distribution { return common.MWD(timeND.WithRand(r), 0) }},
+		{[]byte("io_time"), func(r *rand.Rand) common.Distribution { return common.MWD(timeND.WithRand(r), 0) }},
 	}
 )
 
@@ -40,9 +40,9 @@ type DiskIOMeasurement struct {
 	serial string
 }
 
-func NewDiskIOMeasurement(start time.Time) *DiskIOMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diskIOFields)
-	serial := fmt.Sprintf(diskSerialFmt, rand.Intn(1000), rand.Intn(1000), rand.Intn(1000))
+func NewDiskIOMeasurement(start time.Time, r *rand.Rand) *DiskIOMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diskIOFields, r)
+	serial := fmt.Sprintf(diskSerialFmt, r.Intn(1000), r.Intn(1000), r.Intn(1000))
 	return &DiskIOMeasurement{
 		SubsystemMeasurement: sub,
 		serial:               serial,
@@ -1,16 +1,16 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
 func TestDiskIOMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	m := NewDiskIOMeasurement(now, common.NewRand(42))
 	origSerial := string(m.serial)
 	duration := time.Second
 	oldVals := map[string]float64{}
@@ -19,7 +19,6 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -40,7 +39,7 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 
 func TestDiskIOMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	m := NewDiskIOMeasurement(now, common.NewRand(42))
 	origSerial := string(m.serial)
 	duration := time.Second
 	m.Tick(duration)
@@ -35,6 +35,17 @@ func (d *DevopsSimulator) Next(p *serialize.Point) bool {
 	return d.populatePoint(p, d.simulatedMeasurementIndex)
 }
 
+// Partition implements common.PartitionedSimulator. Every round makes one
+// measurement of each host.
+func (d *DevopsSimulator) Partition(n int) ([]common.Simulator, []int) {
+	commons, roundPoints := d.partition(n)
+	parts := make([]common.Simulator, len(commons))
+	for i, c := range commons {
+		parts[i] = &DevopsSimulator{commonDevopsSimulator: c}
+	}
+	return parts, roundPoints
+}
+
 // DevopsSimulatorConfig is used to create a DevopsSimulator.
 type DevopsSimulatorConfig commonDevopsSimulatorConfig
 
@@ -42,7 +53,7 @@ type DevopsSimulatorConfig commonDevopsSimulatorConfig
 func (d *DevopsSimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	hostInfos := make([]Host, d.HostCount)
 	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = d.HostConstructor(i, d.Start)
+		hostInfos[i] = d.HostConstructor(i, d.Start, common.EntitySeed(d.Seed, i))
 	}
 
 	epochs := calculateEpochs(commonDevopsSimulatorConfig(*d), interval)
@@ -56,8 +67,10 @@ func (d *DevopsSimulatorConfig) NewSimulator(interval time.Duration, limit uint6
 			madePoints: 0,
 			maxPoints:  maxPoints,
 
-			hostIndex: 0,
-			hosts:     hostInfos,
+			hostIndex:  0,
+			hosts:      hostInfos,
+			churn:      newHostChurn(commonDevopsSimulatorConfig(*d)),
+			totalHosts: d.HostCount,
 
 			epoch:          0,
 			epochs:         epochs,
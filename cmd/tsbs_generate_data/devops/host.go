@@ -152,66 +152,70 @@ type Host struct {
 	ServiceEnvironment string
 }
 
-func newHostMeasurements(start time.Time) []common.SimulatedMeasurement {
+// Each measurement of a host draws from a source of random numbers of its
+// own, so the values of one do not depend on which others are simulated.
+func newHostMeasurements(start time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewCPUMeasurement(start),
-		NewDiskIOMeasurement(start),
-		NewDiskMeasurement(start),
-		NewKernelMeasurement(start),
-		NewMemMeasurement(start),
-		NewNetMeasurement(start),
-		NewNginxMeasurement(start),
-		NewPostgresqlMeasurement(start),
-		NewRedisMeasurement(start),
+		NewCPUMeasurement(start, common.MeasurementRand(seed, labelCPU)),
+		NewDiskIOMeasurement(start, common.MeasurementRand(seed, labelDiskIO)),
+		NewDiskMeasurement(start, common.MeasurementRand(seed, labelDisk)),
+		NewKernelMeasurement(start, common.MeasurementRand(seed, labelKernel)),
+		NewMemMeasurement(start, common.MeasurementRand(seed, labelMem)),
+		NewNetMeasurement(start, common.MeasurementRand(seed, labelNet)),
+		NewNginxMeasurement(start, common.MeasurementRand(seed, labelNginx)),
+		NewPostgresqlMeasurement(start, common.MeasurementRand(seed, labelPostgresql)),
+		NewRedisMeasurement(start, common.MeasurementRand(seed, labelRedis)),
 	}
 }
 
-func newCPUOnlyHostMeasurements(start time.Time) []common.SimulatedMeasurement {
+func newCPUOnlyHostMeasurements(start time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewCPUMeasurement(start),
+		NewCPUMeasurement(start, common.MeasurementRand(seed, labelCPU)),
 	}
 }
 
-func newCPUSingleHostMeasurements(start time.Time) []common.SimulatedMeasurement {
+func newCPUSingleHostMeasurements(start time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		newSingleCPUMeasurement(start),
+		newSingleCPUMeasurement(start, common.MeasurementRand(seed, labelCPU)),
 	}
 }
 
-// NewHost creates a new host in a simulated devops use case
-func NewHost(i int, start time.Time) Host {
-	return newHostWithMeasurementGenerator(i, start, newHostMeasurements)
+// NewHost creates a new host in a simulated devops use case, seed is the seed
+// of the host (see common.EntitySeed)
+func NewHost(i int, start time.Time, seed int64) Host {
+	return newHostWithMeasurementGenerator(i, start, seed, newHostMeasurements)
 }
 
 // NewHostCPUOnly creates a new host in a simulated cpu-only use case, which is a subset of a devops case
 // with only CPU metrics simulated
-func NewHostCPUOnly(i int, start time.Time) Host {
-	return newHostWithMeasurementGenerator(i, start, newCPUOnlyHostMeasurements)
+func NewHostCPUOnly(i int, start time.Time, seed int64) Host {
+	return newHostWithMeasurementGenerator(i, start, seed, newCPUOnlyHostMeasurements)
 }
 
 // NewHostCPUSingle creates a new host in a simulated cpu-single use case, which is a subset of a devops case
 // with only a single CPU metric is simulated
-func NewHostCPUSingle(i int, start time.Time) Host {
-	return newHostWithMeasurementGenerator(i, start, newCPUSingleHostMeasurements)
+func NewHostCPUSingle(i int, start time.Time, seed int64) Host {
+	return newHostWithMeasurementGenerator(i, start, seed, newCPUSingleHostMeasurements)
 }
 
-func newHostWithMeasurementGenerator(i int, start time.Time, generator func(time.Time) []common.SimulatedMeasurement) Host {
-	sm := generator(start)
+func newHostWithMeasurementGenerator(i int, start time.Time, seed int64, generator func(time.Time, int64) []common.SimulatedMeasurement) Host {
+	sm := generator(start, seed)
 
-	region := randomRegionSliceChoice(regions)
+	r := common.NewRand(seed)
+	region := randomRegionSliceChoice(r, regions)
 
 	h := Host{
 		// Tag Values that are static throughout the life of a Host:
 		Name:               fmt.Sprintf(hostFmt, i),
 		Region:             region.Name,
-		Datacenter:         common.RandomStringSliceChoice(region.Datacenters),
-		Rack:               getStringRandomInt(machineRackChoicesPerDatacenter),
-		Arch:               common.RandomStringSliceChoice(MachineArchChoices),
-		OS:                 common.RandomStringSliceChoice(MachineOSChoices),
-		Service:            getStringRandomInt(machineServiceChoices),
-		ServiceVersion:     getStringRandomInt(machineServiceVersionChoices),
-		ServiceEnvironment: common.RandomStringSliceChoice(MachineServiceEnvironmentChoices),
-		Team:               common.RandomStringSliceChoice(MachineTeamChoices),
+		Datacenter:         common.RandomStringSliceChoice(r, region.Datacenters),
+		Rack:               getStringRandomInt(r, machineRackChoicesPerDatacenter),
+		Arch:               common.RandomStringSliceChoice(r, MachineArchChoices),
+		OS:                 common.RandomStringSliceChoice(r, MachineOSChoices),
+		Service:            getStringRandomInt(r, machineServiceChoices),
+		ServiceVersion:     getStringRandomInt(r, machineServiceVersionChoices),
+		ServiceEnvironment: common.RandomStringSliceChoice(r, MachineServiceEnvironmentChoices),
+		Team:               common.RandomStringSliceChoice(r, MachineTeamChoices),
 
 		SimulatedMeasurements: sm,
 	}
@@ -226,10 +230,10 @@ func (h *Host) TickAll(d time.Duration) {
 	}
 }
 
-func getStringRandomInt(limit int64) string {
-	return strconv.FormatInt(rand.Int63n(limit), 10)
+func getStringRandomInt(r *rand.Rand, limit int64) string {
+	return strconv.FormatInt(r.Int63n(limit), 10)
 }
 
-func randomRegionSliceChoice(s []region) *region {
-	return &s[rand.Intn(len(s))]
+func randomRegionSliceChoice(r *rand.Rand, s []region) *region {
+	return &s[r.Intn(len(s))]
 }
@@ -12,7 +12,7 @@ import (
 
 func TestNewHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newHostMeasurements(start)
+	measurements := newHostMeasurements(start, 123)
 	if got := len(measurements); got != 9 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -60,7 +60,7 @@ func TestNewHostMeasurements(t *testing.T) {
 
 func TestNewCPUOnlyHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newCPUOnlyHostMeasurements(start)
+	measurements := newCPUOnlyHostMeasurements(start, 123)
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -76,7 +76,7 @@ func TestNewCPUOnlyHostMeasurements(t *testing.T) {
 
 func TestNewCPUSingleHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newCPUSingleHostMeasurements(start)
+	measurements := newCPUSingleHostMeasurements(start, 123)
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -94,7 +94,7 @@ func TestNewHost(t *testing.T) {
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHost(i, now)
+		h := NewHost(i, now, common.EntitySeed(123, i))
 		if got := len(h.SimulatedMeasurements); got != 9 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -117,7 +117,7 @@ func TestNewHostCPUOnly(t *testing.T) {
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUOnly(i, now)
+		h := NewHostCPUOnly(i, now, common.EntitySeed(123, i))
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -140,7 +140,7 @@ func TestNewHostCPUSingle(t *testing.T) {
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUSingle(i, now)
+		h := NewHostCPUSingle(i, now, common.EntitySeed(123, i))
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -159,7 +159,7 @@ func TestNewHostCPUSingle(t *testing.T) {
 	}
 }
 
-func testGenerator(s time.Time) []common.SimulatedMeasurement {
+func testGenerator(s time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
 		&testMeasurement{ticks: 0},
 	}
@@ -188,7 +188,7 @@ func TestNewHostWithMeasurementGenerator(t *testing.T) {
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := newHostWithMeasurementGenerator(i, now, testGenerator)
+		h := newHostWithMeasurementGenerator(i, now, common.EntitySeed(123, i), testGenerator)
 		wantName := fmt.Sprintf(hostFmt, i)
 		if got := string(h.Name); got != wantName {
 			t.Errorf("incorrect host name format: got %s want %s", got, wantName)
@@ -218,7 +218,7 @@ func (m *testMeasurement) ToPoint(_ *serialize.Point) {}
 
 func TestHostTickAll(t *testing.T) {
 	now := time.Now()
-	h := newHostWithMeasurementGenerator(0, now, testGenerator)
+	h := newHostWithMeasurementGenerator(0, now, 123, testGenerator)
 	if got := h.SimulatedMeasurements[0].(*testMeasurement).ticks; got != 0 {
 		t.Errorf("ticks not equal to 0 to start: got %d", got)
 	}
@@ -238,8 +238,9 @@ func TestHostTickAll(t *testing.T) {
 
 func TestGetStringRandomInt(t *testing.T) {
 	limit := int64(100)
+	r := common.NewRand(42)
 	for i := 0; i < 1000000; i++ {
-		s := getStringRandomInt(limit)
+		s := getStringRandomInt(r, limit)
 		testStringNumberIsValid(t, limit, s)
 	}
 }
@@ -254,8 +255,9 @@ func testIfInRegionSlice(t *testing.T, arr []region, choice *region) {
 }
 
 func TestRandomRegionSliceChoice(t *testing.T) {
+	r := common.NewRand(42)
 	for i := 0; i < 1000000; i++ {
-		r := randomRegionSliceChoice(regions)
-		testIfInRegionSlice(t, regions, r)
+		choice := randomRegionSliceChoice(r, regions)
+		testIfInRegionSlice(t, regions, choice)
 	}
 }
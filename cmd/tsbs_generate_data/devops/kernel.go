@@ -12,17 +12,17 @@ var (
 	labelKernel         = []byte("kernel") // heap optimization
 	labelKernelBootTime = []byte("boot_time")
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	kernelND = common.ND(5, 1)
 
 	kernelFields = []common.LabeledDistributionMaker{
-		{[]byte("interrupts"), func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{[]byte("context_switches"), func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{[]byte("processes_forked"), func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{[]byte("disk_pages_in"), func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{[]byte("disk_pages_out"), func() common.Distribution { return common.MWD(kernelND, 0) }},
+		{[]byte("interrupts"), func(r *rand.Rand) common.Distribution { return common.MWD(kernelND.WithRand(r), 0) }},
+		{[]byte("context_switches"), func(r *rand.Rand) common.Distribution { return common.MWD(kernelND.WithRand(r), 0) }},
+		{[]byte("processes_forked"), func(r *rand.Rand) common.Distribution { return common.MWD(kernelND.WithRand(r), 0) }},
+		{[]byte("disk_pages_in"), func(r *rand.Rand) common.Distribution { return common.MWD(kernelND.WithRand(r), 0) }},
+		{[]byte("disk_pages_out"), func(r *rand.Rand) common.Distribution { return common.MWD(kernelND.WithRand(r), 0) }},
 	}
 )
 
@@ -31,9 +31,9 @@ type KernelMeasurement struct {
 	bootTime int64
 }
 
-func NewKernelMeasurement(start time.Time) *KernelMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, kernelFields)
-	bootTime := rand.Int63n(240)
+func NewKernelMeasurement(start time.Time, r *rand.Rand) *KernelMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, kernelFields, r)
+	bootTime := r.Int63n(240)
 	return &KernelMeasurement{
 		SubsystemMeasurement: sub,
 		bootTime:             bootTime,
@@ -1,10 +1,10 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
@@ -19,7 +19,7 @@ func testIfInInt64Slice(t *testing.T, arr []int64, choice int64) {
 
 func TestMemMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	m := NewMemMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	oldVals := map[string]float64{}
 	oldTotal := m.bytesTotal
@@ -29,7 +29,6 @@ func TestMemMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -50,7 +49,7 @@ func TestMemMeasurementTick(t *testing.T) {
 
 func TestMemMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	m := NewMemMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	m.Tick(duration)
 
@@ -13,21 +13,21 @@ var (
 	labelNet             = []byte("net") // heap optimization
 	labelNetTagInterface = []byte("interface")
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	highND = common.ND(50, 1)
 	lowND  = common.ND(5, 1)
 
 	netFields = []common.LabeledDistributionMaker{
-		{[]byte("bytes_sent"), func() common.Distribution { return common.MWD(highND, 0) }},
-		{[]byte("bytes_recv"), func() common.Distribution { return common.MWD(highND, 0) }},
-		{[]byte("packets_sent"), func() common.Distribution { return common.MWD(highND, 0) }},
-		{[]byte("packets_recv"), func() common.Distribution { return common.MWD(highND, 0) }},
-		{[]byte("err_in"), func() common.Distribution { return common.MWD(lowND, 0) }},
-		{[]byte("err_out"), func() common.Distribution { return common.MWD(lowND, 0) }},
-		{[]byte("drop_in"), func() common.Distribution { return common.MWD(lowND, 0) }},
-		{[]byte("drop_out"), func() common.Distribution { return common.MWD(lowND, 0) }},
+		{[]byte("bytes_sent"), func(r *rand.Rand) common.Distribution { return common.MWD(highND.WithRand(r), 0) }},
+		{[]byte("bytes_recv"), func(r *rand.Rand) common.Distribution { return common.MWD(highND.WithRand(r), 0) }},
+		{[]byte("packets_sent"), func(r *rand.Rand) common.Distribution { return common.MWD(highND.WithRand(r), 0) }},
+		{[]byte("packets_recv"), func(r *rand.Rand) common.Distribution { return common.MWD(highND.WithRand(r), 0) }},
+		{[]byte("err_in"), func(r *rand.Rand) common.Distribution { return common.MWD(lowND.WithRand(r), 0) }},
+		{[]byte("err_out"), func(r *rand.Rand) common.Distribution { return common.MWD(lowND.WithRand(r), 0) }},
+		{[]byte("drop_in"), func(r *rand.Rand) common.Distribution { return common.MWD(lowND.WithRand(r), 0) }},
+		{[]byte("drop_out"), func(r *rand.Rand) common.Distribution { return common.MWD(lowND.WithRand(r), 0) }},
 	}
 )
 
@@ -36,9 +36,9 @@ type NetMeasurement struct {
 	interfaceName string
 }
 
-func NewNetMeasurement(start time.Time) *NetMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, netFields)
-	interfaceName := fmt.Sprintf("eth%d", rand.Intn(4))
+func NewNetMeasurement(start time.Time, r *rand.Rand) *NetMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, netFields, r)
+	interfaceName := fmt.Sprintf("eth%d", r.Intn(4))
 	return &NetMeasurement{
 		SubsystemMeasurement: sub,
 		interfaceName:        interfaceName,
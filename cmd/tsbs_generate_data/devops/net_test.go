@@ -1,16 +1,16 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
 func TestNetMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewNetMeasurement(now)
+	m := NewNetMeasurement(now, common.NewRand(42))
 	origName := string(m.interfaceName)
 	duration := time.Second
 	oldVals := map[string]float64{}
@@ -19,7 +19,6 @@ func TestNetMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -40,7 +39,7 @@ func TestNetMeasurementTick(t *testing.T) {
 
 func TestNetMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewNetMeasurement(now)
+	m := NewNetMeasurement(now, common.NewRand(42))
 	origName := m.interfaceName
 	duration := time.Second
 	m.Tick(duration)
@@ -15,19 +15,19 @@ var (
 	labelNginxTagPort   = []byte("port")
 	labelNginxTagServer = []byte("server")
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	nginxND = common.ND(5, 1)
 
 	nginxFields = []common.LabeledDistributionMaker{
-		{[]byte("accepts"), func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{[]byte("active"), func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{[]byte("handled"), func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{[]byte("reading"), func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{[]byte("requests"), func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{[]byte("waiting"), func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{[]byte("writing"), func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
+		{[]byte("accepts"), func(r *rand.Rand) common.Distribution { return common.MWD(nginxND.WithRand(r), 0) }},
+		{[]byte("active"), func(r *rand.Rand) common.Distribution { return common.CWD(nginxND.WithRand(r), 0, 100, 0) }},
+		{[]byte("handled"), func(r *rand.Rand) common.Distribution { return common.MWD(nginxND.WithRand(r), 0) }},
+		{[]byte("reading"), func(r *rand.Rand) common.Distribution { return common.CWD(nginxND.WithRand(r), 0, 100, 0) }},
+		{[]byte("requests"), func(r *rand.Rand) common.Distribution { return common.MWD(nginxND.WithRand(r), 0) }},
+		{[]byte("waiting"), func(r *rand.Rand) common.Distribution { return common.CWD(nginxND.WithRand(r), 0, 100, 0) }},
+		{[]byte("writing"), func(r *rand.Rand) common.Distribution { return common.CWD(nginxND.WithRand(r), 0, 100, 0) }},
 	}
 )
 
@@ -36,10 +36,10 @@ type NginxMeasurement struct {
 	port, serverName string
 }
 
-func NewNginxMeasurement(start time.Time) *NginxMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, nginxFields)
-	serverName := fmt.Sprintf("nginx_%d", rand.Intn(100000))
-	port := strconv.FormatInt(rand.Int63n(20000)+1024, 10)
+func NewNginxMeasurement(start time.Time, r *rand.Rand) *NginxMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, nginxFields, r)
+	serverName := fmt.Sprintf("nginx_%d", r.Intn(100000))
+	port := strconv.FormatInt(r.Int63n(20000)+1024, 10)
 	return &NginxMeasurement{
 		SubsystemMeasurement: sub,
 		port:                 port,
@@ -1,16 +1,16 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
 func TestNginxMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewNginxMeasurement(now)
+	m := NewNginxMeasurement(now, common.NewRand(42))
 	origName := string(m.serverName)
 	origPort := string(m.port)
 	duration := time.Second
@@ -20,7 +20,6 @@ func TestNginxMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -47,7 +46,7 @@ func TestNginxMeasurementTick(t *testing.T) {
 
 func TestNginxMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewNginxMeasurement(now)
+	m := NewNginxMeasurement(now, common.NewRand(42))
 	origName := m.serverName
 	origPort := m.port
 	duration := time.Second
@@ -1,6 +1,7 @@
 package devops
 
 import (
+	"math/rand"
 	"time"
 
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
@@ -10,29 +11,29 @@ import (
 var (
 	labelPostgresql = []byte("postgresl") // heap optimization
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	pgND     = common.ND(5, 1)
 	pgHighND = common.ND(1024, 1)
 
 	postgresqlFields = []common.LabeledDistributionMaker{
-		{[]byte("numbackends"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("xact_commit"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("xact_rollback"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("blks_read"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("blks_hit"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("tup_returned"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("tup_fetched"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("tup_inserted"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("tup_updated"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("tup_deleted"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("conflicts"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("temp_files"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("temp_bytes"), func() common.Distribution { return common.CWD(pgHighND, 0, 1024*1024*1024, 0) }},
-		{[]byte("deadlocks"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("blk_read_time"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
-		{[]byte("blk_write_time"), func() common.Distribution { return common.CWD(pgND, 0, 1000, 0) }},
+		{[]byte("numbackends"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("xact_commit"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("xact_rollback"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("blks_read"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("blks_hit"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("tup_returned"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("tup_fetched"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("tup_inserted"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("tup_updated"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("tup_deleted"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("conflicts"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("temp_files"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("temp_bytes"), func(r *rand.Rand) common.Distribution { return common.CWD(pgHighND.WithRand(r), 0, 1024*1024*1024, 0) }},
+		{[]byte("deadlocks"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("blk_read_time"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("blk_write_time"), func(r *rand.Rand) common.Distribution { return common.CWD(pgND.WithRand(r), 0, 1000, 0) }},
 	}
 )
 
@@ -40,8 +41,8 @@ type PostgresqlMeasurement struct {
 	*common.SubsystemMeasurement
 }
 
-func NewPostgresqlMeasurement(start time.Time) *PostgresqlMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, postgresqlFields)
+func NewPostgresqlMeasurement(start time.Time, r *rand.Rand) *PostgresqlMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, postgresqlFields, r)
 	return &PostgresqlMeasurement{sub}
 }
 
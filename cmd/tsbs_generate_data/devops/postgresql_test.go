@@ -1,16 +1,16 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
 func TestPostgresqlMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	m := NewPostgresqlMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(postgresqlFields)
@@ -18,7 +18,6 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
@@ -33,7 +32,7 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 
 func TestPostgresqlMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	m := NewPostgresqlMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	m.Tick(duration)
 
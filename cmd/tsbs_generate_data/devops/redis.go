@@ -18,48 +18,52 @@ var (
 
 	sixteenGB = float64(16 * 1024 * 1024 * 1024)
 
-	// Reuse NormalDistributions as arguments to other distributions. This is
-	// safe to do because the higher-level distribution advances the ND and
-	// immediately uses its value and saves the state
+	// NormalDistributions used as arguments to other distributions. Each of
+	// them gets a copy drawing from the random numbers of its measurement,
+	// see WithRand
 	redisLowND  = common.ND(5, 1)
 	redisHighND = common.ND(50, 1)
 
 	redisFields = []common.LabeledDistributionMaker{
-		{[]byte("total_connections_received"), func() common.Distribution { return common.MWD(redisLowND, 0) }},
-		{[]byte("expired_keys"), func() common.Distribution { return common.MWD(redisHighND, 0) }},
-		{[]byte("evicted_keys"), func() common.Distribution { return common.MWD(redisHighND, 0) }},
-		{[]byte("keyspace_hits"), func() common.Distribution { return common.MWD(redisHighND, 0) }},
-		{[]byte("keyspace_misses"), func() common.Distribution { return common.MWD(redisHighND, 0) }},
+		{[]byte("total_connections_received"), func(r *rand.Rand) common.Distribution { return common.MWD(redisLowND.WithRand(r), 0) }},
+		{[]byte("expired_keys"), func(r *rand.Rand) common.Distribution { return common.MWD(redisHighND.WithRand(r), 0) }},
+		{[]byte("evicted_keys"), func(r *rand.Rand) common.Distribution { return common.MWD(redisHighND.WithRand(r), 0) }},
+		{[]byte("keyspace_hits"), func(r *rand.Rand) common.Distribution { return common.MWD(redisHighND.WithRand(r), 0) }},
+		{[]byte("keyspace_misses"), func(r *rand.Rand) common.Distribution { return common.MWD(redisHighND.WithRand(r), 0) }},
 
-		{[]byte("instantaneous_ops_per_sec"), func() common.Distribution { return common.WD(common.ND(1, 1), 0) }},
-		{[]byte("instantaneous_input_kbps"), func() common.Distribution { return common.WD(common.ND(1, 1), 0) }},
-		{[]byte("instantaneous_output_kbps"), func() common.Distribution { return common.WD(common.ND(1, 1), 0) }},
-		{[]byte("connected_clients"), func() common.Distribution { return common.CWD(redisHighND, 0, 10000, 0) }},
-		{[]byte("used_memory"), func() common.Distribution { return common.CWD(redisHighND, 0, sixteenGB, sixteenGB/2) }},
-		{[]byte("used_memory_rss"), func() common.Distribution { return common.CWD(redisHighND, 0, sixteenGB, sixteenGB/2) }},
-		{[]byte("used_memory_peak"), func() common.Distribution { return common.CWD(redisHighND, 0, sixteenGB, sixteenGB/2) }},
-		{[]byte("used_memory_lua"), func() common.Distribution { return common.CWD(redisHighND, 0, sixteenGB, sixteenGB/2) }},
-		{[]byte("rdb_changes_since_last_save"), func() common.Distribution { return common.CWD(redisHighND, 0, 10000, 0) }},
+		{[]byte("instantaneous_ops_per_sec"), func(r *rand.Rand) common.Distribution { return common.WD(common.ND(1, 1).WithRand(r), 0) }},
+		{[]byte("instantaneous_input_kbps"), func(r *rand.Rand) common.Distribution { return common.WD(common.ND(1, 1).WithRand(r), 0) }},
+		{[]byte("instantaneous_output_kbps"), func(r *rand.Rand) common.Distribution { return common.WD(common.ND(1, 1).WithRand(r), 0) }},
+		{[]byte("connected_clients"), func(r *rand.Rand) common.Distribution { return common.CWD(redisHighND.WithRand(r), 0, 10000, 0) }},
+		{[]byte("used_memory"), newRedisMemoryDistribution},
+		{[]byte("used_memory_rss"), newRedisMemoryDistribution},
+		{[]byte("used_memory_peak"), newRedisMemoryDistribution},
+		{[]byte("used_memory_lua"), newRedisMemoryDistribution},
+		{[]byte("rdb_changes_since_last_save"), func(r *rand.Rand) common.Distribution { return common.CWD(redisHighND.WithRand(r), 0, 10000, 0) }},
 
-		{[]byte("sync_full"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("sync_partial_ok"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("sync_partial_err"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("pubsub_channels"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("pubsub_patterns"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("latest_fork_usec"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("connected_slaves"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("master_repl_offset"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("repl_backlog_active"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("repl_backlog_size"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("repl_backlog_histlen"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("mem_fragmentation_ratio"), func() common.Distribution { return common.CWD(redisLowND, 0, 100, 0) }},
-		{[]byte("used_cpu_sys"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("used_cpu_user"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("used_cpu_sys_children"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
-		{[]byte("used_cpu_user_children"), func() common.Distribution { return common.CWD(redisLowND, 0, 1000, 0) }},
+		{[]byte("sync_full"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("sync_partial_ok"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("sync_partial_err"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("pubsub_channels"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("pubsub_patterns"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("latest_fork_usec"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("connected_slaves"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("master_repl_offset"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("repl_backlog_active"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("repl_backlog_size"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("repl_backlog_histlen"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("mem_fragmentation_ratio"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 100, 0) }},
+		{[]byte("used_cpu_sys"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("used_cpu_user"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("used_cpu_sys_children"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
+		{[]byte("used_cpu_user_children"), func(r *rand.Rand) common.Distribution { return common.CWD(redisLowND.WithRand(r), 0, 1000, 0) }},
 	}
 )
 
+func newRedisMemoryDistribution(r *rand.Rand) common.Distribution {
+	return common.CWD(redisHighND.WithRand(r), 0, sixteenGB, sixteenGB/2)
+}
+
 type RedisMeasurement struct {
 	*common.SubsystemMeasurement
 
@@ -67,10 +71,10 @@ type RedisMeasurement struct {
 	uptime           time.Duration
 }
 
-func NewRedisMeasurement(start time.Time) *RedisMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, redisFields)
-	serverName := fmt.Sprintf("redis_%d", rand.Intn(100000))
-	port := strconv.FormatInt(rand.Int63n(20000)+1024, 10)
+func NewRedisMeasurement(start time.Time, r *rand.Rand) *RedisMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, redisFields, r)
+	serverName := fmt.Sprintf("redis_%d", r.Intn(100000))
+	port := strconv.FormatInt(r.Int63n(20000)+1024, 10)
 	return &RedisMeasurement{
 		SubsystemMeasurement: sub,
 		port:                 port,
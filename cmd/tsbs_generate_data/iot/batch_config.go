@@ -32,9 +32,9 @@ type batchConfig struct {
 	OutOfOrderEntries   map[int]bool
 }
 
-func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int) *batchConfig {
+func newBatchConfig(r *rand.Rand, outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int) *batchConfig {
 
-	batchMissing := rand.Float64() < bMissingChance
+	batchMissing := r.Float64() < bMissingChance
 
 	if batchMissing {
 		return &batchConfig{
@@ -42,11 +42,11 @@ func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagC
 		}
 	}
 
-	batchOutOfOrder := rand.Float64() < bOutOfOrderChance
+	batchOutOfOrder := r.Float64() < bOutOfOrderChance
 
 	batchInsertPrevious := false
 	if outOfOrderBatchCount > 0 {
-		batchInsertPrevious = rand.Float64() < bInsertPreviousChance
+		batchInsertPrevious = r.Float64() < bInsertPreviousChance
 	}
 
 	zeroFields := make(map[int]int)
@@ -56,26 +56,26 @@ func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagC
 	outOfOrderEntries := make(map[int]bool)
 
 	for i := 0; i < defaultBatchSize; i++ {
-		if outOfOrderEntryCount > 0 && rand.Float64() < eInsertPreviousChance {
+		if outOfOrderEntryCount > 0 && r.Float64() < eInsertPreviousChance {
 			insertPreviousEntry[i] = true
 			outOfOrderEntryCount--
 		}
 
-		if rand.Float64() < eMissingChance {
+		if r.Float64() < eMissingChance {
 			missingEntries[i] = true
 			// Since the entry is missing, no point in setting zero values or making it out-of-order.
 			continue
 		}
 
-		if fieldCount > 0 && rand.Float64() < zeroFieldChance {
-			zeroFields[i] = rand.Intn(fieldCount)
+		if fieldCount > 0 && r.Float64() < zeroFieldChance {
+			zeroFields[i] = r.Intn(fieldCount)
 		}
 
-		if tagCount > 0 && rand.Float64() < zeroTagChance {
-			zeroTags[i] = rand.Intn(tagCount)
+		if tagCount > 0 && r.Float64() < zeroTagChance {
+			zeroTags[i] = r.Intn(tagCount)
 		}
 
-		if rand.Float64() < eOutOfOrderChance {
+		if r.Float64() < eOutOfOrderChance {
 			outOfOrderEntries[i] = true
 		}
 	}
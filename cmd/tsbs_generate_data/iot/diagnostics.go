@@ -1,6 +1,7 @@
 package iot
 
 import (
+	"math/rand"
 	"time"
 
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
@@ -26,27 +27,27 @@ var (
 	diagnosticsFields = []common.LabeledDistributionMaker{
 		{
 			Label: labelFuelState,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					&customFuelDistribution{common.CWD(fuelUD, 0, maxFuel, maxFuel)},
+					&customFuelDistribution{common.CWD(fuelUD.WithRand(r), 0, maxFuel, maxFuel)},
 					1,
 				)
 			},
 		},
 		{
 			Label: labelCurrentLoad,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.LD(loadSaddleUD, loadUD, 1-loadChangeChance),
+					common.LD(loadSaddleUD.WithRand(r), loadUD.WithRand(r), 1-loadChangeChance),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelStatus,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(statusND, 0, 5, 0),
+					common.CWD(statusND.WithRand(r), 0, 5, 0),
 					0,
 				)
 			},
@@ -83,9 +84,10 @@ func (m *DiagnosticsMeasurement) ToPoint(p *serialize.Point) {
 	p.AppendField(diagnosticsFields[2].Label, int64(m.Distributions[2].Get()))
 }
 
-// NewDiagnosticsMeasurement creates a DiagnosticsMeasurement with start time.
-func NewDiagnosticsMeasurement(start time.Time) *DiagnosticsMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diagnosticsFields)
+// NewDiagnosticsMeasurement creates a DiagnosticsMeasurement with start time,
+// drawing random numbers from r.
+func NewDiagnosticsMeasurement(start time.Time, r *rand.Rand) *DiagnosticsMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diagnosticsFields, r)
 
 	return &DiagnosticsMeasurement{
 		SubsystemMeasurement: sub,
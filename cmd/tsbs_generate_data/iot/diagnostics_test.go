@@ -10,7 +10,7 @@ import (
 
 func TestDiagnosticsMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiagnosticsMeasurement(now)
+	m := NewDiagnosticsMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	m.Tick(duration)
 
@@ -35,63 +35,63 @@ var (
 	readingsFields = []common.LabeledDistributionMaker{
 		{
 			Label: labelLatitude,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(geoStepUD, -90.0, 90.0, rand.Float64()*maxLatitude),
+					common.CWD(geoStepUD.WithRand(r), -90.0, 90.0, r.Float64()*maxLatitude),
 					5,
 				)
 			},
 		},
 		{
 			Label: labelLongitude,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(geoStepUD, -180, 180, rand.Float64()*maxLongitude),
+					common.CWD(geoStepUD.WithRand(r), -180, 180, r.Float64()*maxLongitude),
 					5,
 				)
 			},
 		},
 		{
 			Label: labelElevation,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(bigUD, 0, maxElevation, rand.Float64()*500),
+					common.CWD(bigUD.WithRand(r), 0, maxElevation, r.Float64()*500),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelVelocity,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(bigUD, 0, maxVelocity, 0),
+					common.CWD(bigUD.WithRand(r), 0, maxVelocity, 0),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelHeading,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(smallUD, 0, maxHeading, rand.Float64()*maxHeading),
+					common.CWD(smallUD.WithRand(r), 0, maxHeading, r.Float64()*maxHeading),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelGrade,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(smallUD, 0, maxGrade, 0),
+					common.CWD(smallUD.WithRand(r), 0, maxGrade, 0),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelFuelConsumption,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(smallUD, 0, maxFuelConsumption, maxFuelConsumption/2),
+					common.CWD(smallUD.WithRand(r), 0, maxFuelConsumption, maxFuelConsumption/2),
 					1,
 				)
 			},
@@ -115,9 +115,10 @@ func (m *ReadingsMeasurement) ToPoint(p *serialize.Point) {
 	}
 }
 
-// NewReadingsMeasurement creates a new ReadingsMeasurement with start time.
-func NewReadingsMeasurement(start time.Time) *ReadingsMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, readingsFields)
+// NewReadingsMeasurement creates a new ReadingsMeasurement with start time,
+// drawing random numbers from r.
+func NewReadingsMeasurement(start time.Time, r *rand.Rand) *ReadingsMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, readingsFields, r)
 
 	return &ReadingsMeasurement{
 		SubsystemMeasurement: sub,
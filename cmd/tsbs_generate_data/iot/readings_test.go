@@ -4,12 +4,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
 func TestReadingsMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewReadingsMeasurement(now)
+	m := NewReadingsMeasurement(now, common.NewRand(42))
 	duration := time.Second
 	m.Tick(duration)
 
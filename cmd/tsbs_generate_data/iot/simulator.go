@@ -30,10 +30,19 @@ func (sc *SimulatorConfig) NewSimulator(interval time.Duration, limit uint64) co
 		}
 	}
 
+	// Batches mix the entries of all trucks, so their configs draw from a
+	// source of random numbers of the whole simulation. The entries of a truck
+	// do not depend on the number of trucks, but which of them are missing,
+	// zeroed or out of order does.
+	r := common.NewRand(sc.Seed)
+	configGenerator := func(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int) *batchConfig {
+		return newBatchConfig(r, outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount)
+	}
+
 	return &Simulator{
 		base:            s,
 		batchSize:       defaultBatchSize,
-		configGenerator: newBatchConfig,
+		configGenerator: configGenerator,
 		maxFieldCount:   maxFieldCount,
 	}
 }
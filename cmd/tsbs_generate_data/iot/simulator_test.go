@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -520,3 +521,73 @@ func TestSimulatorTagTypes(t *testing.T) {
 		}
 	}
 }
+
+// TestSimulatorTruckAcrossScale shows which data of a truck depends on the
+// number of trucks: the entries of a truck are the same, but since batches
+// mix the entries of all trucks, which of them are missing, zeroed or out of
+// order is not.
+func TestSimulatorTruckAcrossScale(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	// generate returns the fields of the entries of truck_2 by measurement
+	// and timestamp
+	generate := func(scale uint64, batched bool) map[string][]string {
+		sc := &SimulatorConfig{
+			Start: start,
+			End:   start.Add(time.Hour),
+
+			InitGeneratorScale:   scale,
+			GeneratorScale:       scale,
+			GeneratorConstructor: NewTruck,
+			Seed:                 123,
+		}
+		s := sc.NewSimulator(10*time.Second, 0).(*Simulator)
+		if !batched {
+			s.batchSize = 0
+		}
+		entries := make(map[string][]string)
+		serializer := &serialize.InfluxSerializer{}
+		for !s.Finished() {
+			p := serialize.NewPoint()
+			if !s.Next(p) {
+				continue
+			}
+			var b bytes.Buffer
+			if err := serializer.Serialize(p, &b); err != nil {
+				t.Fatalf("cannot serialize: %v", err)
+			}
+			line := strings.TrimSpace(b.String())
+			if !strings.Contains(line, "name=truck_2,") {
+				continue
+			}
+			parts := strings.Split(line, " ")
+			key := strings.Split(parts[0], ",")[0] + " " + parts[len(parts)-1]
+			entries[key] = strings.Split(parts[len(parts)-2], ",")
+		}
+		return entries
+	}
+
+	small := generate(4, false)
+	large := generate(8, false)
+	if len(small) == 0 || !reflect.DeepEqual(small, large) {
+		t.Fatalf("entries of truck_2 differ between scales")
+	}
+
+	for _, scale := range []uint64{4, 8} {
+		for key, fields := range generate(scale, true) {
+			want, ok := small[key]
+			if !ok {
+				t.Errorf("scale %d: unknown entry of truck_2: %s", scale, key)
+				continue
+			}
+			all := make(map[string]bool)
+			for _, f := range want {
+				all[f] = true
+			}
+			for _, f := range fields {
+				if !all[f] {
+					t.Errorf("scale %d: incorrect field of truck_2 entry %s: %s", scale, key, f)
+				}
+			}
+		}
+	}
+}
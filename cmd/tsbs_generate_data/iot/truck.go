@@ -2,7 +2,6 @@ package iot
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
@@ -82,31 +81,33 @@ func (t Truck) Tags() []common.Tag {
 	return t.tags
 }
 
-func newTruckMeasurements(start time.Time) []common.SimulatedMeasurement {
+func newTruckMeasurements(start time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewReadingsMeasurement(start),
-		NewDiagnosticsMeasurement(start),
+		NewReadingsMeasurement(start, common.MeasurementRand(seed, labelReadings)),
+		NewDiagnosticsMeasurement(start, common.MeasurementRand(seed, labelDiagnostics)),
 	}
 }
 
-// NewTruck creates a new truck in a simulated iot use case
-func NewTruck(i int, start time.Time) common.Generator {
-	truck := newTruckWithMeasurementGenerator(i, start, newTruckMeasurements)
+// NewTruck creates a new truck in a simulated iot use case, seed is the seed
+// of the truck (see common.EntitySeed)
+func NewTruck(i int, start time.Time, seed int64) common.Generator {
+	truck := newTruckWithMeasurementGenerator(i, start, seed, newTruckMeasurements)
 	return &truck
 }
 
-func newTruckWithMeasurementGenerator(i int, start time.Time, generator func(time.Time) []common.SimulatedMeasurement) Truck {
-	sm := generator(start)
+func newTruckWithMeasurementGenerator(i int, start time.Time, seed int64, generator func(time.Time, int64) []common.SimulatedMeasurement) Truck {
+	sm := generator(start, seed)
 
-	m := modelChoices[rand.Intn(len(modelChoices))]
+	r := common.NewRand(seed)
+	m := modelChoices[r.Intn(len(modelChoices))]
 
 	h := Truck{
 		tags: []common.Tag{
 			{Key: []byte("name"), Value: fmt.Sprintf(truckNameFmt, i)},
-			{Key: []byte("fleet"), Value: common.RandomStringSliceChoice(usecase.FleetChoices)},
-			{Key: []byte("driver"), Value: common.RandomStringSliceChoice(driverChoices)},
+			{Key: []byte("fleet"), Value: common.RandomStringSliceChoice(r, usecase.FleetChoices)},
+			{Key: []byte("driver"), Value: common.RandomStringSliceChoice(r, driverChoices)},
 			{Key: []byte("model"), Value: m.Name},
-			{Key: []byte("device_version"), Value: common.RandomStringSliceChoice(deviceVersionChoices)},
+			{Key: []byte("device_version"), Value: common.RandomStringSliceChoice(r, deviceVersionChoices)},
 			{Key: []byte("load_capacity"), Value: m.LoadCapacity},
 			{Key: []byte("fuel_capacity"), Value: m.FuelCapacity},
 			{Key: []byte("nominal_fuel_consumption"), Value: m.FuelConsumption},
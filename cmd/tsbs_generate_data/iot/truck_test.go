@@ -8,7 +8,7 @@ import (
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 )
 
-func testGenerator(s time.Time) []common.SimulatedMeasurement {
+func testGenerator(s time.Time, seed int64) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
 		&testMeasurement{ticks: 0},
 	}
@@ -24,7 +24,7 @@ func (m *testMeasurement) ToPoint(_ *serialize.Point) {}
 func TestNewTruckMeasurements(t *testing.T) {
 	start := time.Now()
 
-	measurements := newTruckMeasurements(start)
+	measurements := newTruckMeasurements(start, 42)
 
 	if got := len(measurements); got != 2 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 2)
@@ -44,7 +44,7 @@ func TestNewTruckMeasurements(t *testing.T) {
 
 func TestNewTruck(t *testing.T) {
 	start := time.Now()
-	generator := NewTruck(1, start)
+	generator := NewTruck(1, start, common.EntitySeed(42, 1))
 
 	truck := generator.(*Truck)
 
@@ -59,7 +59,7 @@ func TestNewTruck(t *testing.T) {
 
 func TestTruckTickAll(t *testing.T) {
 	now := time.Now()
-	truck := newTruckWithMeasurementGenerator(0, now, testGenerator)
+	truck := newTruckWithMeasurementGenerator(0, now, 42, testGenerator)
 	if got := truck.simulatedMeasurements[0].(*testMeasurement).ticks; got != 0 {
 		t.Errorf("ticks not equal to 0 to start: got %d", got)
 	}
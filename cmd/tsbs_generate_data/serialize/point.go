@@ -19,6 +19,9 @@ type Point struct {
 	fieldKeys       [][]byte
 	fieldValues     []interface{}
 	timestamp       *time.Time
+
+	// ownTimestamp holds the timestamp after OwnTimestamp
+	ownTimestamp time.Time
 }
 
 // NewPoint returns a new empty Point
@@ -54,6 +57,16 @@ func (p *Point) Reset() {
 	p.timestamp = nil
 }
 
+// OwnTimestamp makes the Point keep its own copy of its timestamp, which is
+// otherwise shared with whatever set it, e.g. a measurement that moves on to
+// the next interval.
+func (p *Point) OwnTimestamp() {
+	if p.timestamp != nil {
+		p.ownTimestamp = *p.timestamp
+		p.timestamp = &p.ownTimestamp
+	}
+}
+
 // SetTimestamp sets the Timestamp for this data point
 func (p *Point) SetTimestamp(t *time.Time) {
 	p.timestamp = t
@@ -113,6 +126,11 @@ func (p *Point) TagKeys() [][]byte {
 	return p.tagKeys
 }
 
+// TagValues returns the Point's tag values, in the order of TagKeys
+func (p *Point) TagValues() []interface{} {
+	return p.tagValues
+}
+
 // AppendTag adds a tag with a given key and value to this data point
 func (p *Point) AppendTag(key []byte, value interface{}) {
 	p.tagKeys = append(p.tagKeys, key)
@@ -209,6 +209,23 @@ func TestSetTimestamp(t *testing.T) {
 	}
 }
 
+func TestOwnTimestamp(t *testing.T) {
+	p := NewPoint()
+	p.OwnTimestamp() // no timestamp yet
+	if p.timestamp != nil {
+		t.Errorf("timestamp set without one")
+	}
+
+	now := time.Now()
+	p.SetTimestamp(&now)
+	p.OwnTimestamp()
+	later := now.Add(time.Second)
+	now = later
+	if p.timestamp == &now || p.timestamp.Equal(later) {
+		t.Errorf("timestamp still shared: got %v", p.timestamp)
+	}
+}
+
 func TestSetMeasurementName(t *testing.T) {
 	p := NewPoint()
 	name := []byte("foo")
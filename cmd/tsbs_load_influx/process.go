@@ -44,6 +44,17 @@ func (p *processor) Close(_ bool) {
 }
 
 func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	metricCnt, rowCnt, err := p.ProcessBatchWithError(b, doLoad)
+	if err != nil {
+		fatal("Error writing: %s\n", err.Error())
+	}
+	return metricCnt, rowCnt
+}
+
+// ProcessBatchWithError writes the batch, waiting for as long as the server
+// asks for backpressure. Any other error is returned so the loader can retry
+// the batch according to its retry policy.
+func (p *processor) ProcessBatchWithError(b load.Batch, doLoad bool) (uint64, uint64, error) {
 	batch := b.(*batch)
 
 	// Write the batch: try until backoff is not needed.
@@ -70,7 +81,9 @@ func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
 			}
 		}
 		if err != nil {
-			fatal("Error writing: %s\n", err.Error())
+			// Keep the batch buffer, it is needed if the batch is retried;
+			// DiscardBatch returns it once the loader gives up
+			return 0, 0, err
 		}
 	}
 	metricCnt := batch.metrics
@@ -79,7 +92,14 @@ func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
 	// Return the batch buffer to the pool.
 	batch.buf.Reset()
 	bufPool.Put(batch.buf)
-	return metricCnt, rowCnt
+	return metricCnt, rowCnt, nil
+}
+
+// DiscardBatch returns the buffer of a batch the loader gave up on to the pool
+func (p *processor) DiscardBatch(b load.Batch) {
+	batch := b.(*batch)
+	batch.buf.Reset()
+	bufPool.Put(batch.buf)
 }
 
 func (p *processor) processBackoffMessages(workerID int) {
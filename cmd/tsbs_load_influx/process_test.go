@@ -240,3 +240,23 @@ func TestProcessorProcessBackoffMessages(t *testing.T) {
 		}
 	}
 }
+
+// The loader only discards batches of processors implementing it
+var _ load.ProcessorWithDiscard = &processor{}
+
+func TestProcessorDiscardBatch(t *testing.T) {
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 0, 1024))
+		},
+	}
+	f := &factory{}
+	b := f.New().(*batch)
+	b.Append(&load.Point{Data: []byte("tag1=tag1val col1=0.0 140")})
+
+	p := &processor{}
+	p.DiscardBatch(b)
+	if got := b.buf.Len(); got != 0 {
+		t.Errorf("discarded batch buffer not reset: got %d bytes", got)
+	}
+}
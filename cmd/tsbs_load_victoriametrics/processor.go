@@ -2,9 +2,9 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/timescale/tsbs/load"
 )
@@ -18,31 +18,37 @@ func (p *processor) Init(workerNum int, _ bool) {
 }
 
 func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (metricCount, rowCount uint64) {
+	mc, rc, err := p.ProcessBatchWithError(b, doLoad)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return mc, rc
+}
+
+// ProcessBatchWithError sends the batch once; failed requests are retried by
+// the loader according to its retry policy.
+func (p *processor) ProcessBatchWithError(b load.Batch, doLoad bool) (metricCount, rowCount uint64, err error) {
 	batch := b.(*batch)
 	if !doLoad {
-		return batch.metrics, batch.rows
+		return batch.metrics, batch.rows, nil
 	}
-	mc, rc := p.do(batch)
-	return mc, rc
+	return p.do(batch)
 }
 
-func (p *processor) do(b *batch) (uint64, uint64) {
-	for {
-		r := bytes.NewReader(b.buf.Bytes())
-		req, err := http.NewRequest("POST", p.url, r)
-		if err != nil {
-			log.Fatalf("error while creating new request: %s", err)
-		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Fatalf("error while executing request: %s", err)
-		}
-		resp.Body.Close()
-		if resp.StatusCode == http.StatusNoContent {
-			b.buf.Reset()
-			return b.metrics, b.rows
-		}
-		log.Printf("server returned HTTP status %d. Retrying", resp.StatusCode)
-		time.Sleep(time.Millisecond * 10)
+func (p *processor) do(b *batch) (uint64, uint64, error) {
+	r := bytes.NewReader(b.buf.Bytes())
+	req, err := http.NewRequest("POST", p.url, r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error while creating new request: %s", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error while executing request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return 0, 0, fmt.Errorf("server returned HTTP status %d", resp.StatusCode)
 	}
+	b.buf.Reset()
+	return b.metrics, b.rows, nil
 }
@@ -104,3 +104,33 @@ func startFakeVMServer(t *testing.T) *fakeVMServer {
 	vm.server = s
 	return vm
 }
+
+func TestProcessorProcessBatchWithError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	f := &factory{}
+	b := f.New().(*batch)
+	b.Append(&load.Point{
+		Data: []byte("tag1=tag1val,tag2=tag2val col1=0.0,col2=0.0 140"),
+	})
+
+	p := &processor{url: s.URL}
+	metrics, rows, err := p.ProcessBatchWithError(b, true)
+	if err == nil {
+		t.Fatalf("expected an error for HTTP status %d", http.StatusServiceUnavailable)
+	}
+	if metrics != 0 || rows != 0 {
+		t.Errorf("expected no metrics or rows for a failed batch; got %d, %d", metrics, rows)
+	}
+	if b.buf.Len() == 0 {
+		t.Errorf("expected batch to be kept for a retry")
+	}
+
+	s.Close()
+	if _, _, err = p.ProcessBatchWithError(b, true); err == nil {
+		t.Fatalf("expected an error for an unreachable server")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	typeMongo = "mongo"
+)
+
+// queryTypes maps the query types that can be inspected to a constructor
+var queryTypes = map[string]func() query.Query{
+	"cassandra":   func() query.Query { return &query.Cassandra{} },
+	"clickhouse":  func() query.Query { return &query.ClickHouse{} },
+	"cratedb":     func() query.Query { return &query.CrateDB{} },
+	"http":        func() query.Query { return &query.HTTP{} },
+	typeMongo:     func() query.Query { return &query.Mongo{} },
+	"mysql":       func() query.Query { return &query.MysqlRequest{} },
+	"siridb":      func() query.Query { return &query.SiriDB{} },
+	"timescaledb": func() query.Query { return &query.TimescaleDB{} },
+}
+
+// typeAliases maps the --format of tsbs_generate_queries to the query types
+// of databases queried over HTTP
+var typeAliases = map[string]string{
+	"akumuli":         "http",
+	"influx":          "http",
+	"victoriametrics": "http",
+}
+
+func init() {
+	// needed for decoding the BSON documents of mongo queries, see
+	// tsbs_run_queries_mongo
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]map[string]interface{}{})
+	gob.Register(bson.M{})
+	gob.Register(bson.D{})
+	gob.Register([]bson.M{})
+	gob.Register(time.Time{})
+}
+
+// queryTypeNames returns the names accepted by --type
+func queryTypeNames() []string {
+	var names []string
+	for name := range queryTypes {
+		names = append(names, name)
+	}
+	for name := range typeAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newQueryFn returns the constructor for the queries of the given type
+func newQueryFn(queryType string) (func() query.Query, error) {
+	if alias, ok := typeAliases[queryType]; ok {
+		queryType = alias
+	}
+	fn, ok := queryTypes[queryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown query type '%s': must be one of %s", queryType, strings.Join(queryTypeNames(), ", "))
+	}
+	return fn, nil
+}
+
+// inspector dumps the queries of a gob stream as text or JSON lines, or
+// converts JSON lines back to gob
+type inspector struct {
+	newQuery func() query.Query
+	// label only keeps the queries whose label matches, nil keeps all
+	label *regexp.Regexp
+}
+
+// dump decodes the queries from r and writes the matching ones to w in the
+// given output format. It returns the number of queries written.
+func (in *inspector) dump(r io.Reader, w io.Writer, output string) (int, error) {
+	if output != outputText && output != outputJSON {
+		return 0, fmt.Errorf("unknown output '%s': must be %s or %s", output, outputText, outputJSON)
+	}
+	dec := gob.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	n := 0
+	for i := uint64(0); ; i++ {
+		q := in.newQuery()
+		err := dec.Decode(q)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("cannot decode query %d: %v", i, err)
+		}
+		if in.label != nil && !in.label.Match(q.HumanLabelName()) {
+			continue
+		}
+		n++
+		if output == outputJSON {
+			err = enc.Encode(queryFields(q))
+		} else {
+			err = writeText(w, i, q)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// convert reads queries as JSON lines from r, in the format written by dump,
+// and encodes the matching ones to w as gob. It returns the number of
+// queries written.
+func (in *inspector) convert(r io.Reader, w io.Writer) (int, error) {
+	if _, ok := in.newQuery().(*query.Mongo); ok {
+		return 0, fmt.Errorf("cannot convert %s queries, their BSON documents lose their types in JSON", typeMongo)
+	}
+	dec := json.NewDecoder(r)
+	enc := gob.NewEncoder(w)
+	n := 0
+	for i := 0; ; i++ {
+		var fields map[string]json.RawMessage
+		err := dec.Decode(&fields)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("cannot read line %d: %v", i+1, err)
+		}
+		q := in.newQuery()
+		if err := setQueryFields(q, fields); err != nil {
+			return n, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if in.label != nil && !in.label.Match(q.HumanLabelName()) {
+			continue
+		}
+		if err := enc.Encode(q); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// queryFields returns the exported fields of a query by name, with byte
+// slices as strings so that queries can be read and edited
+func queryFields(q query.Query) map[string]interface{} {
+	v := reflect.ValueOf(q).Elem()
+	fields := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if b, ok := v.Field(i).Interface().([]byte); ok {
+			fields[f.Name] = string(b)
+		} else {
+			fields[f.Name] = v.Field(i).Interface()
+		}
+	}
+	return fields
+}
+
+// setQueryFields sets the exported fields of a query from their JSON
+// values, the reverse of queryFields
+func setQueryFields(q query.Query, fields map[string]json.RawMessage) error {
+	v := reflect.ValueOf(q).Elem()
+	for name, raw := range fields {
+		f, ok := v.Type().FieldByName(name)
+		if !ok || f.PkgPath != "" {
+			return fmt.Errorf("unknown field '%s'", name)
+		}
+		field := v.FieldByIndex(f.Index)
+		if field.Type() == reflect.TypeOf([]byte(nil)) {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return fmt.Errorf("field '%s': %v", name, err)
+			}
+			field.SetBytes([]byte(s))
+			continue
+		}
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("field '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+// writeText writes a query as readable text: its number, label and
+// description, the time range if the query has one, and all other fields
+func writeText(w io.Writer, i uint64, q query.Query) error {
+	fields := queryFields(q)
+	lines := []string{
+		fmt.Sprintf("query %d: %s", i, q.HumanLabelName()),
+		fmt.Sprintf("description: %s", q.HumanDescriptionName()),
+	}
+	if start, end, ok := timeRange(q); ok {
+		lines = append(lines, fmt.Sprintf("time range: %s - %s", start.Format(time.RFC3339), end.Format(time.RFC3339)))
+	}
+	var names []string
+	for name := range fields {
+		if name != "HumanLabel" && name != "HumanDescription" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := fields[name]
+		if s, ok := value.(string); ok && strings.Contains(s, "\n") {
+			lines = append(lines, fmt.Sprintf("%s:\n%s", name, s))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %v", name, value))
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", strings.Join(lines, "\n"))
+	return err
+}
+
+// timeRange returns the time range of queries that carry it explicitly
+func timeRange(q query.Query) (time.Time, time.Time, bool) {
+	switch q := q.(type) {
+	case *query.HTTP:
+		if q.StartTimestamp != 0 || q.EndTimestamp != 0 {
+			return time.Unix(0, q.StartTimestamp).UTC(), time.Unix(0, q.EndTimestamp).UTC(), true
+		}
+	case *query.Cassandra:
+		return q.TimeStart.UTC(), q.TimeEnd.UTC(), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+var testQueries = []*query.TimescaleDB{
+	{
+		HumanLabel:       []byte("TimescaleDB last row per host"),
+		HumanDescription: []byte("TimescaleDB last row per host"),
+		Hypertable:       []byte("cpu"),
+		SqlQuery:         []byte("SELECT *\nFROM cpu"),
+	},
+	{
+		HumanLabel:       []byte("TimescaleDB max cpu"),
+		HumanDescription: []byte("TimescaleDB max cpu: 2016-01-01T00:00:00Z"),
+		Hypertable:       []byte("cpu"),
+		SqlQuery:         []byte("SELECT max(usage_user) FROM cpu"),
+	},
+}
+
+func encodeTestQueries(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for _, q := range testQueries {
+		if err := enc.Encode(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestNewQueryFn(t *testing.T) {
+	fn, err := newQueryFn("influx")
+	if err != nil {
+		t.Fatalf("unexpected error for alias: %v", err)
+	}
+	if _, ok := fn().(*query.HTTP); !ok {
+		t.Errorf("influx is not an HTTP query")
+	}
+	fn, err = newQueryFn("timescaledb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := fn().(*query.TimescaleDB); !ok {
+		t.Errorf("timescaledb is not a TimescaleDB query")
+	}
+	if _, err := newQueryFn("foo"); err == nil {
+		t.Errorf("unexpected lack of error for unknown type")
+	}
+}
+
+func TestDumpText(t *testing.T) {
+	in := &inspector{newQuery: queryTypes["timescaledb"]}
+	var out bytes.Buffer
+	n, err := in.dump(encodeTestQueries(t), &out, outputText)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("incorrect number of queries: got %d want %d", n, 2)
+	}
+	want := "query 0: TimescaleDB last row per host\n" +
+		"description: TimescaleDB last row per host\n" +
+		"Hypertable: cpu\n" +
+		"SqlQuery:\nSELECT *\nFROM cpu\n\n"
+	if got := out.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("incorrect text:\ngot\n%s\nwant prefix\n%s", got, want)
+	}
+}
+
+func TestDumpLabelFilter(t *testing.T) {
+	in := &inspector{newQuery: queryTypes["timescaledb"], label: regexp.MustCompile("max cpu")}
+	var out bytes.Buffer
+	n, err := in.dump(encodeTestQueries(t), &out, outputJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("incorrect number of queries: got %d want %d", n, 1)
+	}
+	want := `{"HumanDescription":"TimescaleDB max cpu: 2016-01-01T00:00:00Z","HumanLabel":"TimescaleDB max cpu","Hypertable":"cpu","SqlQuery":"SELECT max(usage_user) FROM cpu"}` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("incorrect json:\ngot\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := in.dump(encodeTestQueries(t), &out, "xml"); err == nil {
+		t.Errorf("unexpected lack of error for unknown output")
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	in := &inspector{newQuery: queryTypes["timescaledb"]}
+	var lines bytes.Buffer
+	if _, err := in.dump(encodeTestQueries(t), &lines, outputJSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	n, err := in.convert(&lines, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != len(testQueries) {
+		t.Errorf("incorrect number of queries: got %d want %d", n, len(testQueries))
+	}
+	if got, want := out.Bytes(), encodeTestQueries(t).Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("converted queries differ from the original")
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	in := &inspector{newQuery: queryTypes["timescaledb"]}
+	var out bytes.Buffer
+	if _, err := in.convert(strings.NewReader(`{"Foo":"bar"}`), &out); err == nil {
+		t.Errorf("unexpected lack of error for unknown field")
+	}
+	if _, err := in.convert(strings.NewReader(`{"SqlQuery":1}`), &out); err == nil {
+		t.Errorf("unexpected lack of error for wrong field type")
+	}
+	if _, err := in.convert(strings.NewReader(`not json`), &out); err == nil {
+		t.Errorf("unexpected lack of error for invalid json")
+	}
+
+	in = &inspector{newQuery: queryTypes[typeMongo]}
+	if _, err := in.convert(strings.NewReader(`{}`), &out); err == nil {
+		t.Errorf("unexpected lack of error for mongo queries")
+	}
+}
+
+func TestConvertHTTP(t *testing.T) {
+	in := &inspector{newQuery: queryTypes["http"]}
+	var out bytes.Buffer
+	line := `{"HumanLabel":"Influx max cpu","Method":"GET","Path":"/query?q=SELECT","StartTimestamp":1451606400000000000,"EndTimestamp":1451610000000000000}`
+	if _, err := in.convert(strings.NewReader(line), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var q query.HTTP
+	if err := gob.NewDecoder(&out).Decode(&q); err != nil {
+		t.Fatalf("cannot decode converted query: %v", err)
+	}
+	if string(q.Path) != "/query?q=SELECT" || q.EndTimestamp != 1451610000000000000 {
+		t.Errorf("incorrect query: %+v", q)
+	}
+	start, end, ok := timeRange(&q)
+	if !ok {
+		t.Fatalf("missing time range")
+	}
+	if want := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("incorrect start: got %v want %v", start, want)
+	}
+	if want := time.Date(2016, 1, 1, 1, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("incorrect end: got %v want %v", end, want)
+	}
+}
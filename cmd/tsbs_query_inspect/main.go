@@ -0,0 +1,71 @@
+// tsbs_query_inspect shows the queries of a file generated by
+// tsbs_generate_queries as text or JSON lines, optionally only those with a
+// matching label. JSON lines, e.g. after editing them by hand, can be
+// converted back into a query file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+func main() {
+	var (
+		queryType string
+		fileName  string
+		output    string
+		label     string
+		toGob     bool
+	)
+	pflag.StringVar(&queryType, "type", "", fmt.Sprintf("Type of the queries: %s", strings.Join(queryTypeNames(), ", ")))
+	pflag.StringVar(&fileName, "file", "", "File to read queries from instead of STDIN. Accepts a comma-separated list of files and glob patterns; gzip and zstd files are decompressed automatically")
+	pflag.StringVar(&output, "output", outputText, fmt.Sprintf("Output format: '%s' or '%s' (JSON lines)", outputText, outputJSON))
+	pflag.StringVar(&label, "label", "", "Only show queries whose label matches this regular expression")
+	pflag.BoolVar(&toGob, "to-gob", false, "Read JSON lines as written with --output=json and write them as a query file to STDOUT")
+	pflag.Parse()
+
+	newQuery, err := newQueryFn(queryType)
+	if err != nil {
+		log.Fatal(err)
+	}
+	in := &inspector{newQuery: newQuery}
+	if len(label) > 0 {
+		in.label, err = regexp.Compile(label)
+		if err != nil {
+			log.Fatalf("invalid --label: %v", err)
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if len(fileName) > 0 {
+		f, err := utils.OpenInputFiles(fileName)
+		if err != nil {
+			log.Fatalf("cannot open %s: %v", fileName, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(os.Stdout)
+	defer bw.Flush()
+
+	var n int
+	if toGob {
+		n, err = in.convert(br, bw)
+	} else {
+		n, err = in.dump(br, bw, output)
+	}
+	if err != nil {
+		bw.Flush()
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d queries\n", n)
+}
@@ -113,9 +113,10 @@ func prettyPrintResponse(rows *sqlx.Rows, q *query.ClickHouse) {
 }
 
 type queryExecutorOptions struct {
-	showExplain   bool
-	debug         bool
-	printResponse bool
+	showExplain    bool
+	debug          bool
+	printResponse  bool
+	captureResults bool
 }
 
 // query.Processor interface implementation
@@ -134,9 +135,10 @@ func (p *processor) Init(workerNumber int) {
 	p.db = sqlx.MustConnect("clickhouse", getConnectString(workerNumber))
 	p.opts = &queryExecutorOptions{
 		// ClickHouse could not do EXPLAIN
-		showExplain:   false,
-		debug:         runner.DebugLevel() > 0,
-		printResponse: runner.DoPrintResponses(),
+		showExplain:    false,
+		debug:          runner.DebugLevel() > 0,
+		printResponse:  runner.DoPrintResponses(),
+		captureResults: runner.DoCaptureResults(),
 	}
 }
 
@@ -165,7 +167,14 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 	if p.opts.debug {
 		fmt.Println(sql)
 	}
-	if p.opts.printResponse {
+	if p.opts.captureResults {
+		result, err := query.ScanResultRows(rows.Rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		runner.CaptureResult(q, result)
+	} else if p.opts.printResponse {
 		prettyPrintResponse(rows, chQuery)
 	}
 
@@ -178,3 +187,15 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 
 	return []*query.Stat{stat}, err
 }
+
+// FlushCache drops the mark cache and the cache of uncompressed blocks, so
+// that the next query reads from disk (or the OS page cache, see
+// --flush-cache-command)
+func (p *processor) FlushCache() error {
+	for _, stmt := range []string{"SYSTEM DROP MARK CACHE", "SYSTEM DROP UNCOMPRESSED CACHE"} {
+		if _, err := p.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
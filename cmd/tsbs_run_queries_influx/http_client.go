@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,6 +34,7 @@ type HTTPClientDoOptions struct {
 	PrettyPrintResponses bool
 	chunkSize            uint64
 	database             string
+	captureResults       bool
 }
 
 var httpClientOnce = sync.Once{}
@@ -55,9 +60,9 @@ func NewHTTPClient(host string) *HTTPClient {
 	}
 }
 
-// Do performs the action specified by the given Query. It uses fasthttp, and
-// tries to minimize heap allocations.
-func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (lag float64, err error) {
+// Do performs the action specified by the given Query, giving up when ctx is
+// done. It tries to minimize heap allocations.
+func (w *HTTPClient) Do(ctx context.Context, q *query.HTTP, opts *HTTPClientDoOptions) (lag float64, err error) {
 	// populate uri from the reusable byte slice:
 	w.uri = w.uri[:0]
 	w.uri = append(w.uri, w.Host...)
@@ -72,29 +77,38 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (lag float64,
 	// populate a request with data from the Query:
 	req, err := http.NewRequest(string(q.Method), string(w.uri), nil)
 	if err != nil {
-		panic(err)
+		return 0, query.ClientError(err)
 	}
+	req = req.WithContext(ctx)
 
 	// Perform the request while tracking latency:
 	start := time.Now()
 	resp, err := w.client.Do(req)
 	if err != nil {
-		panic(err)
+		return 0, query.ClientError(err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		panic("http request did not return status 200 OK")
+		return 0, query.HTTPError(resp.StatusCode, fmt.Errorf("http request did not return status 200 OK: %s", resp.Status))
 	}
 
 	var body []byte
 	body, err = ioutil.ReadAll(resp.Body)
 
 	if err != nil {
-		panic(err)
+		return 0, query.ClientError(err)
 	}
 
 	lag = float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
 
+	if opts != nil && opts.captureResults {
+		rows, err := resultRows(body)
+		if err != nil {
+			return 0, query.ClientError(fmt.Errorf("cannot parse response: %v", err))
+		}
+		runner.CaptureResult(q, rows)
+	}
+
 	if opts != nil {
 		// Print debug messages, if applicable:
 		switch opts.Debug {
@@ -134,3 +148,52 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (lag float64,
 
 	return lag, err
 }
+
+// influxResponse is the part of an InfluxDB query response needed to capture
+// its results
+type influxResponse struct {
+	Results []struct {
+		Series []struct {
+			Tags   map[string]string `json:"tags"`
+			Values [][]interface{}   `json:"values"`
+		} `json:"series"`
+	} `json:"results"`
+}
+
+// resultRows extracts the rows of all series of a response, which can be
+// several JSON objects for chunked responses. The tag values of a series,
+// ordered by tag key, are tags of each of its rows, also if they look like
+// numbers, so they are never compared as values.
+func resultRows(body []byte) ([]query.ResultRow, error) {
+	var rows []query.ResultRow
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	for {
+		var resp influxResponse
+		err := dec.Decode(&resp)
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range resp.Results {
+			for _, series := range result.Series {
+				keys := make([]string, 0, len(series.Tags))
+				for k := range series.Tags {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, values := range series.Values {
+					row := query.NewResultRow(values...)
+					tags := make([]string, 0, len(keys)+len(row.Tags))
+					for _, k := range keys {
+						tags = append(tags, series.Tags[k])
+					}
+					row.Tags = append(tags, row.Tags...)
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+}
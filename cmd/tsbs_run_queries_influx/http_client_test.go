@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func TestResultRows(t *testing.T) {
+	// A chunked response of two JSON objects
+	body := []byte(`{"results":[{"series":[{"tags":{"region":"eu-west-1","hostname":"42"},"values":[["2016-01-01T00:00:00Z",1.5,2]]}]}]}
+{"results":[{"series":[{"tags":{"hostname":"host_1"},"values":[["2016-01-01T00:00:00Z",3,"x"],["2016-01-01T00:01:00Z",4,null]]}]}]}`)
+	want := []query.ResultRow{
+		{Time: "2016-01-01T00:00:00Z", Tags: []string{"42", "eu-west-1"}, Values: []float64{1.5, 2}},
+		{Time: "2016-01-01T00:00:00Z", Tags: []string{"host_1", "x"}, Values: []float64{3}},
+		{Time: "2016-01-01T00:01:00Z", Tags: []string{"host_1"}, Values: []float64{4}},
+	}
+	// Map iteration order is random, so decode a few times
+	for i := 0; i < 10; i++ {
+		got, err := resultRows(body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("incorrect rows: got %+v want %+v", got, want)
+		}
+	}
+
+	if _, err := resultRows([]byte(`{"results":`)); err == nil {
+		t.Errorf("unexpected lack of error for invalid json")
+	}
+}
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -76,14 +77,19 @@ func (p *processor) Init(workerNumber int) {
 		PrettyPrintResponses: runner.DoPrintResponses(),
 		chunkSize:            chunkSize,
 		database:             runner.DatabaseName(),
+		captureResults:       runner.DoCaptureResults(),
 	}
 	url := daemonUrls[workerNumber%len(daemonUrls)]
 	p.w = NewHTTPClient(url)
 }
 
-func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	return p.ProcessQueryWithContext(context.Background(), q, isWarm)
+}
+
+func (p *processor) ProcessQueryWithContext(ctx context.Context, q query.Query, _ bool) ([]*query.Stat, error) {
 	hq := q.(*query.HTTP)
-	lag, err := p.w.Do(hq, p.opts)
+	lag, err := p.w.Do(ctx, hq, p.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -6,15 +6,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4/stdlib"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -168,9 +172,10 @@ func mapRows(r *sql.Rows) []map[string]interface{} {
 }
 
 type queryExecutorOptions struct {
-	showExplain   bool
-	debug         bool
-	printResponse bool
+	showExplain    bool
+	debug          bool
+	printResponse  bool
+	captureResults bool
 }
 
 type processor struct {
@@ -185,15 +190,24 @@ func (p *processor) Init(workerNumber int) {
 	if err != nil {
 		panic(err)
 	}
+	// A worker runs one query at a time, so one connection is enough. It also
+	// makes FlushCache discard the session state of the connection the next
+	// query runs on, rather than of any connection of the pool.
+	db.SetMaxOpenConns(1)
 	p.db = db
 	p.opts = &queryExecutorOptions{
-		showExplain:   showExplain,
-		debug:         runner.DebugLevel() > 0,
-		printResponse: runner.DoPrintResponses(),
+		showExplain:    showExplain,
+		debug:          runner.DebugLevel() > 0,
+		printResponse:  runner.DoPrintResponses(),
+		captureResults: runner.DoCaptureResults(),
 	}
 }
 
 func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	return p.ProcessQueryWithContext(context.Background(), q, isWarm)
+}
+
+func (p *processor) ProcessQueryWithContext(ctx context.Context, q query.Query, isWarm bool) ([]*query.Stat, error) {
 	// No need to run again for EXPLAIN
 	if isWarm && p.opts.showExplain {
 		return nil, nil
@@ -205,9 +219,9 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 	if showExplain {
 		qry = "EXPLAIN ANALYZE " + qry
 	}
-	rows, err := p.db.Query(qry)
+	rows, err := p.db.QueryContext(ctx, qry)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	if p.opts.debug {
@@ -223,6 +237,13 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 			text += s + "\n"
 		}
 		fmt.Printf("%s\n\n%s\n-----\n\n", qry, text)
+	} else if p.opts.captureResults {
+		result, err := query.ScanResultRows(rows)
+		if err != nil {
+			rows.Close()
+			return nil, classifyError(err)
+		}
+		runner.CaptureResult(q, result)
 	} else if p.opts.printResponse {
 		prettyPrintResponse(rows, tq)
 	}
@@ -231,7 +252,7 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 	}
 	rows.Close()
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	took := float64(time.Since(start).Nanoseconds()) / 1e6
 	stat := query.GetStat()
@@ -239,3 +260,31 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 
 	return []*query.Stat{stat}, err
 }
+
+// FlushCache discards the session state (e.g. cached plans) and, if the
+// pg_buffercache extension supports it (PostgreSQL 17 and later), evicts all
+// shared buffers. The OS page cache is not touched; drop it with
+// --flush-cache-command.
+func (p *processor) FlushCache() error {
+	if _, err := p.db.Exec("DISCARD ALL"); err != nil {
+		return err
+	}
+	if _, err := p.db.Exec("SELECT count(pg_buffercache_evict(bufferid)) FROM pg_buffercache"); err != nil {
+		evictWarningOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "WARNING: cannot evict shared buffers, only discarding session state (needs pg_buffercache on PostgreSQL 17+): %v\n", err)
+		})
+	}
+	return nil
+}
+
+// evictWarningOnce makes workers warn only once that buffers can't be evicted
+var evictWarningOnce sync.Once
+
+// classifyError marks errors reported by the database server as such
+func classifyError(err error) error {
+	switch err.(type) {
+	case *pgconn.PgError, *pq.Error:
+		return query.ServerError(err)
+	}
+	return err
+}
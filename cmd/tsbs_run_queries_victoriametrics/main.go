@@ -7,6 +7,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -80,8 +81,13 @@ func (p *processor) Init(workerNum int) {
 
 // query.Processor interface implementation
 func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	return p.ProcessQueryWithContext(context.Background(), q, isWarm)
+}
+
+// query.ProcessorWithContext interface implementation
+func (p *processor) ProcessQueryWithContext(ctx context.Context, q query.Query, isWarm bool) ([]*query.Stat, error) {
 	hq := q.(*query.HTTP)
-	lag, err := p.do(hq)
+	lag, err := p.do(ctx, hq)
 	if err != nil {
 		return nil, err
 	}
@@ -90,17 +96,18 @@ func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, err
 	return []*query.Stat{stat}, nil
 }
 
-func (p *processor) do(q *query.HTTP) (float64, error) {
+func (p *processor) do(ctx context.Context, q *query.HTTP) (float64, error) {
 	// populate a request with data from the Query:
 	req, err := http.NewRequest(string(q.Method), p.url+string(q.Path), nil)
 	if err != nil {
-		return 0, fmt.Errorf("error while creating request: %s", err)
+		return 0, query.ClientError(fmt.Errorf("error while creating request: %s", err))
 	}
+	req = req.WithContext(ctx)
 
 	start := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("query execution error: %s", err)
+		return 0, query.ClientError(fmt.Errorf("query execution error: %s", err))
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
@@ -108,7 +115,7 @@ func (p *processor) do(q *query.HTTP) (float64, error) {
 		return 0, fmt.Errorf("error while reading response body: %s", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("non-200 statuscode received: %d; Body: %s", resp.StatusCode, string(body))
+		return 0, query.HTTPError(resp.StatusCode, fmt.Errorf("non-200 statuscode received: %d; Body: %s", resp.StatusCode, string(body)))
 	}
 	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
 
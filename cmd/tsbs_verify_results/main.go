@@ -0,0 +1,99 @@
+// tsbs_verify_results compares the query results captured by two query runs
+// with --capture-results, e.g. of the same query file against two databases,
+// and reports the queries whose results differ.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	precision   int
+	maxReported int
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <results file> <results file>\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+	pflag.IntVar(&precision, "precision", query.DefaultCapturePrecision,
+		"Number of decimal places to round values to before comparing them. Lower it to tolerate rounding differences between databases.")
+	pflag.IntVar(&maxReported, "max-reported", 20, "Report at most this many differing queries (0 = all)")
+	pflag.Parse()
+	if pflag.NArg() != 2 {
+		pflag.Usage()
+		os.Exit(2)
+	}
+	a := readResults(pflag.Arg(0))
+	b := readResults(pflag.Arg(1))
+
+	if verify(os.Stdout, pflag.Arg(0), pflag.Arg(1), a, b) > 0 {
+		os.Exit(1)
+	}
+}
+
+func readResults(fileName string) map[uint64]*query.CapturedResult {
+	r, err := utils.OpenInputFiles(fileName)
+	if err != nil {
+		log.Fatalf("cannot open %s: %v", fileName, err)
+	}
+	defer r.Close()
+	results, err := query.ReadCapturedResults(r)
+	if err != nil {
+		log.Fatalf("cannot read results from %s: %v", fileName, err)
+	}
+	return results
+}
+
+// verify reports the queries whose results differ between a and b, or that
+// only one of them has, and returns their number
+func verify(w io.Writer, nameA, nameB string, a, b map[uint64]*query.CapturedResult) int {
+	ids := make([]uint64, 0, len(a))
+	for id := range a {
+		ids = append(ids, id)
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	differing := 0
+	for _, id := range ids {
+		ra, okA := a[id]
+		rb, okB := b[id]
+		var msg string
+		switch {
+		case !okA:
+			msg = fmt.Sprintf("query %d (%s): only in %s", id, rb.Label, nameB)
+		case !okB:
+			msg = fmt.Sprintf("query %d (%s): only in %s", id, ra.Label, nameA)
+		default:
+			diff := query.DiffResults(ra, rb, precision)
+			if len(diff) == 0 {
+				continue
+			}
+			msg = fmt.Sprintf("query %d (%s / %s): %s", id, ra.Label, rb.Label, diff)
+		}
+		differing++
+		if maxReported == 0 || differing <= maxReported {
+			fmt.Fprintln(w, msg)
+		}
+	}
+	if maxReported > 0 && differing > maxReported {
+		fmt.Fprintf(w, "... and %d more\n", differing-maxReported)
+	}
+	fmt.Fprintf(w, "%d of %d queries differ\n", differing, len(ids))
+	return differing
+}
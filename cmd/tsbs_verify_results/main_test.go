@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func captured(id uint64, label string, values ...float64) *query.CapturedResult {
+	res := &query.CapturedResult{ID: id, Label: label}
+	for _, v := range values {
+		res.Rows = append(res.Rows, query.NewResultRow("host_0", v))
+	}
+	return res
+}
+
+func TestVerify(t *testing.T) {
+	precision = query.DefaultCapturePrecision
+	maxReported = 0
+
+	cases := []struct {
+		desc      string
+		a         []*query.CapturedResult
+		b         []*query.CapturedResult
+		want      int
+		wantLines []string
+	}{
+		{
+			desc: "matching",
+			a:    []*query.CapturedResult{captured(0, "lastpoint", 1, 2), captured(1, "high-cpu-1", 3)},
+			b:    []*query.CapturedResult{captured(0, "lastpoint", 1, 2), captured(1, "high-cpu-1", 3.0000001)},
+			want: 0,
+			wantLines: []string{
+				"0 of 2 queries differ",
+			},
+		},
+		{
+			desc: "mismatched values",
+			a:    []*query.CapturedResult{captured(0, "lastpoint", 1, 2), captured(1, "high-cpu-1", 3)},
+			b:    []*query.CapturedResult{captured(0, "lastpoint", 1, 2.5), captured(1, "high-cpu-1", 3, 4)},
+			want: 2,
+			wantLines: []string{
+				"query 0 (lastpoint / lastpoint): row 1: ",
+				"query 1 (high-cpu-1 / high-cpu-1): 1 rows vs 2 rows",
+				"2 of 2 queries differ",
+			},
+		},
+		{
+			// labels name the database, only the results are compared
+			desc: "labels of different databases",
+			a:    []*query.CapturedResult{captured(0, "TimescaleDB lastpoint", 1), captured(1, "TimescaleDB high-cpu-1", 3)},
+			b:    []*query.CapturedResult{captured(0, "Influx lastpoint", 1), captured(1, "Influx high-cpu-1", 4)},
+			want: 1,
+			wantLines: []string{
+				"query 1 (TimescaleDB high-cpu-1 / Influx high-cpu-1): row 0: ",
+				"1 of 2 queries differ",
+			},
+		},
+		{
+			desc: "missing captures",
+			a:    []*query.CapturedResult{captured(0, "lastpoint", 1), captured(1, "high-cpu-1", 3)},
+			b:    []*query.CapturedResult{captured(0, "lastpoint", 1), captured(2, "groupby", 5)},
+			want: 2,
+			wantLines: []string{
+				"query 1 (high-cpu-1): only in a",
+				"query 2 (groupby): only in b",
+				"2 of 3 queries differ",
+			},
+		},
+	}
+
+	toMap := func(results []*query.CapturedResult) map[uint64]*query.CapturedResult {
+		m := make(map[uint64]*query.CapturedResult)
+		for _, r := range results {
+			m[r.ID] = r
+		}
+		return m
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if got := verify(&buf, "a", "b", toMap(c.a), toMap(c.b)); got != c.want {
+			t.Errorf("%s: incorrect number of differing queries: got %d want %d", c.desc, got, c.want)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(c.wantLines) {
+			t.Errorf("%s: incorrect number of lines: got %d want %d:\n%s", c.desc, len(lines), len(c.wantLines), buf.String())
+			continue
+		}
+		for i, want := range c.wantLines {
+			if !strings.HasPrefix(lines[i], want) {
+				t.Errorf("%s: incorrect line %d: got %s want %s", c.desc, i, lines[i], want)
+			}
+		}
+	}
+}
+
+func TestVerifyMaxReported(t *testing.T) {
+	precision = query.DefaultCapturePrecision
+	maxReported = 1
+	defer func() { maxReported = 0 }()
+
+	a := map[uint64]*query.CapturedResult{0: captured(0, "lastpoint", 1), 1: captured(1, "lastpoint", 2)}
+	b := map[uint64]*query.CapturedResult{}
+	var buf bytes.Buffer
+	if got := verify(&buf, "a", "b", a, b); got != 2 {
+		t.Errorf("incorrect number of differing queries: got %d want 2", got)
+	}
+	want := "query 0 (lastpoint): only in a\n... and 1 more\n2 of 2 queries differ\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output: got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -15,6 +15,7 @@ require (
 	github.com/jackc/pgconn v1.1.0
 	github.com/jackc/pgx/v4 v4.1.1
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/klauspost/compress v1.9.5
 	github.com/kshvakov/clickhouse v1.3.11
 	github.com/lib/pq v1.2.0
 	github.com/pkg/errors v0.9.1
@@ -27,4 +28,5 @@ require (
 	github.com/valyala/fasthttp v1.4.0
 	go.mongodb.org/mongo-driver v1.4.6
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,207 @@
+package inputs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+// Ways to assign points to shards
+const (
+	ShardByRoundRobin = "round-robin"
+	ShardByEntity     = "entity"
+)
+
+var shardByChoices = []string{
+	ShardByRoundRobin,
+	ShardByEntity,
+}
+
+var compressionChoices = []string{
+	utils.CompressionNone,
+	utils.CompressionGzip,
+	utils.CompressionZstd,
+}
+
+// dataShard is one of the outputs of a DataGenerator
+type dataShard struct {
+	fileName string
+	w        *bufio.Writer
+	// closers are closed in order once all data is written
+	closers []io.Closer
+	// serializer is used for the points of this shard instead of the shared
+	// one, for serializers that keep state about the points they wrote
+	serializer serialize.PointSerializer
+	points     uint64
+}
+
+// dataOutput is where a DataGenerator writes its points: a single file or
+// writer, or a number of shard files.
+type dataOutput struct {
+	shards      []*dataShard
+	shardBy     string
+	compression string
+	// written is the number of points written so far
+	written uint64
+}
+
+// newDataOutput opens the outputs described by c. Without a file the output
+// goes to fallback and can not be sharded.
+func newDataOutput(c *DataGeneratorConfig, fallback io.Writer) (*dataOutput, error) {
+	o := &dataOutput{shardBy: c.ShardBy, compression: c.compression()}
+	if len(c.File) == 0 {
+		s, err := o.newShard("", fallback)
+		if err != nil {
+			return nil, err
+		}
+		o.shards = append(o.shards, s)
+		return o, nil
+	}
+
+	for i := uint(0); i < c.Shards; i++ {
+		fileName := c.File
+		if c.Shards > 1 {
+			fileName = shardFileName(c.File, i)
+		}
+		file, err := os.Create(fileName)
+		if err != nil {
+			o.close()
+			return nil, fmt.Errorf("cannot open file for write %s: %v", fileName, err)
+		}
+		s, err := o.newShard(fileName, file)
+		if err != nil {
+			file.Close()
+			o.close()
+			return nil, err
+		}
+		s.closers = append(s.closers, file)
+		o.shards = append(o.shards, s)
+	}
+	return o, nil
+}
+
+func (o *dataOutput) newShard(fileName string, w io.Writer) (*dataShard, error) {
+	cw, err := utils.NewCompressingWriter(w, o.compression)
+	if err != nil {
+		return nil, err
+	}
+	return &dataShard{
+		fileName: fileName,
+		w:        bufio.NewWriterSize(cw, defaultWriteSize),
+		closers:  []io.Closer{cw},
+	}, nil
+}
+
+// shardFileName returns the name of shard i of file, e.g. data-3.gz for
+// data.gz, so the shards can be loaded with a pattern like data-*.gz
+func shardFileName(file string, i uint) string {
+	stem, ext := utils.SplitCompressionExtension(file)
+	return fmt.Sprintf("%s-%d%s", stem, i, ext)
+}
+
+// manifestFileName returns the name of the manifest describing the shards
+// of file
+func manifestFileName(file string) string {
+	stem, _ := utils.SplitCompressionExtension(file)
+	return stem + ".manifest.json"
+}
+
+// shardIndex returns the shard of p, the n-th point written
+func (o *dataOutput) shardIndex(n uint64, p *serialize.Point) int {
+	if len(o.shards) == 1 {
+		return 0
+	}
+	if o.shardBy == ShardByEntity {
+		return int(entityHash(p) % uint64(len(o.shards)))
+	}
+	return int(n % uint64(len(o.shards)))
+}
+
+// entityHash hashes the value of the first tag of p, which names the entity
+// (host, truck, ...) that p belongs to in all use cases
+func entityHash(p *serialize.Point) uint64 {
+	h := fnv.New64a()
+	if values := p.TagValues(); len(values) > 0 {
+		switch v := values[0].(type) {
+		case string:
+			io.WriteString(h, v)
+		case []byte:
+			h.Write(v)
+		case nil:
+		default:
+			fmt.Fprint(h, v)
+		}
+	}
+	return h.Sum64()
+}
+
+// flush flushes the buffered data of all shards
+func (o *dataOutput) flush() error {
+	var err error
+	for _, s := range o.shards {
+		if ferr := s.w.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+// close flushes all shards and closes their compressors and files
+func (o *dataOutput) close() error {
+	err := o.flush()
+	for _, s := range o.shards {
+		for _, c := range s.closers {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		s.closers = nil
+	}
+	return err
+}
+
+// dataManifest lists the shards written by a DataGenerator
+type dataManifest struct {
+	Format      string          `json:"format"`
+	UseCase     string          `json:"use_case"`
+	Seed        int64           `json:"seed"`
+	ShardBy     string          `json:"shard_by"`
+	Compression string          `json:"compression"`
+	Points      uint64          `json:"points"`
+	Shards      []manifestShard `json:"shards"`
+}
+
+type manifestShard struct {
+	File   string `json:"file"`
+	Points uint64 `json:"points"`
+}
+
+// writeManifest writes the manifest of the shards of c.File. The files are
+// listed relative to the manifest, so the directory can be moved.
+func (o *dataOutput) writeManifest(c *DataGeneratorConfig) error {
+	m := dataManifest{
+		Format:      c.Format,
+		UseCase:     c.Use,
+		Seed:        c.Seed,
+		ShardBy:     o.shardBy,
+		Compression: o.compression,
+		Shards:      []manifestShard{},
+	}
+	for _, s := range o.shards {
+		m.Points += s.points
+		m.Shards = append(m.Shards, manifestShard{File: filepath.Base(s.fileName), Points: s.points})
+	}
+	data, err := json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestFileName(c.File), append(data, '\n'), 0644)
+}
@@ -0,0 +1,194 @@
+package inputs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+func TestShardFileName(t *testing.T) {
+	cases := []struct {
+		file     string
+		shard    string
+		manifest string
+	}{
+		{file: "data", shard: "data-2", manifest: "data.manifest.json"},
+		{file: "data.gz", shard: "data-2.gz", manifest: "data.manifest.json"},
+		{file: "/tmp/data.csv.zst", shard: "/tmp/data.csv-2.zst", manifest: "/tmp/data.csv.manifest.json"},
+	}
+	for _, c := range cases {
+		if got := shardFileName(c.file, 2); got != c.shard {
+			t.Errorf("%s: incorrect shard file name: got %s want %s", c.file, got, c.shard)
+		}
+		if got := manifestFileName(c.file); got != c.manifest {
+			t.Errorf("%s: incorrect manifest file name: got %s want %s", c.file, got, c.manifest)
+		}
+	}
+}
+
+func TestDataGeneratorConfigValidateShards(t *testing.T) {
+	c := &DataGeneratorConfig{
+		BaseConfig: BaseConfig{
+			Seed:   123,
+			Format: FormatInflux,
+			Use:    useCaseDevops,
+			Scale:  10,
+		},
+		LogInterval:          time.Second,
+		InterleavedNumGroups: 1,
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c.Shards != 1 {
+		t.Errorf("incorrect default shards: got %d want 1", c.Shards)
+	}
+	if c.ShardBy != ShardByRoundRobin {
+		t.Errorf("incorrect default shard-by: got %s want %s", c.ShardBy, ShardByRoundRobin)
+	}
+	if got := c.compression(); got != utils.CompressionNone {
+		t.Errorf("incorrect default compression: got %s want %s", got, utils.CompressionNone)
+	}
+
+	checkErr := func(want string) {
+		err := c.Validate()
+		if err == nil {
+			t.Errorf("unexpected lack of error, want %s", want)
+		} else if err.Error() != want {
+			t.Errorf("incorrect error: got\n%s\nwant\n%s", err.Error(), want)
+		}
+	}
+
+	c.Shards = 2
+	checkErr(errShardsNeedFile)
+	c.File = "data.zst"
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error with file: %v", err)
+	}
+	if got := c.compression(); got != utils.CompressionZstd {
+		t.Errorf("incorrect inferred compression: got %s want %s", got, utils.CompressionZstd)
+	}
+
+	c.ShardBy = "host"
+	checkErr(fmt.Sprintf(errUnknownShardByFmt, "host"))
+	c.ShardBy = ShardByEntity
+
+	c.Compress = "bz2"
+	checkErr(fmt.Sprintf(errUnknownCompressFmt, "bz2"))
+}
+
+func readShard(t *testing.T, fileName string) []string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, done, err := utils.NewDecompressingReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestGenerateShards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-shards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	generate := func(file, shardBy string, workers uint) {
+		c := &DataGeneratorConfig{
+			BaseConfig: BaseConfig{
+				Seed:      123,
+				Format:    FormatInflux,
+				Use:       useCaseCPUOnly,
+				Scale:     10,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   defaultTimeEnd,
+				File:      filepath.Join(dir, file),
+			},
+			Limit:                5000,
+			LogInterval:          10 * time.Second,
+			InterleavedNumGroups: 1,
+			Workers:              workers,
+			Shards:               3,
+			ShardBy:              shardBy,
+		}
+		dg := &DataGenerator{}
+		if err := dg.Generate(c); err != nil {
+			t.Fatalf("unexpected error generating %s: %v", file, err)
+		}
+	}
+
+	generate("entity.gz", ShardByEntity, 1)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "entity.manifest.json"))
+	if err != nil {
+		t.Fatalf("unexpected error reading manifest: %v", err)
+	}
+	var m dataManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unexpected error parsing manifest: %v", err)
+	}
+	if m.Points != 5000 || m.Compression != utils.CompressionGzip || m.ShardBy != ShardByEntity || len(m.Shards) != 3 {
+		t.Fatalf("incorrect manifest: %+v", m)
+	}
+
+	hostname := regexp.MustCompile(`hostname=host_\d+`)
+	hostShards := make(map[string]int)
+	for i, s := range m.Shards {
+		if want := fmt.Sprintf("entity-%d.gz", i); s.File != want {
+			t.Errorf("incorrect shard file: got %s want %s", s.File, want)
+		}
+		lines := readShard(t, filepath.Join(dir, s.File))
+		if uint64(len(lines)) != s.Points {
+			t.Errorf("%s: incorrect number of points: got %d want %d", s.File, len(lines), s.Points)
+		}
+		for _, line := range lines {
+			host := hostname.FindString(line)
+			if shard, ok := hostShards[host]; ok && shard != i {
+				t.Errorf("%s is in shards %d and %d", host, shard, i)
+			}
+			hostShards[host] = i
+		}
+	}
+	if len(hostShards) != 10 {
+		t.Errorf("incorrect number of hosts: got %d want 10", len(hostShards))
+	}
+
+	// Shards are the same regardless of the number of workers
+	for _, shardBy := range shardByChoices {
+		generate("serial-"+shardBy+".zst", shardBy, 1)
+		generate("parallel-"+shardBy+".zst", shardBy, 4)
+		for i := 0; i < 3; i++ {
+			serial := readShard(t, filepath.Join(dir, fmt.Sprintf("serial-%s-%d.zst", shardBy, i)))
+			parallel := readShard(t, filepath.Join(dir, fmt.Sprintf("parallel-%s-%d.zst", shardBy, i)))
+			if len(serial) == 0 || len(serial) != len(parallel) {
+				t.Fatalf("%s shard %d: incorrect number of points: got %d and %d", shardBy, i, len(serial), len(parallel))
+			}
+			for j := range serial {
+				if serial[j] != parallel[j] {
+					t.Fatalf("%s shard %d: line %d differs with 4 workers:\n%s\n%s", shardBy, i, j, serial[j], parallel[j])
+				}
+			}
+		}
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"sort"
 	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/custom"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/devops"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/iot"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/internal/utils"
 )
 
 // Error messages when using a DataGenerator
@@ -25,6 +26,15 @@ const (
 	errTotalGroupsZero    = "incorrect interleaved groups configuration: total groups = 0"
 	errInvalidGroupsFmt   = "incorrect interleaved groups configuration: id %d >= total groups %d"
 	errCannotParseTimeFmt = "cannot parse time from string '%s': %v"
+	errSchemaNeedsCustom  = "schema can only be used with use case " + useCaseCustom
+	errCustomNeedsSchema  = "use case " + useCaseCustom + " needs a schema"
+	errSerialFormatFmt    = "format %s can only be serialized by one worker"
+	errShardsNeedFile     = "shards can only be written to a file"
+	errUnknownShardByFmt  = "unknown shard-by: '%s'"
+	errUnknownCompressFmt = "unknown compression: '%s'"
+	errChurnRateNegative  = "churn rate cannot be negative"
+	errChurnNeedsHosts    = "churn-rate can only be used with use cases " + useCaseDevops + ", " + useCaseCPUOnly + " and " + useCaseCPUSingle
+	errUnknownLifetimeFmt = "unknown churn-lifetime: '%s'"
 )
 
 const defaultLogInterval = 10 * time.Second
@@ -40,6 +50,13 @@ type DataGeneratorConfig struct {
 	LogInterval          time.Duration `mapstructure:"log-interval"`
 	InterleavedGroupID   uint          `mapstructure:"interleaved-generation-group-id"`
 	InterleavedNumGroups uint          `mapstructure:"interleaved-generation-groups"`
+	Schema               string        `mapstructure:"schema"`
+	Workers              uint          `mapstructure:"workers"`
+	Compress             string        `mapstructure:"compress"`
+	Shards               uint          `mapstructure:"shards"`
+	ShardBy              string        `mapstructure:"shard-by"`
+	ChurnRate            float64       `mapstructure:"churn-rate"`
+	ChurnLifetime        string        `mapstructure:"churn-lifetime"`
 }
 
 // Validate checks that the values of the DataGeneratorConfig are reasonable.
@@ -57,10 +74,63 @@ func (c *DataGeneratorConfig) Validate() error {
 		return fmt.Errorf(errLogIntervalZero)
 	}
 
+	if c.Workers == 0 {
+		c.Workers = 1
+	}
+	// The Akumuli serializer numbers the series it has seen so far
+	if c.Workers > 1 && c.Format == FormatAkumuli {
+		return fmt.Errorf(errSerialFormatFmt, c.Format)
+	}
+
+	if c.Shards == 0 {
+		c.Shards = 1
+	}
+	if c.Shards > 1 && len(c.File) == 0 {
+		return fmt.Errorf(errShardsNeedFile)
+	}
+	if len(c.ShardBy) == 0 {
+		c.ShardBy = ShardByRoundRobin
+	}
+	if !isIn(c.ShardBy, shardByChoices) {
+		return fmt.Errorf(errUnknownShardByFmt, c.ShardBy)
+	}
+	if !isIn(c.compression(), compressionChoices) {
+		return fmt.Errorf(errUnknownCompressFmt, c.Compress)
+	}
+
+	if c.ChurnRate < 0 {
+		return fmt.Errorf(errChurnRateNegative)
+	}
+	if c.ChurnRate > 0 && !isIn(c.Use, []string{useCaseDevops, useCaseCPUOnly, useCaseCPUSingle}) {
+		return fmt.Errorf(errChurnNeedsHosts)
+	}
+	if len(c.ChurnLifetime) == 0 {
+		c.ChurnLifetime = devops.LifetimeExponential
+	}
+	if !isIn(c.ChurnLifetime, devops.LifetimeChoices) {
+		return fmt.Errorf(errUnknownLifetimeFmt, c.ChurnLifetime)
+	}
+
+	if c.Use == useCaseCustom && len(c.Schema) == 0 {
+		return fmt.Errorf(errCustomNeedsSchema)
+	}
+	if c.Use != useCaseCustom && len(c.Schema) > 0 {
+		return fmt.Errorf(errSchemaNeedsCustom)
+	}
+
 	err = validateGroups(c.InterleavedGroupID, c.InterleavedNumGroups)
 	return err
 }
 
+// compression returns the compression of the output, which defaults to the
+// one implied by the extension of the file
+func (c *DataGeneratorConfig) compression() string {
+	if len(c.Compress) > 0 {
+		return c.Compress
+	}
+	return utils.CompressionOfFile(c.File)
+}
+
 func (c *DataGeneratorConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	c.BaseConfig.AddToFlagSet(fs)
 	fs.Uint64("max-data-points", 0, "Limit the number of data points to generate, 0 = no limit")
@@ -71,6 +141,13 @@ func (c *DataGeneratorConfig) AddToFlagSet(fs *pflag.FlagSet) {
 		"Group (0-indexed) to perform round-robin serialization within. Use this to scale up data generation to multiple processes.")
 	fs.Uint("interleaved-generation-groups", 1,
 		"The number of round-robin serialization groups. Use this to scale up data generation to multiple processes.")
+	fs.Uint("workers", 1, "Number of goroutines generating the data in parallel, each simulating and serializing its share of the hosts (iot: only serializing). The output is the same as with one")
+	fs.String("schema", "", fmt.Sprintf("YAML file describing the entities, tags and measurements of the '%s' use case", useCaseCustom))
+	fs.String("compress", "", fmt.Sprintf("Compression of the output (choices: %s, %s, %s). Default is inferred from the extension of -file (.gz, .zst)", utils.CompressionNone, utils.CompressionGzip, utils.CompressionZstd))
+	fs.Uint("shards", 1, "Number of files to split the output into; needs -file. Shard i of data.gz is data-i.gz, listed with its point count in data.manifest.json")
+	fs.String("shard-by", ShardByRoundRobin, fmt.Sprintf("How points are assigned to shards (choices: %s, %s). '%s' keeps all points of a host/truck in one shard", ShardByRoundRobin, ShardByEntity, ShardByEntity))
+	fs.Float64("churn-rate", 0, "Share of the hosts replaced per hour in the devops use cases, e.g. 0.5 replaces half of them every hour. A replacement gets a new hostname, so the total number of series grows while the active ones stay the same. 0 = no churn")
+	fs.String("churn-lifetime", devops.LifetimeExponential, fmt.Sprintf("Distribution of the lifetime of a host with -churn-rate (choices: %s, %s, %s)", devops.LifetimeExponential, devops.LifetimeUniform, devops.LifetimeFixed))
 
 }
 
@@ -88,8 +165,10 @@ type DataGenerator struct {
 	tsEnd   time.Time
 
 	// bufOut represents the buffered writer that should actually be passed to
-	// any operations that write out data.
+	// any operations that write out data. With shards it is the first one.
 	bufOut *bufio.Writer
+	// out holds all the outputs, see output
+	out *dataOutput
 }
 
 func (g *DataGenerator) init(config GeneratorConfig) error {
@@ -120,10 +199,11 @@ func (g *DataGenerator) init(config GeneratorConfig) error {
 	if g.Out == nil {
 		g.Out = os.Stdout
 	}
-	g.bufOut, err = getBufferedWriter(g.config.File, g.Out)
+	g.out, err = newDataOutput(g.config, g.Out)
 	if err != nil {
 		return err
 	}
+	g.bufOut = g.out.shards[0].w
 
 	return nil
 }
@@ -134,24 +214,54 @@ func (g *DataGenerator) Generate(config GeneratorConfig) error {
 		return err
 	}
 
-	rand.Seed(g.config.Seed)
-
 	scfg, err := g.getSimulatorConfig(g.config)
 	if err != nil {
+		g.out.close()
 		return err
 	}
 
 	sim := scfg.NewSimulator(g.config.LogInterval, g.config.Limit)
 	serializer, err := g.getSerializer(sim, g.config.Format)
 	if err != nil {
+		g.out.close()
 		return err
 	}
+	// The Akumuli serializer numbers the series it has seen so far, so every
+	// shard needs its own
+	if g.config.Format == FormatAkumuli {
+		for _, s := range g.out.shards[1:] {
+			s.serializer = serialize.NewAkumuliSerializer()
+		}
+	}
+
+	err = g.runSimulator(sim, serializer, g.config)
+	if cerr := g.out.close(); err == nil {
+		err = cerr
+	}
+	if err == nil && len(g.out.shards) > 1 {
+		err = g.out.writeManifest(g.config)
+	}
+	return err
+}
 
-	return g.runSimulator(sim, serializer, g.config)
+// output returns the outputs of the generator, by default a single one
+// writing to bufOut
+func (g *DataGenerator) output() *dataOutput {
+	if g.out == nil {
+		g.out = &dataOutput{shards: []*dataShard{{w: g.bufOut}}}
+	}
+	return g.out
 }
 
 func (g *DataGenerator) runSimulator(sim common.Simulator, serializer serialize.PointSerializer, dgc *DataGeneratorConfig) error {
-	defer g.bufOut.Flush()
+	if dgc.Workers > 1 {
+		if psim, ok := sim.(common.PartitionedSimulator); ok {
+			return g.runSimulatorPartitioned(psim, serializer, dgc)
+		}
+		return g.runSimulatorParallel(sim, serializer, dgc)
+	}
+	out := g.output()
+	defer out.flush()
 
 	currGroupID := uint(0)
 	point := serialize.NewPoint()
@@ -164,10 +274,17 @@ func (g *DataGenerator) runSimulator(sim common.Simulator, serializer serialize.
 
 		// in the default case this is always true
 		if currGroupID == dgc.InterleavedGroupID {
-			err := serializer.Serialize(point, g.bufOut)
+			s := out.shards[out.shardIndex(out.written, point)]
+			shardSerializer := serializer
+			if s.serializer != nil {
+				shardSerializer = s.serializer
+			}
+			err := shardSerializer.Serialize(point, s.w)
 			if err != nil {
 				return fmt.Errorf("can not serialize point: %s", err)
 			}
+			s.points++
+			out.written++
 		}
 		point.Reset()
 
@@ -187,7 +304,10 @@ func (g *DataGenerator) getSimulatorConfig(dgc *DataGeneratorConfig) (common.Sim
 
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
+			Seed:            dgc.Seed,
 			HostConstructor: devops.NewHost,
+			ChurnRate:       dgc.ChurnRate,
+			ChurnLifetime:   dgc.ChurnLifetime,
 		}
 	case useCaseIoT:
 		ret = &iot.SimulatorConfig{
@@ -197,6 +317,7 @@ func (g *DataGenerator) getSimulatorConfig(dgc *DataGeneratorConfig) (common.Sim
 			InitGeneratorScale:   dgc.InitialScale,
 			GeneratorScale:       dgc.Scale,
 			GeneratorConstructor: iot.NewTruck,
+			Seed:                 dgc.Seed,
 		}
 	case useCaseCPUOnly:
 		ret = &devops.CPUOnlySimulatorConfig{
@@ -205,7 +326,10 @@ func (g *DataGenerator) getSimulatorConfig(dgc *DataGeneratorConfig) (common.Sim
 
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
+			Seed:            dgc.Seed,
 			HostConstructor: devops.NewHostCPUOnly,
+			ChurnRate:       dgc.ChurnRate,
+			ChurnLifetime:   dgc.ChurnLifetime,
 		}
 	case useCaseCPUSingle:
 		ret = &devops.CPUOnlySimulatorConfig{
@@ -214,8 +338,19 @@ func (g *DataGenerator) getSimulatorConfig(dgc *DataGeneratorConfig) (common.Sim
 
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
+			Seed:            dgc.Seed,
 			HostConstructor: devops.NewHostCPUSingle,
+			ChurnRate:       dgc.ChurnRate,
+			ChurnLifetime:   dgc.ChurnLifetime,
 		}
+	case useCaseCustom:
+		// The schema sets the number of entities, --scale does not apply
+		var schema *custom.Schema
+		schema, err = custom.LoadSchema(dgc.Schema)
+		if err != nil {
+			return nil, err
+		}
+		ret = schema.NewSimulatorConfig(g.tsStart, g.tsEnd, dgc.Seed)
 	default:
 		err = fmt.Errorf("unknown use case: '%s'", dgc.Use)
 	}
@@ -256,16 +391,24 @@ func (g *DataGenerator) getSerializer(sim common.Simulator, format string) (seri
 	return ret, err
 }
 
+// writeHeader writes the header describing the tags and fields to every
+// output, so each shard can be loaded on its own
 func (g *DataGenerator) writeHeader(sim common.Simulator) {
-	g.bufOut.WriteString("tags")
+	for _, s := range g.output().shards {
+		writeHeader(s.w, sim)
+	}
+}
+
+func writeHeader(w *bufio.Writer, sim common.Simulator) {
+	w.WriteString("tags")
 	types := sim.TagTypes()
 	for i, key := range sim.TagKeys() {
-		g.bufOut.WriteString(",")
-		g.bufOut.Write(key)
-		g.bufOut.WriteString(" ")
-		g.bufOut.WriteString(types[i].String())
+		w.WriteString(",")
+		w.Write(key)
+		w.WriteString(" ")
+		w.WriteString(types[i].String())
 	}
-	g.bufOut.WriteString("\n")
+	w.WriteString("\n")
 	// sort the keys so the header is deterministic
 	keys := make([]string, 0)
 	fields := sim.Fields()
@@ -274,12 +417,12 @@ func (g *DataGenerator) writeHeader(sim common.Simulator) {
 	}
 	sort.Strings(keys)
 	for _, measurementName := range keys {
-		g.bufOut.WriteString(measurementName)
+		w.WriteString(measurementName)
 		for _, field := range fields[measurementName] {
-			g.bufOut.WriteString(",")
-			g.bufOut.Write(field)
+			w.WriteString(",")
+			w.Write(field)
 		}
-		g.bufOut.WriteString("\n")
+		w.WriteString("\n")
 	}
-	g.bufOut.WriteString("\n")
+	w.WriteString("\n")
 }
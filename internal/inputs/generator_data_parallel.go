@@ -0,0 +1,360 @@
+package inputs
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+)
+
+// pointBatchSize is the number of points a worker serializes at once
+const pointBatchSize = 1000
+
+// pointBatch is a batch of simulated points that a worker serializes into
+// one buffer per shard. Batches are numbered so the output can be written in
+// order.
+type pointBatch struct {
+	seq    uint64
+	points []*serialize.Point
+	// n is the number of points in use
+	n    int
+	bufs []bytes.Buffer
+	// counts is the number of points in each of bufs
+	counts []uint64
+	err    error
+}
+
+func newPointBatch(shards int) *pointBatch {
+	b := &pointBatch{
+		points: make([]*serialize.Point, pointBatchSize),
+		bufs:   make([]bytes.Buffer, shards),
+		counts: make([]uint64, shards),
+	}
+	for i := range b.points {
+		b.points[i] = serialize.NewPoint()
+	}
+	return b
+}
+
+// serialize serializes the points of the batch into the buffers of their
+// shards and resets them. All batches but the last are full, so the points
+// are numbered from seq*pointBatchSize.
+func (b *pointBatch) serialize(serializer serialize.PointSerializer, out *dataOutput) {
+	first := b.seq * pointBatchSize
+	for i, p := range b.points[:b.n] {
+		if b.err == nil {
+			shard := out.shardIndex(first+uint64(i), p)
+			if err := serializer.Serialize(p, &b.bufs[shard]); err != nil {
+				b.err = fmt.Errorf("can not serialize point: %s", err)
+			}
+			b.counts[shard]++
+		}
+		p.Reset()
+	}
+}
+
+// reset empties the batch for reuse
+func (b *pointBatch) reset() {
+	b.n = 0
+	for i := range b.bufs {
+		b.bufs[i].Reset()
+		b.counts[i] = 0
+	}
+	b.err = nil
+}
+
+// runSimulatorParallel is runSimulator with dgc.Workers goroutines
+// serializing batches of points, for simulators that can not be partitioned
+// (e.g. iot, which mangles batches of points of all trucks). The simulator
+// itself runs on a single goroutine. The batches are written in the order
+// they were simulated, so the output is the same as with a single worker.
+func (g *DataGenerator) runSimulatorParallel(sim common.Simulator, serializer serialize.PointSerializer, dgc *DataGeneratorConfig) error {
+	out := g.output()
+	defer out.flush()
+
+	workers := int(dgc.Workers)
+	// Every batch is either being filled, serialized or waiting to be
+	// written; free holds the others and never blocks on return
+	free := make(chan *pointBatch, 2*workers+1)
+	for i := 0; i < cap(free); i++ {
+		free <- newPointBatch(len(out.shards))
+	}
+	toSerialize := make(chan *pointBatch, workers)
+	serialized := make(chan *pointBatch, workers)
+	// failed is closed when writing fails, to stop the simulation
+	failed := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range toSerialize {
+				b.serialize(serializer, out)
+				serialized <- b
+			}
+		}()
+	}
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- out.writeBatches(serialized, free, failed)
+	}()
+
+	currGroupID := uint(0)
+	seq := uint64(0)
+	b := <-free
+simulate:
+	for !sim.Finished() {
+		p := b.points[b.n]
+		write := sim.Next(p)
+		if !write {
+			p.Reset()
+			continue
+		}
+
+		// in the default case this is always true
+		if currGroupID == dgc.InterleavedGroupID {
+			p.OwnTimestamp()
+			b.n++
+			if b.n == len(b.points) {
+				b.seq = seq
+				seq++
+				toSerialize <- b
+				select {
+				case b = <-free:
+				case <-failed:
+					b = nil
+					break simulate
+				}
+			}
+		} else {
+			p.Reset()
+		}
+
+		currGroupID = (currGroupID + 1) % dgc.InterleavedNumGroups
+	}
+	if b != nil && b.n > 0 {
+		b.seq = seq
+		toSerialize <- b
+	}
+
+	close(toSerialize)
+	wg.Wait()
+	close(serialized)
+	return <-writeErr
+}
+
+// writeBatches writes the serialized batches to the shards in order of their
+// sequence numbers and hands them back to free. On the first error it closes
+// failed and only drains the remaining batches.
+func (o *dataOutput) writeBatches(serialized <-chan *pointBatch, free chan<- *pointBatch, failed chan struct{}) error {
+	pending := make(map[uint64]*pointBatch)
+	next := uint64(0)
+	var err error
+	for b := range serialized {
+		pending[b.seq] = b
+		for {
+			b, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if err == nil {
+				err = b.err
+				for i := range b.bufs {
+					if err != nil {
+						break
+					}
+					_, err = o.shards[i].w.Write(b.bufs[i].Bytes())
+					o.shards[i].points += b.counts[i]
+					o.written += b.counts[i]
+				}
+				if err != nil {
+					close(failed)
+				}
+			}
+			b.reset()
+			free <- b
+		}
+	}
+	return err
+}
+
+// simChunk holds the serialized points that a part of a partitioned
+// simulation made in a number of rounds
+type simChunk struct {
+	buf bytes.Buffer
+	// ends is the end in buf of every point the part wrote
+	ends []int
+	// shards is the shard of every point when sharding by entity
+	shards []int
+	// rounds is the number of points written in every round
+	rounds []int
+	err    error
+}
+
+// reset empties the chunk for reuse
+func (c *simChunk) reset() {
+	c.buf.Reset()
+	c.ends = c.ends[:0]
+	c.shards = c.shards[:0]
+	c.rounds = c.rounds[:0]
+	c.err = nil
+}
+
+// partOutput is where the points of a part of a partitioned simulation are
+// written from
+type partOutput struct {
+	chunks chan *simChunk
+	free   chan *simChunk
+	// chunk is the chunk being written, from point of round on
+	chunk *simChunk
+	round int
+	point int
+}
+
+// nextRound returns the chunk holding the next round of the part, or nil
+// once the part is finished
+func (po *partOutput) nextRound() *simChunk {
+	if po.chunk != nil && po.round < len(po.chunk.rounds) {
+		return po.chunk
+	}
+	if po.chunk != nil {
+		po.chunk.reset()
+		po.free <- po.chunk
+	}
+	po.chunk, po.round, po.point = <-po.chunks, 0, 0
+	return po.chunk
+}
+
+// runSimulatorPartitioned is runSimulator with the entities of sim split
+// into dgc.Workers parts, each simulated and serialized on its own
+// goroutine. The points of every round are written part by part, so the
+// output is the same as with a single worker.
+func (g *DataGenerator) runSimulatorPartitioned(sim common.PartitionedSimulator, serializer serialize.PointSerializer, dgc *DataGeneratorConfig) error {
+	out := g.output()
+	defer out.flush()
+
+	parts, roundPoints := sim.Partition(int(dgc.Workers))
+	// stop is closed when writing fails, to stop the parts
+	stop := make(chan struct{})
+	outputs := make([]*partOutput, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		// Every chunk is either being filled, waiting or being written
+		po := &partOutput{chunks: make(chan *simChunk, 1), free: make(chan *simChunk, 3)}
+		for j := 0; j < cap(po.free); j++ {
+			po.free <- &simChunk{}
+		}
+		outputs[i] = po
+		wg.Add(1)
+		go func(part common.Simulator, roundPoints int) {
+			defer wg.Done()
+			defer close(po.chunks)
+			simulatePart(part, roundPoints, serializer, out, po.free, po.chunks, stop)
+		}(part, roundPoints[i])
+	}
+
+	// made is the number of points the simulation wrote so far in all groups
+	made := uint64(0)
+	err := func() error {
+		for len(outputs) > 0 {
+			// The last round may end at any part, after which the others
+			// are finished too
+			for _, po := range outputs {
+				c := po.nextRound()
+				if c == nil {
+					return nil
+				}
+				if c.err != nil {
+					return c.err
+				}
+				n := c.rounds[po.round]
+				if err := out.writePoints(c, po.point, po.point+n, &made, dgc); err != nil {
+					return err
+				}
+				po.round++
+				po.point += n
+			}
+		}
+		return nil
+	}()
+	close(stop)
+	wg.Wait()
+	return err
+}
+
+// simulatePart simulates a part of a partitioned simulation, making
+// roundPoints points per round, and sends its points serialized to chunks in
+// chunks of about pointBatchSize points taken from free. It stops early when
+// stop is closed.
+func simulatePart(part common.Simulator, roundPoints int, serializer serialize.PointSerializer, out *dataOutput, free <-chan *simChunk, chunks chan<- *simChunk, stop <-chan struct{}) {
+	roundsPerChunk := 1
+	if roundPoints < pointBatchSize {
+		roundsPerChunk = pointBatchSize / roundPoints
+	}
+	p := serialize.NewPoint()
+	for !part.Finished() {
+		var c *simChunk
+		select {
+		case c = <-free:
+		case <-stop:
+			return
+		}
+		for r := 0; r < roundsPerChunk && !part.Finished(); r++ {
+			written := 0
+			for i := 0; i < roundPoints && !part.Finished(); i++ {
+				if part.Next(p) {
+					if c.err == nil {
+						if err := serializer.Serialize(p, &c.buf); err != nil {
+							c.err = fmt.Errorf("can not serialize point: %s", err)
+						}
+					}
+					c.ends = append(c.ends, c.buf.Len())
+					if out.shardBy == ShardByEntity {
+						// the number of the point only matters for round-robin
+						c.shards = append(c.shards, out.shardIndex(0, p))
+					}
+					written++
+				}
+				p.Reset()
+			}
+			c.rounds = append(c.rounds, written)
+		}
+		select {
+		case chunks <- c:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writePoints writes points [from, to) of c in the same way runSimulator
+// does. made is the number of points simulated before them in all groups.
+func (o *dataOutput) writePoints(c *simChunk, from, to int, made *uint64, dgc *DataGeneratorConfig) error {
+	for i := from; i < to; i++ {
+		// in the default case this is always true
+		if uint(*made%uint64(dgc.InterleavedNumGroups)) == dgc.InterleavedGroupID {
+			start := 0
+			if i > 0 {
+				start = c.ends[i-1]
+			}
+			shard := int(o.written % uint64(len(o.shards)))
+			if o.shardBy == ShardByEntity {
+				shard = c.shards[i]
+			}
+			s := o.shards[shard]
+			if _, err := s.w.Write(c.buf.Bytes()[start:c.ends[i]]); err != nil {
+				return err
+			}
+			s.points++
+			o.written++
+		}
+		*made++
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_data/devops"
+)
+
+func TestRunSimulatorParallelOrder(t *testing.T) {
+	const points = 3*pointBatchSize + 17
+	var buf bytes.Buffer
+	dgc := &DataGeneratorConfig{
+		BaseConfig:           BaseConfig{Scale: 1},
+		Limit:                points,
+		InitialScale:         1,
+		LogInterval:          defaultLogInterval,
+		InterleavedNumGroups: 1,
+		Workers:              4,
+	}
+	g := &DataGenerator{config: dgc, bufOut: bufio.NewWriter(&buf)}
+	sim := &testSimulator{limit: points, shouldWriteLimit: points}
+	if err := g.runSimulator(sim, &testSerializer{}, dgc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		if want := fmt.Sprintf("iteration=%d", lines); scanner.Text() != want {
+			t.Fatalf("incorrect line: got %s want %s", scanner.Text(), want)
+		}
+		lines++
+	}
+	if lines != points {
+		t.Errorf("incorrect number of points: got %d want %d", lines, points)
+	}
+}
+
+func TestRunSimulatorParallelError(t *testing.T) {
+	const points = 10 * pointBatchSize
+	var buf bytes.Buffer
+	dgc := &DataGeneratorConfig{
+		BaseConfig:           BaseConfig{Scale: 1},
+		Limit:                points,
+		InitialScale:         1,
+		LogInterval:          defaultLogInterval,
+		InterleavedNumGroups: 1,
+		Workers:              2,
+	}
+	g := &DataGenerator{config: dgc, bufOut: bufio.NewWriter(&buf)}
+	sim := &testSimulator{limit: points, shouldWriteLimit: points}
+	if err := g.runSimulator(sim, &testSerializer{shouldError: true}, dgc); err == nil {
+		t.Errorf("unexpected lack of error")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("unexpected output after error: %d bytes", buf.Len())
+	}
+}
+
+func TestRunSimulatorPartitionedError(t *testing.T) {
+	var buf bytes.Buffer
+	dgc := &DataGeneratorConfig{
+		BaseConfig:           BaseConfig{Scale: 100},
+		LogInterval:          defaultLogInterval,
+		InterleavedNumGroups: 1,
+		Workers:              3,
+	}
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	scfg := &devops.CPUOnlySimulatorConfig{
+		Start:           start,
+		End:             start.Add(time.Hour),
+		InitHostCount:   100,
+		HostCount:       100,
+		HostConstructor: devops.NewHostCPUOnly,
+	}
+	g := &DataGenerator{config: dgc, bufOut: bufio.NewWriter(&buf)}
+	if err := g.runSimulator(scfg.NewSimulator(dgc.LogInterval, 0), &testSerializer{shouldError: true}, dgc); err == nil {
+		t.Errorf("unexpected lack of error")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("unexpected output after error: %d bytes", buf.Len())
+	}
+}
+
+// TestGenerateParallelSameOutput checks that simulated data is the same
+// regardless of the number of workers.
+func TestGenerateParallelSameOutput(t *testing.T) {
+	generate := func(use, format string, workers uint) []byte {
+		c := &DataGeneratorConfig{
+			BaseConfig: BaseConfig{
+				Seed:      123,
+				Format:    format,
+				Use:       use,
+				Scale:     10,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   defaultTimeEnd,
+			},
+			Limit:                5000,
+			LogInterval:          10 * time.Second,
+			InterleavedNumGroups: 1,
+			Workers:              workers,
+		}
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		if err := dg.Generate(c); err != nil {
+			t.Fatalf("unexpected error generating %s with %d workers: %v", use, workers, err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, use := range []string{useCaseDevops, useCaseCPUOnly, useCaseIoT} {
+		for _, format := range []string{FormatTimescaleDB, FormatInflux} {
+			serial := generate(use, format, 1)
+			if parallel := generate(use, format, 4); !bytes.Equal(serial, parallel) {
+				t.Errorf("%s/%s: output with 4 workers differs from 1 worker", use, format)
+			}
+		}
+	}
+}
+
+// TestGeneratePartitionedSameOutput checks that simulations of many hosts
+// split over the workers give the same data as one worker, also when the
+// number of hosts grows, hosts churn or the last round is cut short.
+func TestGeneratePartitionedSameOutput(t *testing.T) {
+	cases := []struct {
+		desc         string
+		use          string
+		initialScale uint64
+		limit        uint64
+		churnRate    float64
+		groupID      uint
+		numGroups    uint
+	}{
+		{desc: "devops", use: useCaseDevops},
+		{desc: "devops growing", use: useCaseDevops, initialScale: 7},
+		{desc: "devops cut short", use: useCaseDevops, limit: 12345},
+		{desc: "devops churn", use: useCaseDevops, churnRate: 10},
+		{desc: "cpu-only interleaved", use: useCaseCPUOnly, groupID: 1, numGroups: 3},
+		{desc: "cpu-single growing", use: useCaseCPUSingle, initialScale: 3, limit: 999},
+	}
+
+	generate := func(desc string, c *DataGeneratorConfig) []byte {
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		if err := dg.Generate(c); err != nil {
+			t.Fatalf("%s: unexpected error with %d workers: %v", desc, c.Workers, err)
+		}
+		scfg, err := dg.getSimulatorConfig(c)
+		if err != nil {
+			t.Fatalf("%s: unexpected error getting simulator config: %v", desc, err)
+		}
+		sim := scfg.NewSimulator(c.LogInterval, c.Limit)
+		if _, ok := sim.(common.PartitionedSimulator); !ok {
+			t.Fatalf("%s: simulator %T can not be partitioned", desc, sim)
+		}
+		return buf.Bytes()
+	}
+
+	for _, c := range cases {
+		var want []byte
+		for _, workers := range []uint{1, 2, 7} {
+			dgc := &DataGeneratorConfig{
+				BaseConfig: BaseConfig{
+					Seed:      123,
+					Format:    FormatInflux,
+					Use:       c.use,
+					Scale:     50,
+					TimeStart: defaultTimeStart,
+					TimeEnd:   "2016-01-01T00:20:00Z",
+				},
+				InitialScale:         c.initialScale,
+				Limit:                c.limit,
+				LogInterval:          10 * time.Second,
+				InterleavedGroupID:   c.groupID,
+				InterleavedNumGroups: c.numGroups,
+				ChurnRate:            c.churnRate,
+				ChurnLifetime:        devops.LifetimeExponential,
+				Workers:              workers,
+			}
+			if dgc.InterleavedNumGroups == 0 {
+				dgc.InterleavedNumGroups = 1
+			}
+			got := generate(c.desc, dgc)
+			if workers == 1 {
+				want = got
+				if len(want) == 0 {
+					t.Fatalf("%s: no output", c.desc)
+				}
+			} else if !bytes.Equal(got, want) {
+				t.Errorf("%s: output with %d workers differs from 1 worker", c.desc, workers)
+			}
+		}
+	}
+}
+
+func TestDataGeneratorConfigValidateWorkers(t *testing.T) {
+	c := &DataGeneratorConfig{
+		BaseConfig: BaseConfig{
+			Seed:   123,
+			Format: FormatAkumuli,
+			Use:    useCaseDevops,
+			Scale:  10,
+		},
+		LogInterval:          time.Second,
+		InterleavedNumGroups: 1,
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c.Workers != 1 {
+		t.Errorf("incorrect default workers: got %d want 1", c.Workers)
+	}
+
+	c.Workers = 2
+	err := c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for parallel akumuli")
+	} else if want := fmt.Sprintf(errSerialFormatFmt, FormatAkumuli); err.Error() != want {
+		t.Errorf("incorrect error: got\n%s\nwant\n%s", err.Error(), want)
+	}
+
+	c.Format = FormatInflux
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error for parallel influx: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
@@ -93,6 +94,62 @@ func TestDataGeneratorConfigValidate(t *testing.T) {
 			t.Errorf("incorrect error for group id > num groups: got\n%s\nwant\n%s", got, want)
 		}
 	}
+	c.InterleavedGroupID = 0
+
+	// Test churn validation
+	if c.ChurnLifetime != devops.LifetimeExponential {
+		t.Errorf("incorrect default churn lifetime: got %s want %s", c.ChurnLifetime, devops.LifetimeExponential)
+	}
+	c.ChurnRate = -1
+	err = c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for negative churn rate")
+	} else if got := err.Error(); got != errChurnRateNegative {
+		t.Errorf("incorrect error for negative churn rate: got\n%s\nwant\n%s", got, errChurnRateNegative)
+	}
+	c.ChurnRate = 0.5
+	c.ChurnLifetime = "weibull"
+	err = c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for unknown churn lifetime")
+	} else if want := fmt.Sprintf(errUnknownLifetimeFmt, "weibull"); err.Error() != want {
+		t.Errorf("incorrect error for unknown churn lifetime: got\n%s\nwant\n%s", err.Error(), want)
+	}
+	c.ChurnLifetime = devops.LifetimeFixed
+	c.Use = useCaseIoT
+	err = c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for churn in iot use case")
+	} else if got := err.Error(); got != errChurnNeedsHosts {
+		t.Errorf("incorrect error for churn in iot use case: got\n%s\nwant\n%s", got, errChurnNeedsHosts)
+	}
+	c.Use = useCaseDevops
+	err = c.Validate()
+	if err != nil {
+		t.Errorf("unexpected error for churn in devops use case: %v", err)
+	}
+	c.ChurnRate = 0
+
+	// Test schema validation
+	c.Schema = "schema.yaml"
+	err = c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for schema without custom use case")
+	} else if got := err.Error(); got != errSchemaNeedsCustom {
+		t.Errorf("incorrect error for schema without custom use case: got\n%s\nwant\n%s", got, errSchemaNeedsCustom)
+	}
+	c.Use = useCaseCustom
+	err = c.Validate()
+	if err != nil {
+		t.Errorf("unexpected error for custom use case with schema: %v", err)
+	}
+	c.Schema = ""
+	err = c.Validate()
+	if err == nil {
+		t.Errorf("unexpected lack of error for custom use case without schema")
+	} else if got := err.Error(); got != errCustomNeedsSchema {
+		t.Errorf("incorrect error for custom use case without schema: got\n%s\nwant\n%s", got, errCustomNeedsSchema)
+	}
 }
 
 func TestDataGeneratorInit(t *testing.T) {
@@ -172,12 +229,12 @@ func TestDataGeneratorInit(t *testing.T) {
 const correctData = `tags,hostname string,region string,datacenter string,rack string,os string,arch string,team string,service string,service_version string,service_environment string
 cpu,usage_user,usage_system,usage_idle,usage_nice,usage_iowait,usage_irq,usage_softirq,usage_steal,usage_guest,usage_guest_nice
 
-tags,hostname=host_0,region=eu-central-1,datacenter=eu-central-1a,rack=6,os=Ubuntu15.10,arch=x86,team=SF,service=19,service_version=1,service_environment=test
-cpu,1451606400000000000,58,2,24,61,22,63,6,44,80,38
-tags,hostname=host_0,region=eu-central-1,datacenter=eu-central-1a,rack=6,os=Ubuntu15.10,arch=x86,team=SF,service=19,service_version=1,service_environment=test
-cpu,1451606401000000000,57,3,23,60,23,64,5,44,76,36
-tags,hostname=host_0,region=eu-central-1,datacenter=eu-central-1a,rack=6,os=Ubuntu15.10,arch=x86,team=SF,service=19,service_version=1,service_environment=test
-cpu,1451606402000000000,58,2,25,62,23,65,5,45,78,36
+tags,hostname=host_0,region=us-east-1,datacenter=us-east-1e,rack=66,os=Ubuntu16.04LTS,arch=x64,team=LON,service=5,service_version=0,service_environment=test
+cpu,1451606400000000000,21,61,79,78,42,28,21,97,51,57
+tags,hostname=host_0,region=us-east-1,datacenter=us-east-1e,rack=66,os=Ubuntu16.04LTS,arch=x64,team=LON,service=5,service_version=0,service_environment=test
+cpu,1451606401000000000,19,61,79,77,41,29,21,97,52,57
+tags,hostname=host_0,region=us-east-1,datacenter=us-east-1e,rack=66,os=Ubuntu16.04LTS,arch=x64,team=LON,service=5,service_version=0,service_environment=test
+cpu,1451606402000000000,19,62,78,76,42,28,21,96,50,58
 `
 
 func TestDataGeneratorGenerate(t *testing.T) {
@@ -215,6 +272,48 @@ func TestDataGeneratorGenerate(t *testing.T) {
 
 }
 
+// TestGenerateEntitySameAcrossScale checks that the data of a host does not
+// depend on the number of hosts simulated.
+func TestGenerateEntitySameAcrossScale(t *testing.T) {
+	generate := func(scale uint64) []string {
+		c := &DataGeneratorConfig{
+			BaseConfig: BaseConfig{
+				Seed:      123,
+				Format:    FormatInflux,
+				Use:       useCaseDevops,
+				Scale:     scale,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   "2016-01-01T00:10:00Z",
+			},
+			LogInterval:          10 * time.Second,
+			InterleavedNumGroups: 1,
+		}
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		if err := dg.Generate(c); err != nil {
+			t.Fatalf("unexpected error generating scale %d: %v", scale, err)
+		}
+		var lines []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.Contains(line, "hostname=host_3,") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	small := generate(5)
+	large := generate(10)
+	if len(small) == 0 || len(small) != len(large) {
+		t.Fatalf("incorrect number of lines for host_3: got %d and %d", len(small), len(large))
+	}
+	for i := range small {
+		if small[i] != large[i] {
+			t.Fatalf("line %d of host_3 differs between scales:\n%s\n%s", i, small[i], large[i])
+		}
+	}
+}
+
 var keyIteration = []byte("iteration")
 
 type testSimulator struct {
@@ -272,6 +371,7 @@ func TestRunSimulator(t *testing.T) {
 		totalGroups      uint
 		shouldError      bool
 		wantPoints       uint
+		workers          uint
 	}{
 		{
 			desc:             "shouldWriteLimit = limit",
@@ -324,6 +424,12 @@ func TestRunSimulator(t *testing.T) {
 			shouldWriteLimit: 10,
 		},
 	}
+	// The same again with parallel workers
+	for _, c := range cases[:len(cases):len(cases)] {
+		c.desc += ", workers=3"
+		c.workers = 3
+		cases = append(cases, c)
+	}
 	for _, c := range cases {
 		var buf bytes.Buffer
 		dgc := &DataGeneratorConfig{
@@ -335,6 +441,7 @@ func TestRunSimulator(t *testing.T) {
 			LogInterval:          defaultLogInterval,
 			InterleavedGroupID:   c.groupID,
 			InterleavedNumGroups: c.totalGroups,
+			Workers:              c.workers,
 		}
 		g := &DataGenerator{
 			config: dgc,
@@ -402,8 +509,24 @@ func TestGetSimulatorConfig(t *testing.T) {
 	checkType(useCaseCPUOnly, &devops.CPUOnlySimulatorConfig{})
 	checkType(useCaseCPUSingle, &devops.CPUOnlySimulatorConfig{})
 
+	schemaFile, err := ioutil.TempFile("", "schema*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(schemaFile.Name())
+	schemaFile.WriteString("entities: 1\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: constant}}]}]\n")
+	schemaFile.Close()
+	dgc.Schema = schemaFile.Name()
+	checkType(useCaseCustom, &common.BaseSimulatorConfig{})
+
+	dgc.Schema = schemaFile.Name() + ".missing"
+	if _, err := g.getSimulatorConfig(dgc); err == nil {
+		t.Errorf("unexpected lack of error for missing schema")
+	}
+	dgc.Schema = ""
+
 	dgc.Use = "bogus use case"
-	_, err := g.getSimulatorConfig(dgc)
+	_, err = g.getSimulatorConfig(dgc)
 	if err == nil {
 		t.Errorf("unexpected lack of error for bogus use case")
 	}
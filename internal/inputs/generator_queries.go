@@ -28,6 +28,7 @@ import (
 const (
 	ErrInvalidQueryConfig = "invalid config: QueryGenerator needs a QueryGeneratorConfig"
 	ErrEmptyQueryType     = "query type cannot be empty"
+	ErrQueryTypeAndMix    = "query type and query mix cannot both be set"
 
 	errBadQueryTypeFmt          = "invalid query type for use case '%s': '%s'"
 	errCouldNotDebugFmt         = "could not write debug output: %v"
@@ -56,6 +57,7 @@ type QueryGeneratorConfig struct {
 	BaseConfig
 	Limit                uint64 `mapstructure:"queries"`
 	QueryType            string `mapstructure:"query-type"`
+	QueryMix             string `mapstructure:"query-mix"`
 	InterleavedGroupID   uint   `mapstructure:"interleaved-generation-group-id"`
 	InterleavedNumGroups uint   `mapstructure:"interleaved-generation-groups"`
 
@@ -78,9 +80,17 @@ func (c *QueryGeneratorConfig) Validate() error {
 		return err
 	}
 
-	if c.QueryType == "" {
+	if c.QueryType == "" && c.QueryMix == "" {
 		return fmt.Errorf(ErrEmptyQueryType)
 	}
+	if c.QueryType != "" && c.QueryMix != "" {
+		return fmt.Errorf(ErrQueryTypeAndMix)
+	}
+	if c.QueryMix != "" {
+		if _, err := parseQueryMix(c.QueryMix); err != nil {
+			return err
+		}
+	}
 
 	err = validateGroups(c.InterleavedGroupID, c.InterleavedNumGroups)
 	return err
@@ -90,6 +100,8 @@ func (c *QueryGeneratorConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	c.BaseConfig.AddToFlagSet(fs)
 	fs.Uint64("queries", 1000, "Number of queries to generate.")
 	fs.String("query-type", "", "Query type. (Choices are in the use case matrix.)")
+	fs.String("query-mix", "",
+		"Weighted mix of query types to generate shuffled into one file instead of a single query type, e.g. 'single-groupby-1-1-1=50,lastpoint=30,high-cpu-all=20'.")
 
 	fs.Uint("interleaved-generation-group-id", 0,
 		"Group (0-indexed) to perform round-robin serialization within. Use this to scale up data generation to multiple processes.")
@@ -151,7 +163,12 @@ func (g *QueryGenerator) Generate(config GeneratorConfig) error {
 		return err
 	}
 
-	filler := g.useCaseMatrix[g.config.Use][g.config.QueryType](useGen)
+	var filler utils.QueryFiller
+	if g.config.QueryMix != "" {
+		filler = g.getMixFiller(useGen, g.config)
+	} else {
+		filler = g.useCaseMatrix[g.config.Use][g.config.QueryType](useGen)
+	}
 
 	return g.runQueryGeneration(useGen, filler, g.config)
 }
@@ -180,7 +197,15 @@ func (g *QueryGenerator) init(config GeneratorConfig) error {
 		return fmt.Errorf(errBadUseFmt, g.config.Use)
 	}
 
-	if _, ok := g.useCaseMatrix[g.config.Use][g.config.QueryType]; !ok {
+	if g.config.QueryMix != "" {
+		// Validate has already checked that the mix parses
+		mix, _ := parseQueryMix(g.config.QueryMix)
+		for _, e := range mix {
+			if _, ok := g.useCaseMatrix[g.config.Use][e.queryType]; !ok {
+				return fmt.Errorf(errBadQueryTypeFmt, g.config.Use, e.queryType)
+			}
+		}
+	} else if _, ok := g.useCaseMatrix[g.config.Use][g.config.QueryType]; !ok {
 		return fmt.Errorf(errBadQueryTypeFmt, g.config.Use, g.config.QueryType)
 	}
 
@@ -315,6 +340,18 @@ func (g *QueryGenerator) getUseCaseGenerator(c *QueryGeneratorConfig) (utils.Que
 	}
 }
 
+// getMixFiller returns a filler that generates the query types of the query
+// mix of c in their weighted proportions, shuffled deterministically by seed.
+func (g *QueryGenerator) getMixFiller(useGen utils.QueryGenerator, c *QueryGeneratorConfig) utils.QueryFiller {
+	mix, _ := parseQueryMix(c.QueryMix)
+	fillers := make([]utils.QueryFiller, len(mix))
+	for i, e := range mix {
+		fillers[i] = g.useCaseMatrix[c.Use][e.queryType](useGen)
+	}
+	seq := mixSequence(mix, c.Limit, rand.New(rand.NewSource(c.Seed)))
+	return newMixFiller(fillers, seq)
+}
+
 func (g *QueryGenerator) runQueryGeneration(useGen utils.QueryGenerator, filler utils.QueryFiller, c *QueryGeneratorConfig) error {
 	stats := make(map[string]int64)
 	currentGroup := uint(0)
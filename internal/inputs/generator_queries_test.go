@@ -300,12 +300,12 @@ func TestGetUseCaseGenerator(t *testing.T) {
 	g.config.MongoUseNaive = true
 	checkType(FormatMongo, nmongo)
 
-	bm := mysql.BaseGenerator{}
-	mysql, err := bm.NewDevops(tsStart, tsEnd, scale)
+	bmy := mysql.BaseGenerator{}
+	mysqlGen, err := bmy.NewDevops(tsStart, tsEnd, scale)
 	if err != nil {
 		t.Fatalf("Error creating mysql query generator")
 	}
-	checkType(FormatMysql, mysql)
+	checkType(FormatMysql, mysqlGen)
 
 	bcc := clickhouse.BaseGenerator{}
 	clickh, err := bcc.NewDevops(tsStart, tsEnd, scale)
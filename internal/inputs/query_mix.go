@@ -0,0 +1,112 @@
+package inputs
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	"github.com/timescale/tsbs/query"
+)
+
+const (
+	errBadQueryMixEntryFmt  = "invalid query mix entry '%s': want <query-type>=<weight>"
+	errBadQueryMixWeightFmt = "invalid weight for query type '%s' in query mix: '%s'"
+	errDupQueryMixTypeFmt   = "query type '%s' appears more than once in query mix"
+)
+
+// queryMixEntry is a query type of a query mix together with its weight
+type queryMixEntry struct {
+	queryType string
+	weight    uint64
+}
+
+// parseQueryMix parses a query mix of the form
+// "<query-type>=<weight>,<query-type>=<weight>,...", e.g.
+// "single-groupby-1-1-1=50,lastpoint=30,high-cpu-all=20".
+func parseQueryMix(s string) ([]queryMixEntry, error) {
+	var mix []queryMixEntry
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		kv := strings.Split(part, "=")
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf(errBadQueryMixEntryFmt, part)
+		}
+		queryType := strings.TrimSpace(kv[0])
+		weight, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf(errBadQueryMixWeightFmt, queryType, kv[1])
+		}
+		if seen[queryType] {
+			return nil, fmt.Errorf(errDupQueryMixTypeFmt, queryType)
+		}
+		seen[queryType] = true
+		mix = append(mix, queryMixEntry{queryType: queryType, weight: weight})
+	}
+	return mix, nil
+}
+
+// mixSequence returns, for each of n queries, the index of the query mix
+// entry to generate it from. Every entry gets its share of n by weight (the
+// remainder going to the entries with the largest fractional shares) and
+// the sequence is shuffled with r.
+func mixSequence(mix []queryMixEntry, n uint64, r *rand.Rand) []int {
+	total := uint64(0)
+	for _, e := range mix {
+		total += e.weight
+	}
+
+	counts := make([]uint64, len(mix))
+	remainders := make([]uint64, len(mix))
+	assigned := uint64(0)
+	for i, e := range mix {
+		counts[i] = n * e.weight / total
+		remainders[i] = n * e.weight % total
+		assigned += counts[i]
+	}
+	byRemainder := make([]int, len(mix))
+	for i := range byRemainder {
+		byRemainder[i] = i
+	}
+	sort.SliceStable(byRemainder, func(a, b int) bool {
+		return remainders[byRemainder[a]] > remainders[byRemainder[b]]
+	})
+	for i := uint64(0); assigned < n; i++ {
+		counts[byRemainder[i]]++
+		assigned++
+	}
+
+	seq := make([]int, 0, n)
+	for i, c := range counts {
+		for j := uint64(0); j < c; j++ {
+			seq = append(seq, i)
+		}
+	}
+	r.Shuffle(len(seq), func(i, j int) {
+		seq[i], seq[j] = seq[j], seq[i]
+	})
+	return seq
+}
+
+// mixFiller is a QueryFiller that fills each query with the next query type
+// of a shuffled query mix sequence, so a single run produces the query types
+// interleaved in their weighted proportions.
+type mixFiller struct {
+	fillers []utils.QueryFiller
+	seq     []int
+	next    int
+}
+
+func newMixFiller(fillers []utils.QueryFiller, seq []int) *mixFiller {
+	return &mixFiller{fillers: fillers, seq: seq}
+}
+
+// Fill fills q using the filler of the next query type in the sequence
+func (f *mixFiller) Fill(q query.Query) query.Query {
+	filler := f.fillers[f.seq[f.next%len(f.seq)]]
+	f.next++
+	return filler.Fill(q)
+}
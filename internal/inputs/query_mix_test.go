@@ -0,0 +1,198 @@
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+func TestParseQueryMix(t *testing.T) {
+	cases := []struct {
+		desc    string
+		in      string
+		want    []queryMixEntry
+		wantErr string
+	}{
+		{
+			desc: "single type",
+			in:   "lastpoint=1",
+			want: []queryMixEntry{{"lastpoint", 1}},
+		},
+		{
+			desc: "multiple types with spaces",
+			in:   "single-groupby-1-1-1=50, lastpoint = 30,high-cpu-all=20",
+			want: []queryMixEntry{{"single-groupby-1-1-1", 50}, {"lastpoint", 30}, {"high-cpu-all", 20}},
+		},
+		{
+			desc:    "missing weight",
+			in:      "lastpoint",
+			wantErr: fmt.Sprintf(errBadQueryMixEntryFmt, "lastpoint"),
+		},
+		{
+			desc:    "empty type",
+			in:      "=5",
+			wantErr: fmt.Sprintf(errBadQueryMixEntryFmt, "=5"),
+		},
+		{
+			desc:    "bad weight",
+			in:      "lastpoint=a",
+			wantErr: fmt.Sprintf(errBadQueryMixWeightFmt, "lastpoint", "a"),
+		},
+		{
+			desc:    "zero weight",
+			in:      "lastpoint=0",
+			wantErr: fmt.Sprintf(errBadQueryMixWeightFmt, "lastpoint", "0"),
+		},
+		{
+			desc:    "duplicate type",
+			in:      "lastpoint=1,lastpoint=2",
+			wantErr: fmt.Sprintf(errDupQueryMixTypeFmt, "lastpoint"),
+		},
+	}
+	for _, c := range cases {
+		got, err := parseQueryMix(c.in)
+		if c.wantErr != "" {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error", c.desc)
+			} else if err.Error() != c.wantErr {
+				t.Errorf("%s: incorrect error: got %s want %s", c.desc, err.Error(), c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect mix: got %v want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestMixSequence(t *testing.T) {
+	mix := []queryMixEntry{{"a", 50}, {"b", 30}, {"c", 20}}
+	cases := []struct {
+		n    uint64
+		want []int
+	}{
+		{n: 0, want: []int{0, 0, 0}},
+		{n: 10, want: []int{5, 3, 2}},
+		{n: 100, want: []int{50, 30, 20}},
+		// 3.5, 2.1 and 1.4: the remaining query goes to the largest remainder
+		{n: 7, want: []int{4, 2, 1}},
+	}
+	for _, c := range cases {
+		seq := mixSequence(mix, c.n, rand.New(rand.NewSource(123)))
+		if got := uint64(len(seq)); got != c.n {
+			t.Errorf("n %d: incorrect length: got %d", c.n, got)
+		}
+		counts := make([]int, len(mix))
+		for _, idx := range seq {
+			counts[idx]++
+		}
+		if !reflect.DeepEqual(counts, c.want) {
+			t.Errorf("n %d: incorrect counts: got %v want %v", c.n, counts, c.want)
+		}
+	}
+
+	// The sequence is shuffled, but the same for the same seed
+	seq := mixSequence(mix, 100, rand.New(rand.NewSource(123)))
+	sorted := true
+	for i := 1; i < len(seq); i++ {
+		if seq[i] < seq[i-1] {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		t.Errorf("sequence was not shuffled")
+	}
+	if again := mixSequence(mix, 100, rand.New(rand.NewSource(123))); !reflect.DeepEqual(seq, again) {
+		t.Errorf("sequence differs for the same seed")
+	}
+}
+
+func TestQueryGeneratorConfigValidateQueryMix(t *testing.T) {
+	c, _ := getTestConfigAndGenerator()
+	c.QueryType = ""
+	c.QueryMix = "single-groupby-1-1-1=2,lastpoint=1"
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error for correct query mix: %v", err)
+	}
+
+	c.QueryType = "lastpoint"
+	if err := c.Validate(); err == nil {
+		t.Errorf("unexpected lack of error for query type and query mix")
+	} else if got := err.Error(); got != ErrQueryTypeAndMix {
+		t.Errorf("incorrect error: got %s want %s", got, ErrQueryTypeAndMix)
+	}
+
+	c.QueryType = ""
+	c.QueryMix = "lastpoint"
+	if err := c.Validate(); err == nil {
+		t.Errorf("unexpected lack of error for bad query mix")
+	}
+}
+
+func TestQueryGeneratorGenerateQueryMix(t *testing.T) {
+	c, g := getTestConfigAndGenerator()
+	g.useCaseMatrix[useCaseCPUOnly]["lastpoint"] = devops.NewLastPointPerHost
+	c.QueryType = ""
+	c.Limit = 30
+	c.QueryMix = "single-groupby-1-1-1=2,lastpoint=1"
+
+	// Unknown query types in the mix are rejected
+	c.QueryMix = "single-groupby-1-1-1=2,foo=1"
+	err := g.Generate(c)
+	if err == nil {
+		t.Fatalf("unexpected lack of error for unknown query type in mix")
+	} else if want := fmt.Sprintf(errBadQueryTypeFmt, useCaseCPUOnly, "foo"); err.Error() != want {
+		t.Errorf("incorrect error: got %s want %s", err.Error(), want)
+	}
+
+	c.QueryMix = "single-groupby-1-1-1=2,lastpoint=1"
+	var buf bytes.Buffer
+	g.Out = &buf
+	g.DebugOut = ioutil.Discard
+	if err := g.Generate(c); err != nil {
+		t.Fatalf("unexpected error when generating: got %v", err)
+	}
+
+	counts := make(map[string]int)
+	var labels []string
+	decoder := gob.NewDecoder(bufio.NewReader(&buf))
+	for {
+		var q query.TimescaleDB
+		err := decoder.Decode(&q)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error while decoding: got %v", err)
+		}
+		counts[string(q.HumanLabel)]++
+		labels = append(labels, string(q.HumanLabel))
+	}
+	if len(counts) != 2 {
+		t.Fatalf("incorrect number of labels: got %d want %d", len(counts), 2)
+	}
+	if got := counts["TimescaleDB last row per host"]; got != 10 {
+		t.Errorf("incorrect number of lastpoint queries: got %d want %d", got, 10)
+	}
+	// the types are interleaved rather than generated one after the other
+	changes := 0
+	for i := 1; i < len(labels); i++ {
+		if labels[i] != labels[i-1] {
+			changes++
+		}
+	}
+	if changes < 2 {
+		t.Errorf("query types are not interleaved: %d changes", changes)
+	}
+}
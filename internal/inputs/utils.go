@@ -57,6 +57,7 @@ const (
 	useCaseCPUSingle = "cpu-single"
 	useCaseDevops    = "devops"
 	useCaseIoT       = "iot"
+	useCaseCustom    = "custom"
 )
 
 var useCaseChoices = []string{
@@ -64,6 +65,7 @@ var useCaseChoices = []string{
 	useCaseCPUSingle,
 	useCaseDevops,
 	useCaseIoT,
+	useCaseCustom,
 }
 
 // ParseUTCTime parses a string-represented time of the format 2006-01-02T15:04:05Z07:00
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	inputFileSeparator = ","
+
+	errNoInputFilesFmt = "no files match %q"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ExpandInputFiles returns the files named by spec, a comma-separated list
+// of file names and glob patterns. Matches of a pattern are sorted by name,
+// the order of the list itself is kept.
+func ExpandInputFiles(spec string) ([]string, error) {
+	var files []string
+	for _, part := range strings.Split(spec, inputFileSeparator) {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		if !strings.ContainsAny(part, "*?[") {
+			files = append(files, part)
+			continue
+		}
+		matches, err := filepath.Glob(part)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf(errNoInputFilesFmt, part)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf(errNoInputFilesFmt, spec)
+	}
+	return files, nil
+}
+
+// OpenInputFiles returns a reader over the files named by spec (see
+// ExpandInputFiles), read one after another. Files compressed with gzip or
+// zstd are detected by their magic bytes and decompressed transparently.
+// All files must exist when it is called.
+func OpenInputFiles(spec string) (io.ReadCloser, error) {
+	files, err := ExpandInputFiles(spec)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			return nil, err
+		}
+	}
+	return &multiFileReader{files: files}, nil
+}
+
+// multiFileReader reads a list of files in sequence
+type multiFileReader struct {
+	files []string
+	next  int
+	file  *os.File
+	r     io.Reader
+	close func() error
+}
+
+func (m *multiFileReader) Read(p []byte) (int, error) {
+	for {
+		if m.r == nil {
+			if m.next >= len(m.files) {
+				return 0, io.EOF
+			}
+			if err := m.open(m.files[m.next]); err != nil {
+				return 0, err
+			}
+			m.next++
+		}
+		n, err := m.r.Read(p)
+		if err == io.EOF {
+			if cerr := m.closeCurrent(); cerr != nil {
+				return n, cerr
+			}
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// open opens a single file, decompressing it if needed
+func (m *multiFileReader) open(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	r, closeFn, err := NewDecompressingReader(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot read %s: %v", name, err)
+	}
+	m.file, m.r, m.close = file, r, closeFn
+	return nil
+}
+
+// closeCurrent closes the file currently being read
+func (m *multiFileReader) closeCurrent() error {
+	if m.r == nil {
+		return nil
+	}
+	err := m.close()
+	if ferr := m.file.Close(); err == nil {
+		err = ferr
+	}
+	m.file, m.r, m.close = nil, nil, nil
+	return err
+}
+
+// Close closes the file currently being read
+func (m *multiFileReader) Close() error {
+	return m.closeCurrent()
+}
+
+// NewDecompressingReader returns a reader that decompresses r if it starts
+// with the magic bytes of gzip or zstd, and reads r as is otherwise. The
+// returned function releases the decompressor, it does not close r.
+func NewDecompressingReader(r io.Reader) (io.Reader, func() error, error) {
+	br := bufio.NewReader(r)
+	// Peek returns fewer bytes and an error for short inputs, which are
+	// then simply not compressed
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, gz.Close, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() error { zr.Close(); return nil }, nil
+	default:
+		return br, func() error { return nil }, nil
+	}
+}
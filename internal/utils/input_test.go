@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func writeTestInput(t *testing.T, name string, data []byte) {
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zstdBytes(t *testing.T, data []byte) []byte {
+	var b bytes.Buffer
+	w, err := zstd.NewWriter(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestExpandInputFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"data-2.txt", "data-1.txt", "other.txt"} {
+		writeTestInput(t, filepath.Join(dir, name), nil)
+	}
+
+	cases := []struct {
+		desc      string
+		spec      string
+		want      []string
+		expectErr bool
+	}{
+		{
+			desc: "single file",
+			spec: filepath.Join(dir, "other.txt"),
+			want: []string{filepath.Join(dir, "other.txt")},
+		},
+		{
+			desc: "glob is sorted",
+			spec: filepath.Join(dir, "data-*.txt"),
+			want: []string{filepath.Join(dir, "data-1.txt"), filepath.Join(dir, "data-2.txt")},
+		},
+		{
+			desc: "list keeps its order",
+			spec: filepath.Join(dir, "other.txt") + ", " + filepath.Join(dir, "data-*.txt"),
+			want: []string{filepath.Join(dir, "other.txt"), filepath.Join(dir, "data-1.txt"), filepath.Join(dir, "data-2.txt")},
+		},
+		{
+			desc:      "glob without matches",
+			spec:      filepath.Join(dir, "missing-*.txt"),
+			expectErr: true,
+		},
+		{
+			desc:      "empty list",
+			spec:      ",",
+			expectErr: true,
+		},
+	}
+	for _, c := range cases {
+		got, err := ExpandInputFiles(c.spec)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect files: got %v want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestOpenInputFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestInput(t, filepath.Join(dir, "1-plain.txt"), []byte("plain\n"))
+	writeTestInput(t, filepath.Join(dir, "2-empty.txt"), nil)
+	writeTestInput(t, filepath.Join(dir, "3-gzip.gz"), gzipBytes(t, []byte("gzip\n")))
+	writeTestInput(t, filepath.Join(dir, "4-zstd.zst"), zstdBytes(t, []byte("zstd\n")))
+
+	r, err := OpenInputFiles(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected close error: %v", err)
+	}
+	if want := "plain\ngzip\nzstd\n"; string(got) != want {
+		t.Errorf("incorrect content: got %q want %q", got, want)
+	}
+
+	if _, err := OpenInputFiles(filepath.Join(dir, "1-plain.txt") + "," + filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("expected error for a missing file")
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptSignals are the signals that end a benchmark run early
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// HandleInterrupts calls onInterrupt the first time the process receives
+// SIGINT or SIGTERM, so a run can stop early and still report what it has
+// done so far. A second signal exits immediately. The returned function
+// stops handling the signals and should be called once the run is over.
+func HandleInterrupts(onInterrupt func(os.Signal)) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, interruptSignals...)
+	go func() {
+		select {
+		case sig := <-signals:
+			onInterrupt(sig)
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-signals:
+			log.Fatalf("received %v again, exiting without summary", sig)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleInterrupts(t *testing.T) {
+	got := make(chan os.Signal, 1)
+	stop := HandleInterrupts(func(sig os.Signal) { got <- sig })
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("could not send signal: %v", err)
+	}
+	select {
+	case sig := <-got:
+		if sig != syscall.SIGTERM {
+			t.Errorf("incorrect signal: got %v want %v", sig, syscall.SIGTERM)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("interrupt handler not called")
+	}
+}
+
+func TestHandleInterruptsStopped(t *testing.T) {
+	called := make(chan struct{}, 1)
+	stop := HandleInterrupts(func(os.Signal) { called <- struct{}{} })
+	stop()
+	select {
+	case <-called:
+		t.Errorf("interrupt handler called without a signal")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions supported by NewCompressingWriter
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressionExtensions maps the file name extensions of compressed files
+// to their compression
+var compressionExtensions = map[string]string{
+	".gz":  CompressionGzip,
+	".zst": CompressionZstd,
+}
+
+// CompressionOfFile returns the compression implied by the extension of a
+// file name, CompressionNone if there is none.
+func CompressionOfFile(fileName string) string {
+	ext, compression := splitCompressionExtension(fileName)
+	if len(ext) == 0 {
+		return CompressionNone
+	}
+	return compression
+}
+
+// SplitCompressionExtension splits the extension of a compressed file off
+// its name, e.g. "data.csv.gz" into "data.csv" and ".gz". The extension is
+// empty if the name has no compression extension.
+func SplitCompressionExtension(fileName string) (string, string) {
+	ext, _ := splitCompressionExtension(fileName)
+	return strings.TrimSuffix(fileName, ext), ext
+}
+
+func splitCompressionExtension(fileName string) (string, string) {
+	for ext, compression := range compressionExtensions {
+		if strings.HasSuffix(fileName, ext) {
+			return ext, compression
+		}
+	}
+	return "", ""
+}
+
+// NewCompressingWriter returns a writer that compresses what is written to
+// w. It must be closed to write the end of the compressed stream; this does
+// not close w.
+func NewCompressingWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown compression '%s': must be %s, %s or %s", compression, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
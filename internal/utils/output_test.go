@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressionOfFile(t *testing.T) {
+	cases := []struct {
+		file        string
+		compression string
+		stem        string
+		ext         string
+	}{
+		{file: "data.gz", compression: CompressionGzip, stem: "data", ext: ".gz"},
+		{file: "dir/data.csv.zst", compression: CompressionZstd, stem: "dir/data.csv", ext: ".zst"},
+		{file: "data.txt", compression: CompressionNone, stem: "data.txt"},
+		{file: "", compression: CompressionNone},
+	}
+	for _, c := range cases {
+		if got := CompressionOfFile(c.file); got != c.compression {
+			t.Errorf("%s: incorrect compression: got %s want %s", c.file, got, c.compression)
+		}
+		stem, ext := SplitCompressionExtension(c.file)
+		if stem != c.stem || ext != c.ext {
+			t.Errorf("%s: incorrect split: got %s, %s want %s, %s", c.file, stem, ext, c.stem, c.ext)
+		}
+	}
+}
+
+func TestNewCompressingWriter(t *testing.T) {
+	data := []byte("cpu,hostname=host_0 usage_user=58 1451606400000000000\n")
+	for _, compression := range []string{"", CompressionNone, CompressionGzip, CompressionZstd} {
+		var b bytes.Buffer
+		w, err := NewCompressingWriter(&b, compression)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", compression, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: unexpected write error: %v", compression, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: unexpected close error: %v", compression, err)
+		}
+		compressed := compression != "" && compression != CompressionNone
+		if got := bytes.Equal(b.Bytes(), data); got == compressed {
+			t.Errorf("%s: output is compressed: %v want %v", compression, !got, compressed)
+		}
+
+		r, done, err := NewDecompressingReader(&b)
+		if err != nil {
+			t.Fatalf("%s: unexpected reader error: %v", compression, err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: unexpected read error: %v", compression, err)
+		}
+		done()
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: incorrect round trip: got %q want %q", compression, got, data)
+		}
+	}
+
+	if _, err := NewCompressingWriter(&bytes.Buffer{}, "bz2"); err == nil {
+		t.Errorf("expected error for an unknown compression")
+	}
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+	"github.com/shirou/gopsutil/process"
+)
+
+// DefaultResourceSamplingPeriod is used when no other sampling period is known
+const DefaultResourceSamplingPeriod = 10 * time.Second
+
+const bytesPerMB = 1 << 20
+
+// ResourceSample is a single measurement of the resources used by the
+// benchmark client process and by the host it runs on. CPU percentages are
+// relative to all CPUs of the host and, like the rates, cover the time since
+// the previous sample.
+type ResourceSample struct {
+	Time int64 `json:"Time"`
+
+	ProcessCPUPercent     float64 `json:"ProcessCPUPercent"`
+	ProcessRSSBytes       uint64  `json:"ProcessRSSBytes"`
+	ProcessReadBytesRate  float64 `json:"ProcessReadBytesRate"`
+	ProcessWriteBytesRate float64 `json:"ProcessWriteBytesRate"`
+
+	HostCPUPercent     float64 `json:"HostCPUPercent"`
+	HostMemUsedPercent float64 `json:"HostMemUsedPercent"`
+	HostNetSentRate    float64 `json:"HostNetSentRate"`
+	HostNetRecvRate    float64 `json:"HostNetRecvRate"`
+}
+
+// ResourceStat is the mean and the peak of a sampled value
+type ResourceStat struct {
+	Mean float64 `json:"Mean"`
+	Max  float64 `json:"Max"`
+}
+
+// ResourceSummary summarizes all samples of a run. Memory is in MB, rates are
+// in MB per second.
+type ResourceSummary struct {
+	Samples int `json:"Samples"`
+
+	ProcessCPUPercent ResourceStat `json:"ProcessCPUPercent"`
+	ProcessRSSMB      ResourceStat `json:"ProcessRSSMB"`
+	ProcessReadMBps   ResourceStat `json:"ProcessReadMBps"`
+	ProcessWriteMBps  ResourceStat `json:"ProcessWriteMBps"`
+
+	HostCPUPercent     ResourceStat `json:"HostCPUPercent"`
+	HostMemUsedPercent ResourceStat `json:"HostMemUsedPercent"`
+	HostNetSentMBps    ResourceStat `json:"HostNetSentMBps"`
+	HostNetRecvMBps    ResourceStat `json:"HostNetRecvMBps"`
+}
+
+// String formats the summary as a few human readable lines
+func (s ResourceSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "client resources (mean / peak over %d samples):\n", s.Samples)
+	fmt.Fprintf(&b, "process: cpu %0.1f%% / %0.1f%%, rss %0.1fMB / %0.1fMB, disk read %0.2fMB/s / %0.2fMB/s, disk write %0.2fMB/s / %0.2fMB/s\n",
+		s.ProcessCPUPercent.Mean, s.ProcessCPUPercent.Max, s.ProcessRSSMB.Mean, s.ProcessRSSMB.Max,
+		s.ProcessReadMBps.Mean, s.ProcessReadMBps.Max, s.ProcessWriteMBps.Mean, s.ProcessWriteMBps.Max)
+	fmt.Fprintf(&b, "host   : cpu %0.1f%% / %0.1f%%, mem %0.1f%% / %0.1f%%, net sent %0.2fMB/s / %0.2fMB/s, net recv %0.2fMB/s / %0.2fMB/s\n",
+		s.HostCPUPercent.Mean, s.HostCPUPercent.Max, s.HostMemUsedPercent.Mean, s.HostMemUsedPercent.Max,
+		s.HostNetSentMBps.Mean, s.HostNetSentMBps.Max, s.HostNetRecvMBps.Mean, s.HostNetRecvMBps.Max)
+	return b.String()
+}
+
+// ResourceSampler periodically samples the resources used by the current
+// process and its host, to show whether the benchmark client itself was
+// the bottleneck of a run.
+type ResourceSampler struct {
+	lock    sync.Mutex
+	samples []ResourceSample
+
+	proc     *process.Process
+	prevTime time.Time
+	prevRead uint64
+	prevWrt  uint64
+	prevSent uint64
+	prevRecv uint64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewResourceSampler returns a ResourceSampler for the current process. The
+// first sample covers the time from this call on.
+func NewResourceSampler() (*ResourceSampler, error) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+	s := &ResourceSampler{proc: proc}
+	// Only primes the CPU times and counters the first sample is relative to
+	s.measure()
+	return s, nil
+}
+
+// Start samples every period in the background until Stop is called
+func (s *ResourceSampler) Start(period time.Duration) {
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Sample()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling in the background and waits for a sample in progress
+func (s *ResourceSampler) Stop() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// Sample takes a single sample. Values the platform can't provide are zero.
+func (s *ResourceSampler) Sample() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.samples = append(s.samples, s.measure())
+}
+
+// Samples returns all samples taken so far
+func (s *ResourceSampler) Samples() []ResourceSample {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]ResourceSample(nil), s.samples...)
+}
+
+// Summary returns the mean and peak of all samples taken so far
+func (s *ResourceSampler) Summary() ResourceSummary {
+	return SummarizeResources(s.Samples())
+}
+
+// measure reads the current values and computes the rates since the previous call
+func (s *ResourceSampler) measure() ResourceSample {
+	now := time.Now()
+	sample := ResourceSample{Time: now.Unix()}
+	took := now.Sub(s.prevTime).Seconds()
+	rate := func(cur uint64, prev *uint64) float64 {
+		r := 0.0
+		if !s.prevTime.IsZero() && took > 0 && cur >= *prev {
+			r = float64(cur-*prev) / took
+		}
+		*prev = cur
+		return r
+	}
+
+	if pct, err := s.proc.Percent(0); err == nil {
+		sample.ProcessCPUPercent = pct
+	}
+	if mi, err := s.proc.MemoryInfo(); err == nil {
+		sample.ProcessRSSBytes = mi.RSS
+	}
+	if ioc, err := s.proc.IOCounters(); err == nil {
+		sample.ProcessReadBytesRate = rate(ioc.ReadBytes, &s.prevRead)
+		sample.ProcessWriteBytesRate = rate(ioc.WriteBytes, &s.prevWrt)
+	}
+	if pcts, err := cpu.Percent(0, false); err == nil && len(pcts) > 0 {
+		sample.HostCPUPercent = pcts[0]
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		sample.HostMemUsedPercent = vm.UsedPercent
+	}
+	if counters, err := net.IOCounters(false); err == nil && len(counters) > 0 {
+		sample.HostNetSentRate = rate(counters[0].BytesSent, &s.prevSent)
+		sample.HostNetRecvRate = rate(counters[0].BytesRecv, &s.prevRecv)
+	}
+	s.prevTime = now
+	return sample
+}
+
+// SummarizeResources returns the mean and peak of the given samples
+func SummarizeResources(samples []ResourceSample) ResourceSummary {
+	sum := ResourceSummary{Samples: len(samples)}
+	add := func(stat *ResourceStat, v float64) {
+		stat.Mean += v / float64(len(samples))
+		if v > stat.Max {
+			stat.Max = v
+		}
+	}
+	for _, s := range samples {
+		add(&sum.ProcessCPUPercent, s.ProcessCPUPercent)
+		add(&sum.ProcessRSSMB, float64(s.ProcessRSSBytes)/bytesPerMB)
+		add(&sum.ProcessReadMBps, s.ProcessReadBytesRate/bytesPerMB)
+		add(&sum.ProcessWriteMBps, s.ProcessWriteBytesRate/bytesPerMB)
+		add(&sum.HostCPUPercent, s.HostCPUPercent)
+		add(&sum.HostMemUsedPercent, s.HostMemUsedPercent)
+		add(&sum.HostNetSentMBps, s.HostNetSentRate/bytesPerMB)
+		add(&sum.HostNetRecvMBps, s.HostNetRecvRate/bytesPerMB)
+	}
+	return sum
+}
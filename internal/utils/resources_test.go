@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeResources(t *testing.T) {
+	samples := []ResourceSample{
+		{ProcessCPUPercent: 10, ProcessRSSBytes: 1 << 20, HostCPUPercent: 50, HostNetSentRate: 2 << 20},
+		{ProcessCPUPercent: 30, ProcessRSSBytes: 3 << 20, HostCPUPercent: 70, HostNetSentRate: 0},
+	}
+	s := SummarizeResources(samples)
+	if s.Samples != 2 {
+		t.Errorf("incorrect sample count: got %d want %d", s.Samples, 2)
+	}
+	cases := []struct {
+		desc string
+		stat ResourceStat
+		want ResourceStat
+	}{
+		{"process cpu", s.ProcessCPUPercent, ResourceStat{Mean: 20, Max: 30}},
+		{"process rss", s.ProcessRSSMB, ResourceStat{Mean: 2, Max: 3}},
+		{"host cpu", s.HostCPUPercent, ResourceStat{Mean: 60, Max: 70}},
+		{"host net sent", s.HostNetSentMBps, ResourceStat{Mean: 1, Max: 2}},
+		{"host net recv", s.HostNetRecvMBps, ResourceStat{}},
+	}
+	for _, c := range cases {
+		if c.stat != c.want {
+			t.Errorf("%s: incorrect stat: got %+v want %+v", c.desc, c.stat, c.want)
+		}
+	}
+
+	if got := SummarizeResources(nil); got != (ResourceSummary{}) {
+		t.Errorf("summary without samples not empty: %+v", got)
+	}
+}
+
+func TestResourceSummaryString(t *testing.T) {
+	s := ResourceSummary{Samples: 3, ProcessCPUPercent: ResourceStat{Mean: 12.5, Max: 40}}
+	got := s.String()
+	for _, want := range []string{"over 3 samples", "process: cpu 12.5% / 40.0%", "host   : cpu"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestResourceSampler(t *testing.T) {
+	s, err := NewResourceSampler()
+	if err != nil {
+		t.Fatalf("could not create sampler: %v", err)
+	}
+	s.Start(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	s.Stop()
+	s.Sample()
+
+	samples := s.Samples()
+	if len(samples) < 2 {
+		t.Fatalf("too few samples: got %d want at least %d", len(samples), 2)
+	}
+	last := samples[len(samples)-1]
+	if last.ProcessRSSBytes == 0 {
+		t.Errorf("process RSS not sampled")
+	}
+	if got := s.Summary().Samples; got != len(samples) {
+		t.Errorf("incorrect summary sample count: got %d want %d", got, len(samples))
+	}
+}
@@ -0,0 +1,108 @@
+package load
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filipecosta90/hdrhistogram"
+)
+
+const (
+	// batch latencies are recorded in microseconds, between 1us and 3600s
+	batchLatencyMin     = 1
+	batchLatencyMax     = 3600000000
+	batchLatencySigFigs = 3
+
+	// batchLatencyScale converts recorded values into milliseconds
+	batchLatencyScale = 1e3
+)
+
+// batchLatencies keeps one HDR histogram of ProcessBatch call durations per
+// worker. Each worker only records into its own histogram, so recording does
+// not need synchronization; the lock only guards registration of new workers.
+type batchLatencies struct {
+	mu      sync.Mutex
+	workers map[int]*hdrhistogram.Histogram
+}
+
+// newBatchLatencyHistogram returns an empty histogram with the range and
+// precision used for batch latencies
+func newBatchLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(batchLatencyMin, batchLatencyMax, batchLatencySigFigs)
+}
+
+// forWorker returns the histogram for the given worker, creating it if needed
+func (bl *batchLatencies) forWorker(workerNum int) *hdrhistogram.Histogram {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	if bl.workers == nil {
+		bl.workers = make(map[int]*hdrhistogram.Histogram)
+	}
+	h, ok := bl.workers[workerNum]
+	if !ok {
+		h = newBatchLatencyHistogram()
+		bl.workers[workerNum] = h
+	}
+	return h
+}
+
+// workerNums returns the sorted numbers of all workers that recorded latencies
+func (bl *batchLatencies) workerNums() []int {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	nums := make([]int, 0, len(bl.workers))
+	for n := range bl.workers {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// overall returns a histogram with the latencies of all workers merged
+func (bl *batchLatencies) overall() *hdrhistogram.Histogram {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	all := newBatchLatencyHistogram()
+	for _, h := range bl.workers {
+		all.Merge(h)
+	}
+	return all
+}
+
+// recordBatchLatency records the time elapsed since start into h
+func recordBatchLatency(h *hdrhistogram.Histogram, start time.Time) {
+	// Batches faster than the lowest trackable value are recorded as such
+	us := time.Since(start).Nanoseconds() / int64(time.Microsecond)
+	if us < batchLatencyMin {
+		us = batchLatencyMin
+	}
+	// Values beyond the range are dropped by the histogram; clamp them instead
+	if us > batchLatencyMax {
+		us = batchLatencyMax
+	}
+	_ = h.RecordValue(us)
+}
+
+// BatchLatencySummary describes the distribution of batch insert latencies.
+// All latencies are in milliseconds.
+type BatchLatencySummary struct {
+	Count int64   `json:"Count"`
+	Mean  float64 `json:"Mean"`
+	P50   float64 `json:"P50"`
+	P95   float64 `json:"P95"`
+	P99   float64 `json:"P99"`
+	Max   float64 `json:"Max"`
+}
+
+// newBatchLatencySummary builds a BatchLatencySummary from a histogram
+func newBatchLatencySummary(h *hdrhistogram.Histogram) BatchLatencySummary {
+	return BatchLatencySummary{
+		Count: h.TotalCount(),
+		Mean:  h.Mean() / batchLatencyScale,
+		P50:   float64(h.ValueAtQuantile(50.0)) / batchLatencyScale,
+		P95:   float64(h.ValueAtQuantile(95.0)) / batchLatencyScale,
+		P99:   float64(h.ValueAtQuantile(99.0)) / batchLatencyScale,
+		Max:   float64(h.Max()) / batchLatencyScale,
+	}
+}
@@ -0,0 +1,100 @@
+package load
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchLatenciesForWorker(t *testing.T) {
+	bl := &batchLatencies{}
+	h0 := bl.forWorker(0)
+	if got := bl.forWorker(0); got != h0 {
+		t.Errorf("forWorker returned a different histogram for the same worker")
+	}
+	h2 := bl.forWorker(2)
+	if h2 == h0 {
+		t.Errorf("forWorker returned the same histogram for different workers")
+	}
+	want := []int{0, 2}
+	got := bl.workerNums()
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("incorrect worker nums: got %v want %v", got, want)
+	}
+}
+
+func TestBatchLatenciesOverall(t *testing.T) {
+	bl := &batchLatencies{}
+	for i := 1; i <= 100; i++ {
+		_ = bl.forWorker(i % 2).RecordValue(int64(i * 1000))
+	}
+	s := newBatchLatencySummary(bl.overall())
+	if s.Count != 100 {
+		t.Errorf("incorrect count: got %d want %d", s.Count, 100)
+	}
+	if s.P50 < 49.9 || s.P50 > 50.1 {
+		t.Errorf("incorrect p50: got %f want ~%f", s.P50, 50.0)
+	}
+	if s.P99 < 98.9 || s.P99 > 99.1 {
+		t.Errorf("incorrect p99: got %f want ~%f", s.P99, 99.0)
+	}
+	if s.Max < 99.9 || s.Max > 100.1 {
+		t.Errorf("incorrect max: got %f want ~%f", s.Max, 100.0)
+	}
+	worker := newBatchLatencySummary(bl.forWorker(0))
+	if worker.Count != 50 {
+		t.Errorf("incorrect worker count: got %d want %d", worker.Count, 50)
+	}
+}
+
+func TestRecordBatchLatency(t *testing.T) {
+	h := newBatchLatencyHistogram()
+	recordBatchLatency(h, time.Now().Add(-10*time.Millisecond))
+	recordBatchLatency(h, time.Now().Add(10*time.Millisecond))
+	recordBatchLatency(h, time.Now().Add(-2*time.Hour))
+	if got := h.TotalCount(); got != 3 {
+		t.Errorf("incorrect count: got %d want %d", got, 3)
+	}
+	if got := h.Min(); got != batchLatencyMin {
+		t.Errorf("future start not clamped to min: got %d want %d", got, batchLatencyMin)
+	}
+	if got := h.Max(); got < batchLatencyMax {
+		t.Errorf("long batch not clamped to max: got %d want %d", got, batchLatencyMax)
+	}
+}
+
+func TestWorkRecordsBatchLatency(t *testing.T) {
+	br := &BenchmarkRunner{}
+	b := &testBenchmark{}
+	b.processors = append(b.processors, &testProcessor{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	c := newDuplexChannel(2)
+	c.sendToWorker(&testBatch{})
+	c.sendToWorker(&testBatch{})
+	go br.work(b, &wg, c, 3)
+	<-c.toScanner
+	<-c.toScanner
+	c.close()
+	wg.Wait()
+
+	if got := br.batchLatencies.forWorker(3).TotalCount(); got != 2 {
+		t.Errorf("incorrect number of recorded batches: got %d want %d", got, 2)
+	}
+
+	var out bytes.Buffer
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) {
+		return fmt.Fprintf(&out, s, args...)
+	}
+	defer func() { printFn = oldPrintFn }()
+	br.summary(time.Second)
+	for _, want := range []string{"batch latencies:", "all workers : count: 2", "worker 3    : count: 2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package load
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checkpoint records how far a load run got through its input, so that an
+// interrupted run can be continued with --resume-from.
+type Checkpoint struct {
+	// File is the --file the run was reading from (empty for STDIN)
+	File string `json:"File"`
+	// Items is the number of input items from the start of the input that
+	// have all been acknowledged by workers. Items after it may or may not
+	// have been loaded, so a resumed run can load a few of them twice.
+	Items uint64 `json:"Items"`
+	// Offset is the byte offset in the input just after the last of Items.
+	// Loaders that buffer input on their own read ahead of the decoder,
+	// so it is informational; resuming skips Items items instead.
+	Offset int64 `json:"Offset"`
+	// Time is the Unix time in seconds the checkpoint was taken at
+	Time int64 `json:"Time"`
+}
+
+// readCheckpoint reads a Checkpoint from a file written by writeCheckpoint
+func readCheckpoint(fileName string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// writeCheckpoint writes cp to fileName, replacing it atomically so a crash
+// while writing never leaves a partial checkpoint behind
+func writeCheckpoint(fileName string, cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fileName + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fileName)
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+// trackedBatch wraps a Batch with the position of its first input item, so
+// its completion can be reported to a progressTracker. Workers unwrap it
+// before handing the batch to the Processor.
+type trackedBatch struct {
+	Batch
+	item   uint64
+	offset int64
+}
+
+// unwrapBatch returns the Batch a trackedBatch wraps, or b itself
+func unwrapBatch(b Batch) (Batch, *trackedBatch) {
+	if tb, ok := b.(*trackedBatch); ok {
+		return tb.Batch, tb
+	}
+	return b, nil
+}
+
+// progressTracker keeps track of which input items have been loaded. Every
+// batch that has items in it but is not done yet is pending; all items before
+// the first item of the oldest pending batch are known to be loaded.
+type progressTracker struct {
+	lock    sync.Mutex
+	pending map[*trackedBatch]struct{}
+	// read and readOffset are the number of items the scanner has handed out
+	// in batches and the input offset after them
+	read       uint64
+	readOffset int64
+
+	br      *bufio.Reader
+	counter *countingReader
+}
+
+// newProgressTracker returns a progressTracker for input read through br from
+// counter, that starts skip items into the input
+func newProgressTracker(br *bufio.Reader, counter *countingReader, skip uint64) *progressTracker {
+	t := &progressTracker{
+		pending: make(map[*trackedBatch]struct{}),
+		read:    skip,
+		br:      br,
+		counter: counter,
+	}
+	t.readOffset = t.offset()
+	return t
+}
+
+// offset returns the current offset of the decoder in the input
+func (t *progressTracker) offset() int64 {
+	if t.counter == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&t.counter.n) - int64(t.br.Buffered())
+}
+
+// batchStarted marks a batch that just received its first item as pending
+func (t *progressTracker) batchStarted(tb *trackedBatch, item uint64, offset int64) {
+	tb.item = item
+	tb.offset = offset
+	t.lock.Lock()
+	t.pending[tb] = struct{}{}
+	t.lock.Unlock()
+}
+
+// batchDone marks a batch as loaded
+func (t *progressTracker) batchDone(tb *trackedBatch) {
+	t.lock.Lock()
+	delete(t.pending, tb)
+	t.lock.Unlock()
+}
+
+// itemsRead records that the scanner has put all items before item (which
+// ends at offset) into batches
+func (t *progressTracker) itemsRead(item uint64, offset int64) {
+	t.lock.Lock()
+	t.read = item
+	t.readOffset = offset
+	t.lock.Unlock()
+}
+
+// checkpoint returns the position up to which all items are loaded
+func (t *progressTracker) checkpoint() (uint64, int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	item, offset := t.read, t.readOffset
+	for tb := range t.pending {
+		if tb.item < item {
+			item, offset = tb.item, tb.offset
+		}
+	}
+	return item, offset
+}
+
+// saveCheckpoint writes the current checkpoint to the --checkpoint-file
+func (l *BenchmarkRunner) saveCheckpoint() {
+	items, offset := l.tracker.checkpoint()
+	cp := &Checkpoint{
+		File:   l.FileName,
+		Items:  items,
+		Offset: offset,
+		Time:   time.Now().Unix(),
+	}
+	if err := writeCheckpoint(l.CheckpointFile, cp); err != nil {
+		printFn("could not write checkpoint to %s: %v\n", l.CheckpointFile, err)
+	}
+}
+
+// checkpointPeriodically saves a checkpoint every period until done is closed
+func (l *BenchmarkRunner) checkpointPeriodically(period time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.saveCheckpoint()
+		case <-done:
+			return
+		}
+	}
+}
+
+// skipItems decodes and drops the first n items of the input, returning how
+// many were actually skipped
+func skipItems(decoder PointDecoder, br *bufio.Reader, n uint64) uint64 {
+	var skipped uint64
+	for skipped < n {
+		if decoder.Decode(br) == nil {
+			break
+		}
+		skipped++
+	}
+	return skipped
+}
@@ -0,0 +1,150 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointReadWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "checkpoint.json")
+	want := &Checkpoint{File: "data.txt", Items: 42, Offset: 1024, Time: 1}
+	if err := writeCheckpoint(fileName, want); err != nil {
+		t.Fatalf("could not write checkpoint: %v", err)
+	}
+	if _, err := os.Stat(fileName + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary checkpoint file left behind")
+	}
+	got, err := readCheckpoint(fileName)
+	if err != nil {
+		t.Fatalf("could not read checkpoint: %v", err)
+	}
+	if *got != *want {
+		t.Errorf("incorrect checkpoint: got %v want %v", got, want)
+	}
+
+	if _, err := readCheckpoint(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expected error reading a missing checkpoint")
+	}
+}
+
+func TestProgressTrackerCheckpoint(t *testing.T) {
+	tr := newProgressTracker(bufio.NewReader(bytes.NewReader(nil)), nil, 10)
+	if items, _ := tr.checkpoint(); items != 10 {
+		t.Errorf("incorrect initial checkpoint: got %d want %d", items, 10)
+	}
+
+	b1 := &trackedBatch{Batch: &testBatch{}}
+	b2 := &trackedBatch{Batch: &testBatch{}}
+	tr.batchStarted(b1, 10, 100)
+	tr.batchStarted(b2, 12, 120)
+	tr.itemsRead(15, 150)
+	if items, offset := tr.checkpoint(); items != 10 || offset != 100 {
+		t.Errorf("incorrect checkpoint with two pending: got %d/%d want %d/%d", items, offset, 10, 100)
+	}
+
+	tr.batchDone(b1)
+	if items, offset := tr.checkpoint(); items != 12 || offset != 120 {
+		t.Errorf("incorrect checkpoint with one pending: got %d/%d want %d/%d", items, offset, 12, 120)
+	}
+
+	tr.batchDone(b2)
+	if items, offset := tr.checkpoint(); items != 15 || offset != 150 {
+		t.Errorf("incorrect checkpoint with none pending: got %d/%d want %d/%d", items, offset, 15, 150)
+	}
+}
+
+func TestScanWithIndexerTracker(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02, 0x03, 0x04}
+	input := &countingReader{r: bytes.NewReader(data)}
+	br := bufio.NewReader(input)
+	channels := []*duplexChannel{newDuplexChannel(10)}
+	decoder := &testDecoder{}
+	// first item was loaded in a previous run
+	skipped := skipItems(decoder, br, 1)
+	if skipped != 1 {
+		t.Fatalf("incorrect number of skipped items: got %d want %d", skipped, 1)
+	}
+	tr := newProgressTracker(br, input, skipped)
+
+	// hold on to all batches so nothing is done yet
+	var received []Batch
+	done := make(chan struct{})
+	go func() {
+		for b := range channels[0].toWorker {
+			received = append(received, b)
+			if len(received) == 2 {
+				break
+			}
+		}
+		close(done)
+	}()
+	go func() {
+		<-done
+		for i := range received {
+			// acknowledge the second batch only
+			if i == 1 {
+				_, tb := unwrapBatch(received[i])
+				tr.batchDone(tb)
+			}
+			channels[0].sendToScanner()
+		}
+	}()
+
+	read := scanWithIndexer(channels, 2, 0, br, decoder, &testFactory{}, &ConstantIndexer{}, nil, tr)
+	if read != 4 {
+		t.Fatalf("incorrect number of items read: got %d want %d", read, 4)
+	}
+	items, offset := tr.checkpoint()
+	if items != 1 || offset != 1 {
+		t.Errorf("checkpoint moved past a pending batch: got %d/%d want %d/%d", items, offset, 1, 1)
+	}
+
+	_, tb := unwrapBatch(received[0])
+	if tb == nil {
+		t.Fatalf("batch was not wrapped for tracking")
+	}
+	if inner, _ := unwrapBatch(received[0]); inner.Len() != 2 {
+		t.Errorf("incorrect inner batch length: got %d want %d", inner.Len(), 2)
+	}
+	tr.batchDone(tb)
+	items, offset = tr.checkpoint()
+	if items != 5 || offset != 5 {
+		t.Errorf("incorrect final checkpoint: got %d/%d want %d/%d", items, offset, 5, 5)
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) { return 0, nil }
+	defer func() { printFn = oldPrintFn }()
+
+	fileName := filepath.Join(dir, "checkpoint.json")
+	if err := writeCheckpoint(fileName, &Checkpoint{Items: 7}); err != nil {
+		t.Fatal(err)
+	}
+	br := &BenchmarkRunner{}
+	br.ResumeFrom = fileName
+	br.DoCreateDB = true
+	br.resume()
+	if br.resumeItems != 7 {
+		t.Errorf("incorrect items to skip: got %d want %d", br.resumeItems, 7)
+	}
+	if br.DoCreateDB {
+		t.Errorf("resuming did not disable creating the database")
+	}
+}
@@ -0,0 +1,91 @@
+package insertstrategy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// RateUnitMetrics expresses a target insert rate in metrics per second
+	RateUnitMetrics = "metrics"
+	// RateUnitRows expresses a target insert rate in rows per second
+	RateUnitRows = "rows"
+
+	// defaultMaxLag is how far the insert schedule may fall behind before the
+	// workers are considered unable to keep up
+	defaultMaxLag = time.Second
+)
+
+// RateRegulator keeps the combined insert rate of all load workers at
+// a target number of items (metrics or rows) per second. Each worker
+// calls Wait after inserting a batch, and the goroutine sleeps until
+// that many items are allowed by the target rate. It acts as a token
+// bucket shared between all workers.
+type RateRegulator interface {
+	// Wait blocks until count more inserted items keep the combined rate at the target
+	Wait(count uint64)
+	// Lagged returns how often the workers fell behind the target rate by more
+	// than the allowed lag, i.e. the database could not keep up
+	Lagged() uint64
+}
+
+type rateRegulator struct {
+	lock     sync.Mutex
+	interval float64 // seconds per item
+	maxLag   time.Duration
+	next     time.Time
+	lagged   uint64
+	nowFn    nowProviderFn
+	sleepFn  func(time.Duration)
+}
+
+// NewRateRegulator returns an implementation of the RateRegulator interface
+// for the given target rate in items per second.
+func NewRateRegulator(targetRate float64) (RateRegulator, error) {
+	if targetRate <= 0 {
+		return nil, fmt.Errorf("target rate must be positive, can't be %f", targetRate)
+	}
+	return &rateRegulator{
+		interval: 1 / targetRate,
+		maxLag:   defaultMaxLag,
+		nowFn:    time.Now,
+		sleepFn:  time.Sleep,
+	}, nil
+}
+
+// ValidateRateUnit returns an error if unit is not a known rate unit
+func ValidateRateUnit(unit string) error {
+	if unit != RateUnitMetrics && unit != RateUnitRows {
+		return fmt.Errorf("unknown rate unit %q: must be %s or %s", unit, RateUnitMetrics, RateUnitRows)
+	}
+	return nil
+}
+
+func (r *rateRegulator) Wait(count uint64) {
+	r.lock.Lock()
+	now := r.nowFn()
+	if r.next.IsZero() {
+		r.next = now
+	}
+	// If inserts are behind schedule by more than the allowed lag, the
+	// database can't keep up. Restart the schedule from now so the backlog
+	// does not turn into an unbounded burst once it catches up.
+	if now.Sub(r.next) > r.maxLag {
+		r.next = now
+		r.lagged++
+	}
+	r.next = r.next.Add(time.Duration(float64(count) * r.interval * float64(time.Second)))
+	durationToSleep := r.next.Sub(now)
+	r.lock.Unlock()
+
+	if durationToSleep > 0 {
+		r.sleepFn(durationToSleep)
+	}
+}
+
+func (r *rateRegulator) Lagged() uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lagged
+}
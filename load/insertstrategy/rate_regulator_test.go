@@ -0,0 +1,77 @@
+package insertstrategy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateRegulator(t *testing.T) {
+	if _, err := NewRateRegulator(0); err == nil {
+		t.Error("expected error for zero target rate")
+	}
+	if _, err := NewRateRegulator(-1); err == nil {
+		t.Error("expected error for negative target rate")
+	}
+	res, err := NewRateRegulator(100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rr := res.(*rateRegulator)
+	if rr.interval != 0.01 {
+		t.Errorf("incorrect interval: got %f want %f", rr.interval, 0.01)
+	}
+	if rr.nowFn == nil || rr.sleepFn == nil {
+		t.Error("time functions not set up")
+	}
+}
+
+func TestValidateRateUnit(t *testing.T) {
+	for _, unit := range []string{RateUnitMetrics, RateUnitRows} {
+		if err := ValidateRateUnit(unit); err != nil {
+			t.Errorf("unexpected error for %s: %v", unit, err)
+		}
+	}
+	if err := ValidateRateUnit("points"); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
+
+func TestRateRegulatorWait(t *testing.T) {
+	start := time.Now()
+	now := start
+	var slept []time.Duration
+	rr := &rateRegulator{
+		interval: 1.0 / 1000, // 1000 items per second
+		maxLag:   time.Second,
+		nowFn:    func() time.Time { return now },
+		sleepFn:  func(d time.Duration) { slept = append(slept, d) },
+	}
+
+	// two workers insert 500 items at the same time: the first one waits half a
+	// second, the second one a full second
+	rr.Wait(500)
+	rr.Wait(500)
+	if len(slept) != 2 || slept[0] != 500*time.Millisecond || slept[1] != time.Second {
+		t.Fatalf("incorrect sleep times: got %v", slept)
+	}
+
+	// inserts that took longer than the schedule don't sleep
+	now = start.Add(1500 * time.Millisecond)
+	rr.Wait(100)
+	if len(slept) != 2 {
+		t.Errorf("slept although behind schedule: %v", slept)
+	}
+	if got := rr.Lagged(); got != 0 {
+		t.Errorf("lagged too early: got %d", got)
+	}
+
+	// falling behind by more than the allowed lag resets the schedule
+	now = start.Add(5 * time.Second)
+	rr.Wait(100)
+	if got := rr.Lagged(); got != 1 {
+		t.Errorf("incorrect lag count: got %d want %d", got, 1)
+	}
+	if len(slept) != 3 || slept[2] != 100*time.Millisecond {
+		t.Errorf("schedule not restarted from now: got %v", slept)
+	}
+}
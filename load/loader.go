@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/load/insertstrategy"
 )
 
@@ -66,6 +67,24 @@ type BenchmarkRunnerConfig struct {
 	ReportingPeriod time.Duration `mapstructure:"reporting-period"`
 	FileName        string        `mapstructure:"file"`
 	Seed            int64         `mapstructure:"seed"`
+	ResultsFile     string        `mapstructure:"results-file"`
+
+	BatchMaxAttempts     uint          `mapstructure:"batch-max-attempts"`
+	BatchRetryBackoff    time.Duration `mapstructure:"batch-retry-backoff"`
+	BatchRetryMaxBackoff time.Duration `mapstructure:"batch-retry-max-backoff"`
+	BatchFailurePolicy   string        `mapstructure:"batch-failure-policy"`
+
+	CheckpointFile   string        `mapstructure:"checkpoint-file"`
+	CheckpointPeriod time.Duration `mapstructure:"checkpoint-period"`
+	ResumeFrom       string        `mapstructure:"resume-from"`
+
+	MaxDuration    time.Duration `mapstructure:"max-duration"`
+	WarmupDuration time.Duration `mapstructure:"warmup-duration"`
+
+	TargetRate     float64 `mapstructure:"target-rate"`
+	TargetRateUnit string  `mapstructure:"target-rate-unit"`
+
+	SampleResources bool `mapstructure:"sample-resources"`
 }
 
 // AddToFlagSet adds command line flags needed by the BenchmarkRunnerConfig to the flag set.
@@ -78,8 +97,21 @@ func (c BenchmarkRunnerConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.Bool("do-create-db", true, "Whether to create the database. Disable on all but one client if running on a multi client setup.")
 	fs.Bool("do-abort-on-exist", false, "Whether to abort if a database with the given name already exists.")
 	fs.Duration("reporting-period", 10*time.Second, "Period to report write stats")
-	fs.String("file", "", "File name to read data from")
+	fs.String("file", "", "File name to read data from. Accepts a comma-separated list of files and glob patterns read in sequence; gzip and zstd files are decompressed automatically")
 	fs.Int64("seed", 0, "PRNG seed (default: 0, which uses the current timestamp)")
+	fs.String("results-file", "", "Write the test results summary json to this file")
+	fs.Uint("batch-max-attempts", defaultBatchMaxAttempts, "Number of times a failed batch is attempted before the batch failure policy applies (only for loaders reporting errors, currently InfluxDB and VictoriaMetrics; the latter retried rejected batches indefinitely before).")
+	fs.Duration("batch-retry-backoff", defaultBatchRetryBackoff, "Initial wait before retrying a failed batch, doubled on every attempt and jittered.")
+	fs.Duration("batch-retry-max-backoff", defaultBatchRetryMaxBackoff, "Maximum wait before retrying a failed batch.")
+	fs.String("checkpoint-file", "", "Periodically write the position in the input up to which all items are loaded to this file.")
+	fs.Duration("checkpoint-period", 10*time.Second, "Period to write the --checkpoint-file")
+	fs.String("resume-from", "", "Continue an interrupted run from this checkpoint file: skips the items already loaded and implies --do-create-db=false.")
+	fs.Duration("max-duration", 0, "Stop loading after this much time, even if there is input left (0 = no limit).")
+	fs.Duration("warmup-duration", 0, "Batches finished within this time from the start are loaded but excluded from the reported rates and latencies.")
+	fs.Float64("target-rate", 0, "Combined insert rate of all workers to aim for, in --target-rate-unit per second (0 = insert as fast as possible).")
+	fs.String("target-rate-unit", insertstrategy.RateUnitMetrics, fmt.Sprintf("Unit of --target-rate: '%s' or '%s' per second.", insertstrategy.RateUnitMetrics, insertstrategy.RateUnitRows))
+	fs.Bool("sample-resources", false, "Sample CPU, memory, disk and network use of the loader and its host every --reporting-period and report them in the summary.")
+	fs.String("batch-failure-policy", BatchFailureAbort, fmt.Sprintf("What to do with a batch that failed all attempts: '%s' the run, '%s' and continue, or '%s' (abort on the first error without retrying).", BatchFailureAbort, BatchFailureSkip, BatchFailureFailFast))
 }
 
 // BenchmarkRunner is responsible for initializing and storing common
@@ -87,10 +119,43 @@ func (c BenchmarkRunnerConfig) AddToFlagSet(fs *pflag.FlagSet) {
 type BenchmarkRunner struct {
 	BenchmarkRunnerConfig
 	br             *bufio.Reader
+	input          *countingReader
 	metricCnt      uint64
 	rowCnt         uint64
 	initialRand    *rand.Rand
 	sleepRegulator insertstrategy.SleepRegulator
+	rateRegulator  insertstrategy.RateRegulator
+	periods        []ReportPeriod
+	batchLatencies batchLatencies
+	retryPolicy    *retryPolicy
+	retryCnt       uint64
+	failedBatchCnt uint64
+	resources      *utils.ResourceSampler
+
+	// stopCh is closed to make the scanner stop reading input early
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// stopReason describes why the scanner was stopped, empty if it read all input
+	stopReason string
+	// interrupted is set if the run was stopped by SIGINT or SIGTERM
+	interrupted bool
+	warmup      warmupState
+
+	// resumeItems is the number of input items to skip when resuming from a checkpoint
+	resumeItems uint64
+	tracker     *progressTracker
+	// checkpointDone is closed to stop writing periodic checkpoints
+	checkpointDone chan struct{}
+}
+
+// warmupState holds the counters at the moment the --warmup-duration ended
+type warmupState struct {
+	lock      sync.Mutex
+	done      bool // done is set once the warmup has ended during the run
+	over      bool // over is set once the run has finished, the warmup can't end anymore
+	metricCnt uint64
+	rowCnt    uint64
+	endedAt   time.Time
 }
 
 var loader = &BenchmarkRunner{}
@@ -125,6 +190,16 @@ func GetBenchmarkRunnerWithBatchSize(c BenchmarkRunnerConfig, batchSize uint) *B
 		}
 	}
 
+	if loader.TargetRate > 0 {
+		if err = insertstrategy.ValidateRateUnit(loader.TargetRateUnit); err != nil {
+			panic(fmt.Sprintf("could not initialize BenchmarkRunner: %v", err))
+		}
+		loader.rateRegulator, err = insertstrategy.NewRateRegulator(loader.TargetRate)
+		if err != nil {
+			panic(fmt.Sprintf("could not initialize BenchmarkRunner: %v", err))
+		}
+	}
+
 	return loader
 }
 
@@ -136,6 +211,17 @@ func (l *BenchmarkRunner) DatabaseName() string {
 // RunBenchmark takes in a Benchmark b, a bufio.Reader br, and holders for number of metrics and rows
 // and uses those to run the load benchmark
 func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
+	var err error
+	l.retryPolicy, err = newRetryPolicy(l.BatchMaxAttempts, l.BatchRetryBackoff, l.BatchRetryMaxBackoff, l.BatchFailurePolicy)
+	if err != nil {
+		fatal("%v", err)
+		return
+	}
+
+	if len(l.ResumeFrom) > 0 {
+		l.resume()
+	}
+
 	l.br = l.GetBufferedReader()
 
 	// Create required DB
@@ -153,10 +239,29 @@ func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
 		go l.work(b, &wg, channels[i%numChannels], i)
 	}
 
+	if l.SampleResources {
+		l.startResourceSampler()
+	}
+
 	// Start scan process - actual data read process
 	start := time.Now()
+	l.stopCh = make(chan struct{})
+	stopInterrupts := utils.HandleInterrupts(l.interrupt)
+	defer stopInterrupts()
+	if l.MaxDuration > 0 {
+		deadline := time.AfterFunc(l.MaxDuration, func() {
+			l.stop(fmt.Sprintf("reached --max-duration of %v", l.MaxDuration))
+		})
+		defer deadline.Stop()
+	}
+	if l.WarmupDuration > 0 {
+		warmupEnd := time.AfterFunc(l.WarmupDuration, l.endWarmup)
+		defer warmupEnd.Stop()
+	}
 	stop_chan := make(chan int)
 	l.scan(b, channels, stop_chan)
+	// The scanner is done, a later stop request has no effect
+	l.stopOnce.Do(func() {})
 
 	// After scan process completed (no more data to come) - begin shutdown process
 
@@ -167,29 +272,136 @@ func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
 
 	// Wait for all workers to finish
 	wg.Wait()
+	l.finishWarmup()
 	end := time.Now()
 
+	if l.resources != nil {
+		// Stop sampling and cover the time since the last sample
+		l.resources.Stop()
+		l.resources.Sample()
+	}
+
+	if l.tracker != nil {
+		if l.checkpointDone != nil {
+			close(l.checkpointDone)
+		}
+		l.saveCheckpoint()
+	}
+
 	// Signal reporter to stop
 	stop_chan <- 0
 
-	l.summary(end.Sub(start))
+	measuredStart := l.measuredStart(start)
+	l.summary(end.Sub(measuredStart))
+
+	if len(l.ResultsFile) > 0 {
+		l.saveTestResult(measuredStart, end)
+	}
+}
+
+// startResourceSampler starts sampling the resources used by the loader and its
+// host at the reporting period
+func (l *BenchmarkRunner) startResourceSampler() {
+	sampler, err := utils.NewResourceSampler()
+	if err != nil {
+		printFn("WARNING: cannot sample resources: %v\n", err)
+		return
+	}
+	period := l.ReportingPeriod
+	if period <= 0 {
+		period = utils.DefaultResourceSamplingPeriod
+	}
+	sampler.Start(period)
+	l.resources = sampler
+}
+
+// stop makes the scanner stop reading input, so the run ends once the
+// batches already handed to workers are done. Only the first reason is kept.
+func (l *BenchmarkRunner) stop(reason string) {
+	l.stopOnce.Do(func() {
+		l.stopReason = reason
+		close(l.stopCh)
+	})
+}
+
+// interrupt stops the run on SIGINT or SIGTERM. The batches already handed to
+// workers are still loaded, and the summary covers everything loaded so far.
+func (l *BenchmarkRunner) interrupt(sig os.Signal) {
+	printFn("received %v, waiting for the batches in flight (send again to exit immediately)\n", sig)
+	l.stopOnce.Do(func() {
+		l.stopReason = fmt.Sprintf("interrupted (%v)", sig)
+		l.interrupted = true
+		close(l.stopCh)
+	})
+}
+
+// endWarmup records the counters at the end of the warmup period. Everything
+// loaded up to now is excluded from the reported rates.
+func (l *BenchmarkRunner) endWarmup() {
+	l.warmup.lock.Lock()
+	defer l.warmup.lock.Unlock()
+	if l.warmup.over {
+		return
+	}
+	l.warmup.metricCnt = atomic.LoadUint64(&l.metricCnt)
+	l.warmup.rowCnt = atomic.LoadUint64(&l.rowCnt)
+	l.warmup.endedAt = time.Now()
+	l.warmup.done = true
+}
+
+// finishWarmup marks the run as finished, so a warmup that has not ended yet
+// no longer can
+func (l *BenchmarkRunner) finishWarmup() {
+	l.warmup.lock.Lock()
+	l.warmup.over = true
+	l.warmup.lock.Unlock()
+}
+
+// warmupDone returns whether the warmup ended during the run
+func (l *BenchmarkRunner) warmupDone() bool {
+	l.warmup.lock.Lock()
+	defer l.warmup.lock.Unlock()
+	return l.warmup.done
+}
+
+// inWarmup returns whether the run is still within the --warmup-duration
+func (l *BenchmarkRunner) inWarmup() bool {
+	return l.WarmupDuration > 0 && !l.warmupDone()
+}
+
+// measuredStart returns the start of the measured part of the run: the end of
+// the warmup if there was one, the start of the run otherwise
+func (l *BenchmarkRunner) measuredStart(start time.Time) time.Time {
+	if l.warmupDone() {
+		return l.warmup.endedAt
+	}
+	return start
+}
+
+// measuredCounts returns the number of metrics and rows loaded after the warmup
+func (l *BenchmarkRunner) measuredCounts() (uint64, uint64) {
+	if l.warmupDone() {
+		return l.metricCnt - l.warmup.metricCnt, l.rowCnt - l.warmup.rowCnt
+	}
+	return l.metricCnt, l.rowCnt
 }
 
 // GetBufferedReader returns the buffered Reader that should be used by the loader
 func (l *BenchmarkRunner) GetBufferedReader() *bufio.Reader {
 	if l.br == nil {
 		if len(l.FileName) > 0 {
-			// Read from specified file
-			file, err := os.Open(l.FileName)
+			// Read from specified files, decompressing them if needed
+			file, err := utils.OpenInputFiles(l.FileName)
 			if err != nil {
 				fatal("cannot open file for read %s: %v", l.FileName, err)
 				return nil
 			}
-			l.br = bufio.NewReaderSize(file, defaultReadSize)
+			l.input = &countingReader{r: file}
 		} else {
 			// Read from STDIN
-			l.br = bufio.NewReaderSize(os.Stdin, defaultReadSize)
+			l.input = &countingReader{r: os.Stdin}
 		}
+		l.br = bufio.NewReaderSize(l.input, defaultReadSize)
 	}
 	return l.br
 }
@@ -275,8 +487,40 @@ func (l *BenchmarkRunner) scan(b Benchmark, channels []*duplexChannel, stop_chan
 		go l.report(l.ReportingPeriod, stop_chan)
 	}
 
+	decoder := b.GetPointDecoder(l.br)
+	if l.resumeItems > 0 {
+		skipped := skipItems(decoder, l.br, l.resumeItems)
+		printFn("skipped %d items already loaded according to %s\n", skipped, l.ResumeFrom)
+	}
+
+	if len(l.CheckpointFile) > 0 {
+		l.tracker = newProgressTracker(l.br, l.input, l.resumeItems)
+		if l.CheckpointPeriod > 0 {
+			l.checkpointDone = make(chan struct{})
+			go l.checkpointPeriodically(l.CheckpointPeriod, l.checkpointDone)
+		}
+	}
+
 	// Scan incoming data
-	return scanWithIndexer(channels, l.BatchSize, l.Limit, l.br, b.GetPointDecoder(l.br), b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))))
+	return scanWithIndexer(channels, l.BatchSize, l.Limit, l.br, decoder, b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))), l.stopCh, l.tracker)
+}
+
+// resume reads the --resume-from checkpoint, so the items it covers are
+// skipped and the existing database is reused
+func (l *BenchmarkRunner) resume() {
+	cp, err := readCheckpoint(l.ResumeFrom)
+	if err != nil {
+		fatal("cannot read checkpoint %s: %v", l.ResumeFrom, err)
+		return
+	}
+	if cp.File != l.FileName {
+		printFn("WARNING: checkpoint %s was taken reading from '%s', not '%s'\n", l.ResumeFrom, cp.File, l.FileName)
+	}
+	l.resumeItems = cp.Items
+	if l.DoCreateDB {
+		printFn("resuming from checkpoint %s, the database will not be created\n", l.ResumeFrom)
+		l.DoCreateDB = false
+	}
 }
 
 // work is the processing function for each worker in the loader
@@ -286,15 +530,26 @@ func (l *BenchmarkRunner) work(b Benchmark, wg *sync.WaitGroup, c *duplexChannel
 	proc := b.GetProcessor()
 	proc.Init(workerNum, l.DoLoad)
 
+	latencies := l.batchLatencies.forWorker(workerNum)
+
 	// Process batches coming from duplexChannel.toWorker queue
 	// and send ACKs into duplexChannel.toScanner queue
 	for b := range c.toWorker {
 		startedWorkAt := time.Now()
-		metricCnt, rowCnt := proc.ProcessBatch(b, l.DoLoad)
+		batch, tb := unwrapBatch(b)
+		metricCnt, rowCnt, loaded := l.processBatch(proc, batch, workerNum)
+		if !l.inWarmup() {
+			recordBatchLatency(latencies, startedWorkAt)
+		}
+		// A skipped batch stays pending, so checkpoints never move past it
+		if tb != nil && loaded {
+			l.tracker.batchDone(tb)
+		}
 		atomic.AddUint64(&l.metricCnt, metricCnt)
 		atomic.AddUint64(&l.rowCnt, rowCnt)
 		c.sendToScanner()
 		l.timeToSleep(workerNum, startedWorkAt)
+		l.waitForTargetRate(metricCnt, rowCnt)
 	}
 
 	// Close proc if necessary
@@ -312,15 +567,78 @@ func (l *BenchmarkRunner) timeToSleep(workerNum int, startedWorkAt time.Time) {
 	}
 }
 
+// waitForTargetRate makes the worker wait until the inserted items are allowed
+// by the --target-rate, if any
+func (l *BenchmarkRunner) waitForTargetRate(metricCnt, rowCnt uint64) {
+	if l.rateRegulator == nil {
+		return
+	}
+	if l.TargetRateUnit == insertstrategy.RateUnitRows {
+		l.rateRegulator.Wait(rowCnt)
+	} else {
+		l.rateRegulator.Wait(metricCnt)
+	}
+}
+
 // summary prints the summary of statistics from loading
 func (l *BenchmarkRunner) summary(took time.Duration) {
-	metricRate := float64(l.metricCnt) / float64(took.Seconds())
+	metricCnt, rowCnt := l.measuredCounts()
+	metricRate := float64(metricCnt) / float64(took.Seconds())
 	printFn("\nSummary:\n")
-	printFn("loaded %d metrics in %0.3fsec with %d workers (mean rate %0.2f metrics/sec)\n", l.metricCnt, took.Seconds(), l.Workers, metricRate)
-	if l.rowCnt > 0 {
-		rowRate := float64(l.rowCnt) / float64(took.Seconds())
-		printFn("loaded %d rows in %0.3fsec with %d workers (mean rate %0.2f rows/sec)\n", l.rowCnt, took.Seconds(), l.Workers, rowRate)
+	if len(l.stopReason) > 0 {
+		printFn("stopped early: %s\n", l.stopReason)
+	}
+	if l.warmupDone() {
+		printFn("excluded %d metrics and %d rows loaded during the %v warmup\n", l.warmup.metricCnt, l.warmup.rowCnt, l.WarmupDuration)
+	} else if l.WarmupDuration > 0 {
+		printFn("run ended before the %v warmup was over, reporting all batches\n", l.WarmupDuration)
+	}
+	printFn("loaded %d metrics in %0.3fsec with %d workers (mean rate %0.2f metrics/sec)\n", metricCnt, took.Seconds(), l.Workers, metricRate)
+	if rowCnt > 0 {
+		rowRate := float64(rowCnt) / float64(took.Seconds())
+		printFn("loaded %d rows in %0.3fsec with %d workers (mean rate %0.2f rows/sec)\n", rowCnt, took.Seconds(), l.Workers, rowRate)
+	}
+
+	if l.rateRegulator != nil {
+		achieved := l.achievedTargetRate(took)
+		printFn("target rate %0.2f %s/sec, achieved %0.2f %s/sec (%0.1f%%)\n", l.TargetRate, l.TargetRateUnit, achieved, l.TargetRateUnit, 100*achieved/l.TargetRate)
+		if lagged := l.rateRegulator.Lagged(); lagged > 0 {
+			printFn("WARNING: the database could not keep up with the target rate, inserts fell behind %d times\n", lagged)
+		}
+	}
+
+	if l.failedBatchCnt > 0 || l.retryCnt > 0 {
+		printFn("%d batches failed and were skipped, %d retries in total\n", l.failedBatchCnt, l.retryCnt)
 	}
+
+	if l.resources != nil {
+		printFn("%s", l.resources.Summary())
+	}
+
+	workerNums := l.batchLatencies.workerNums()
+	if len(workerNums) == 0 {
+		return
+	}
+	printFn("batch latencies:\n")
+	printBatchLatency("all workers", newBatchLatencySummary(l.batchLatencies.overall()))
+	for _, n := range workerNums {
+		printBatchLatency(fmt.Sprintf("worker %d", n), newBatchLatencySummary(l.batchLatencies.forWorker(n)))
+	}
+}
+
+// achievedTargetRate returns the mean insert rate in --target-rate-unit per second
+func (l *BenchmarkRunner) achievedTargetRate(took time.Duration) float64 {
+	count, rowCnt := l.measuredCounts()
+	if l.TargetRateUnit == insertstrategy.RateUnitRows {
+		count = rowCnt
+	}
+	return float64(count) / took.Seconds()
+}
+
+// printBatchLatency prints a single line of batch latency statistics
+func printBatchLatency(label string, s BatchLatencySummary) {
+	printFn("%-12s: count: %d, mean: %0.2fms, p50: %0.2fms, p95: %0.2fms, p99: %0.2fms, max: %0.2fms\n",
+		label, s.Count, s.Mean, s.P50, s.P95, s.P99, s.Max)
 }
 
 // report handles periodic reporting of loading stats
@@ -334,29 +652,40 @@ func (l *BenchmarkRunner) report(period time.Duration, stop_chan <-chan int) {
 	ticker := time.NewTicker(period)
 	for {
 		select {
-			case now := <-ticker.C:
-				cCount := atomic.LoadUint64(&l.metricCnt)
-				rCount := atomic.LoadUint64(&l.rowCnt)
-
-				sinceStart := now.Sub(start)
-				took := now.Sub(prevTime)
-				colrate := float64(cCount-prevColCount) / float64(took.Seconds())
-				overallColRate := float64(cCount) / float64(sinceStart.Seconds())
-				if rCount > 0 {
-					rowrate := float64(rCount-prevRowCount) / float64(took.Seconds())
-					overallRowRate := float64(rCount) / float64(sinceStart.Seconds())
-					printFn("%d,%0.2f,%E,%0.2f,%0.2f,%E,%0.2f\n", now.Unix(), colrate, float64(cCount), overallColRate, rowrate, float64(rCount), overallRowRate)
-				} else {
-					printFn("%d,%0.2f,%E,%0.2f,-,-,-\n", now.Unix(), colrate, float64(cCount), overallColRate)
-				}
+		case now := <-ticker.C:
+			cCount := atomic.LoadUint64(&l.metricCnt)
+			rCount := atomic.LoadUint64(&l.rowCnt)
+
+			sinceStart := now.Sub(start)
+			took := now.Sub(prevTime)
+			colrate := float64(cCount-prevColCount) / float64(took.Seconds())
+			overallColRate := float64(cCount) / float64(sinceStart.Seconds())
+			period := ReportPeriod{
+				Time:              now.Unix(),
+				PeriodMetricRate:  colrate,
+				MetricTotal:       cCount,
+				OverallMetricRate: overallColRate,
+			}
+			if rCount > 0 {
+				rowrate := float64(rCount-prevRowCount) / float64(took.Seconds())
+				overallRowRate := float64(rCount) / float64(sinceStart.Seconds())
+				period.PeriodRowRate = rowrate
+				period.RowTotal = rCount
+				period.OverallRowRate = overallRowRate
+				printFn("%d,%0.2f,%E,%0.2f,%0.2f,%E,%0.2f\n", now.Unix(), colrate, float64(cCount), overallColRate, rowrate, float64(rCount), overallRowRate)
+			} else {
+				printFn("%d,%0.2f,%E,%0.2f,-,-,-\n", now.Unix(), colrate, float64(cCount), overallColRate)
+			}
+
+			l.periods = append(l.periods, period)
 
-				prevColCount = cCount
-				prevRowCount = rCount
-				prevTime = now
+			prevColCount = cCount
+			prevRowCount = rCount
+			prevTime = now
 
-			case <-stop_chan:
-				ticker.Stop()
-				return
+		case <-stop_chan:
+			ticker.Stop()
+			return
 		}
 	}
 }
@@ -7,8 +7,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/timescale/tsbs/load/insertstrategy"
 )
 
 type testProcessor struct {
@@ -456,6 +459,68 @@ func TestWorkWithSleep(t *testing.T) {
 	}
 }
 
+type testRateRegulator struct {
+	counts []uint64
+	lagged uint64
+	lock   sync.Mutex
+}
+
+func (rr *testRateRegulator) Wait(count uint64) {
+	rr.lock.Lock()
+	rr.counts = append(rr.counts, count)
+	rr.lock.Unlock()
+}
+
+func (rr *testRateRegulator) Lagged() uint64 {
+	return rr.lagged
+}
+
+func TestWorkWithTargetRate(t *testing.T) {
+	for _, unit := range []string{insertstrategy.RateUnitMetrics, insertstrategy.RateUnitRows} {
+		rr := &testRateRegulator{}
+		br := &BenchmarkRunner{rateRegulator: rr}
+		br.TargetRateUnit = unit
+		b := &testBenchmark{}
+		b.processors = append(b.processors, &testProcessor{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		c := newDuplexChannel(1)
+		c.sendToWorker(&testBatch{})
+		go br.work(b, &wg, c, 0)
+		<-c.toScanner
+		c.close()
+		wg.Wait()
+
+		// testProcessor reports 1 metric and 0 rows per batch
+		want := uint64(1)
+		if unit == insertstrategy.RateUnitRows {
+			want = 0
+		}
+		if len(rr.counts) != 1 || rr.counts[0] != want {
+			t.Errorf("%s: rate regulator called with wrong counts: got %v want [%d]", unit, rr.counts, want)
+		}
+	}
+}
+
+func TestSummaryWithTargetRate(t *testing.T) {
+	br := &BenchmarkRunner{rateRegulator: &testRateRegulator{lagged: 3}}
+	br.TargetRate = 20
+	br.TargetRateUnit = insertstrategy.RateUnitMetrics
+	br.metricCnt = 10
+	var b bytes.Buffer
+	printFn = func(s string, args ...interface{}) (n int, err error) {
+		return fmt.Fprintf(&b, s, args...)
+	}
+	br.summary(time.Second)
+	got := string(b.Bytes())
+	if !strings.Contains(got, "target rate 20.00 metrics/sec, achieved 10.00 metrics/sec (50.0%)") {
+		t.Errorf("summary missing target rate line:\n%s", got)
+	}
+	if !strings.Contains(got, "fell behind 3 times") {
+		t.Errorf("summary missing lag warning:\n%s", got)
+	}
+}
+
 func TestSummary(t *testing.T) {
 	cases := []struct {
 		desc    string
@@ -521,7 +586,8 @@ func TestReport(t *testing.T) {
 	}
 	br := &BenchmarkRunner{}
 	duration := 200 * time.Millisecond
-	go br.report(duration)
+	stopChan := make(chan int)
+	go br.report(duration, stopChan)
 
 	time.Sleep(25 * time.Millisecond)
 	if got := atomic.LoadInt64(&counter); got != 1 {
@@ -556,4 +622,89 @@ func TestReport(t *testing.T) {
 	if end[len(end)-1:len(end)] == "-" {
 		t.Errorf("TestReport: row report ends in -")
 	}
+
+	stopChan <- 0
+	if got := len(br.periods); got != 3 {
+		t.Errorf("TestReport: incorrect number of recorded periods: got %d want %d", got, 3)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	br := &BenchmarkRunner{}
+	br.WarmupDuration = time.Minute
+	br.metricCnt = 10
+	br.rowCnt = 2
+	if !br.inWarmup() {
+		t.Errorf("runner not in warmup before it ended")
+	}
+	start := time.Now()
+	br.endWarmup()
+	if br.inWarmup() {
+		t.Errorf("runner still in warmup after it ended")
+	}
+	br.metricCnt = 25
+	br.rowCnt = 5
+	if got := br.measuredStart(start); got != br.warmup.endedAt {
+		t.Errorf("measured start is not the end of warmup: got %v want %v", got, br.warmup.endedAt)
+	}
+	metrics, rows := br.measuredCounts()
+	if metrics != 15 || rows != 3 {
+		t.Errorf("incorrect measured counts: got %d/%d want %d/%d", metrics, rows, 15, 3)
+	}
+
+	// a warmup that ends after the run finished has no effect
+	br = &BenchmarkRunner{}
+	br.WarmupDuration = time.Minute
+	br.metricCnt = 10
+	br.finishWarmup()
+	br.endWarmup()
+	if got := br.measuredStart(start); got != start {
+		t.Errorf("measured start changed after the run finished: got %v want %v", got, start)
+	}
+	if metrics, _ := br.measuredCounts(); metrics != 10 {
+		t.Errorf("incorrect measured metrics: got %d want %d", metrics, 10)
+	}
+}
+
+func TestStop(t *testing.T) {
+	br := &BenchmarkRunner{stopCh: make(chan struct{})}
+	br.stop("first")
+	br.stop("second")
+	select {
+	case <-br.stopCh:
+	default:
+		t.Errorf("stop channel not closed")
+	}
+	if br.stopReason != "first" {
+		t.Errorf("incorrect stop reason: got %s want %s", br.stopReason, "first")
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	br := &BenchmarkRunner{stopCh: make(chan struct{})}
+	var b bytes.Buffer
+	printFn = func(s string, args ...interface{}) (n int, err error) {
+		return fmt.Fprintf(&b, s, args...)
+	}
+	br.interrupt(syscall.SIGTERM)
+	select {
+	case <-br.stopCh:
+	default:
+		t.Errorf("stop channel not closed")
+	}
+	if !br.interrupted {
+		t.Errorf("run not marked as interrupted")
+	}
+	want := "interrupted (terminated)"
+	if br.stopReason != want {
+		t.Errorf("incorrect stop reason: got %s want %s", br.stopReason, want)
+	}
+
+	// An interrupt after the run was stopped for another reason keeps that reason
+	br = &BenchmarkRunner{stopCh: make(chan struct{})}
+	br.stop("first")
+	br.interrupt(syscall.SIGINT)
+	if br.interrupted || br.stopReason != "first" {
+		t.Errorf("interrupt changed an earlier stop: interrupted %v, reason %s", br.interrupted, br.stopReason)
+	}
 }
@@ -14,3 +14,22 @@ type ProcessorCloser interface {
 	// Close cleans up after a Processor
 	Close(doLoad bool)
 }
+
+// ProcessorWithError is a Processor that reports a failed batch back to the
+// loader instead of handling the failure itself. The loader uses
+// ProcessBatchWithError for such processors and retries failed batches
+// according to its --batch-* retry flags.
+type ProcessorWithError interface {
+	Processor
+	// ProcessBatchWithError handles a single batch of data, returning an error if it could not be loaded
+	ProcessBatchWithError(b Batch, doLoad bool) (metricCount, rowCount uint64, err error)
+}
+
+// ProcessorWithDiscard is a ProcessorWithError that is told when the loader
+// gives up on a failed batch, e.g. to return its buffers to a pool. Until
+// then the batch may be attempted again, so it must be left intact.
+type ProcessorWithDiscard interface {
+	ProcessorWithError
+	// DiscardBatch releases a batch that will not be attempted again
+	DiscardBatch(b Batch)
+}
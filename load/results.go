@@ -0,0 +1,159 @@
+package load
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+const loadResultFormatVersion = "0.1"
+
+// LoaderTestResult is the machine-readable summary of a load run that is
+// written to the file given with --results-file.
+type LoaderTestResult struct {
+	// ResultFormatVersion allows consumers to detect changes to the layout
+	ResultFormatVersion string `json:"ResultFormatVersion"`
+	// RunnerConfig holds the configuration the run was started with
+	RunnerConfig BenchmarkRunnerConfig `json:"RunnerConfig"`
+
+	// StartTime and EndTime are Unix timestamps in milliseconds
+	StartTime      int64 `json:"StartTime"`
+	EndTime        int64 `json:"EndTime"`
+	DurationMillis int64 `json:"DurationMillis"`
+	// Interrupted is set if the run was stopped by SIGINT or SIGTERM, so the
+	// results only cover part of the input
+	Interrupted bool `json:"Interrupted"`
+
+	Totals  LoaderTotals   `json:"Totals"`
+	Periods []ReportPeriod `json:"Periods"`
+
+	// BatchLatency summarizes the ProcessBatch latencies of all workers,
+	// WorkerBatchLatency holds the same summary keyed by worker number
+	BatchLatency       BatchLatencySummary         `json:"BatchLatency"`
+	WorkerBatchLatency map[int]BatchLatencySummary `json:"WorkerBatchLatency"`
+
+	// Resources summarizes the resources used by the loader and its host,
+	// only set when running with --sample-resources
+	Resources *utils.ResourceSummary `json:"Resources,omitempty"`
+}
+
+// LoaderTotals holds the overall counts and mean rates of a load run.
+type LoaderTotals struct {
+	MetricCount uint64  `json:"MetricCount"`
+	RowCount    uint64  `json:"RowCount"`
+	MetricRate  float64 `json:"MetricRate"`
+	RowRate     float64 `json:"RowRate"`
+	Workers     uint    `json:"Workers"`
+	BatchSize   uint    `json:"BatchSize"`
+	Seed        int64   `json:"Seed"`
+	// FailedBatches is the number of batches skipped after exhausting all attempts
+	FailedBatches uint64 `json:"FailedBatches"`
+	// Retries is the number of times a failed batch was attempted again
+	Retries uint64 `json:"Retries"`
+
+	// TargetRate, TargetRateUnit and AchievedRate are only set when running with --target-rate;
+	// TargetRateLagged counts how often the inserts fell behind the target rate
+	TargetRate       float64 `json:"TargetRate,omitempty"`
+	TargetRateUnit   string  `json:"TargetRateUnit,omitempty"`
+	AchievedRate     float64 `json:"AchievedRate,omitempty"`
+	TargetRateLagged uint64  `json:"TargetRateLagged"`
+
+	// WarmupMetricCount and WarmupRowCount were loaded during the --warmup-duration
+	// and are not part of the counts and rates above
+	WarmupMetricCount uint64 `json:"WarmupMetricCount"`
+	WarmupRowCount    uint64 `json:"WarmupRowCount"`
+	// StopReason is set if the run ended before all input was loaded
+	StopReason string `json:"StopReason,omitempty"`
+}
+
+// ReportPeriod is a single sample taken by the periodic reporter. Rates are
+// per second; the period rates cover the time since the previous sample.
+type ReportPeriod struct {
+	Time              int64   `json:"Time"`
+	PeriodMetricRate  float64 `json:"PeriodMetricRate"`
+	MetricTotal       uint64  `json:"MetricTotal"`
+	OverallMetricRate float64 `json:"OverallMetricRate"`
+	PeriodRowRate     float64 `json:"PeriodRowRate"`
+	RowTotal          uint64  `json:"RowTotal"`
+	OverallRowRate    float64 `json:"OverallRowRate"`
+}
+
+// newLoaderTestResult builds the LoaderTestResult for a run that started at
+// start and finished at end.
+func (l *BenchmarkRunner) newLoaderTestResult(start, end time.Time) *LoaderTestResult {
+	took := end.Sub(start)
+	metricCnt, rowCnt := l.measuredCounts()
+	totals := LoaderTotals{
+		MetricCount: metricCnt,
+		RowCount:    rowCnt,
+		Workers:     l.Workers,
+		BatchSize:   l.BatchSize,
+		Seed:        l.Seed,
+
+		FailedBatches: l.failedBatchCnt,
+		Retries:       l.retryCnt,
+		StopReason:    l.stopReason,
+	}
+	if l.warmupDone() {
+		totals.WarmupMetricCount = l.warmup.metricCnt
+		totals.WarmupRowCount = l.warmup.rowCnt
+	}
+	if took > 0 {
+		totals.MetricRate = float64(metricCnt) / took.Seconds()
+		totals.RowRate = float64(rowCnt) / took.Seconds()
+	}
+	if l.rateRegulator != nil {
+		totals.TargetRate = l.TargetRate
+		totals.TargetRateUnit = l.TargetRateUnit
+		totals.TargetRateLagged = l.rateRegulator.Lagged()
+		if took > 0 {
+			totals.AchievedRate = l.achievedTargetRate(took)
+		}
+	}
+
+	periods := l.periods
+	if periods == nil {
+		periods = []ReportPeriod{}
+	}
+
+	workerLatency := make(map[int]BatchLatencySummary)
+	for _, n := range l.batchLatencies.workerNums() {
+		workerLatency[n] = newBatchLatencySummary(l.batchLatencies.forWorker(n))
+	}
+
+	var resources *utils.ResourceSummary
+	if l.resources != nil {
+		summary := l.resources.Summary()
+		resources = &summary
+	}
+
+	return &LoaderTestResult{
+		ResultFormatVersion: loadResultFormatVersion,
+		RunnerConfig:        l.BenchmarkRunnerConfig,
+		StartTime:           start.UnixNano() / int64(time.Millisecond),
+		EndTime:             end.UnixNano() / int64(time.Millisecond),
+		DurationMillis:      took.Nanoseconds() / int64(time.Millisecond),
+		Interrupted:         l.interrupted,
+		Totals:              totals,
+		Periods:             periods,
+		BatchLatency:        newBatchLatencySummary(l.batchLatencies.overall()),
+		WorkerBatchLatency:  workerLatency,
+		Resources:           resources,
+	}
+}
+
+// saveTestResult writes the results of the run as JSON to the --results-file
+func (l *BenchmarkRunner) saveTestResult(start, end time.Time) {
+	res := l.newLoaderTestResult(start, end)
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		fatal("cannot encode results: %v", err)
+		return
+	}
+	printFn("Saving results json file to %s\n", l.ResultsFile)
+	if err := ioutil.WriteFile(l.ResultsFile, data, 0644); err != nil {
+		fatal("cannot write results file %s: %v", l.ResultsFile, err)
+	}
+}
@@ -0,0 +1,107 @@
+package load
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+func TestNewLoaderTestResult(t *testing.T) {
+	br := &BenchmarkRunner{}
+	br.Workers = 4
+	br.BatchSize = 1000
+	br.Seed = 123
+	br.metricCnt = 100
+	br.rowCnt = 10
+	start := time.Unix(1000, 0)
+	end := start.Add(2 * time.Second)
+
+	res := br.newLoaderTestResult(start, end)
+	if got := res.DurationMillis; got != 2000 {
+		t.Errorf("incorrect duration: got %d want %d", got, 2000)
+	}
+	if got := res.StartTime; got != 1000000 {
+		t.Errorf("incorrect start time: got %d want %d", got, 1000000)
+	}
+	if got := res.Totals.MetricRate; got != 50.0 {
+		t.Errorf("incorrect metric rate: got %f want %f", got, 50.0)
+	}
+	if got := res.Totals.RowRate; got != 5.0 {
+		t.Errorf("incorrect row rate: got %f want %f", got, 5.0)
+	}
+	if got := res.Totals.Workers; got != 4 {
+		t.Errorf("incorrect workers: got %d want %d", got, 4)
+	}
+	if got := res.Totals.Seed; got != 123 {
+		t.Errorf("incorrect seed: got %d want %d", got, 123)
+	}
+	if res.Periods == nil {
+		t.Errorf("periods should be an empty slice, not nil")
+	}
+	if res.Resources != nil {
+		t.Errorf("resources set without --sample-resources")
+	}
+}
+
+func TestNewLoaderTestResultWithResources(t *testing.T) {
+	sampler, err := utils.NewResourceSampler()
+	if err != nil {
+		t.Fatalf("could not create sampler: %v", err)
+	}
+	sampler.Sample()
+	br := &BenchmarkRunner{resources: sampler}
+	start := time.Now()
+	res := br.newLoaderTestResult(start, start.Add(time.Second))
+	if res.Resources == nil {
+		t.Fatalf("resources not set")
+	}
+	if got := res.Resources.Samples; got != 1 {
+		t.Errorf("incorrect number of resource samples: got %d want %d", got, 1)
+	}
+}
+
+func TestSaveTestResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-load-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) { return 0, nil }
+	defer func() { printFn = oldPrintFn }()
+
+	br := &BenchmarkRunner{}
+	br.ResultsFile = filepath.Join(dir, "results.json")
+	br.DBName = "benchmark"
+	br.metricCnt = 10
+	br.periods = []ReportPeriod{{Time: 1, MetricTotal: 5}, {Time: 2, MetricTotal: 10}}
+	start := time.Now()
+	br.saveTestResult(start, start.Add(time.Second))
+
+	data, err := ioutil.ReadFile(br.ResultsFile)
+	if err != nil {
+		t.Fatalf("results file not written: %v", err)
+	}
+	var got LoaderTestResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("results file is not valid json: %v", err)
+	}
+	if got.RunnerConfig.DBName != "benchmark" {
+		t.Errorf("incorrect db name: got %s want %s", got.RunnerConfig.DBName, "benchmark")
+	}
+	if got.Totals.MetricCount != 10 {
+		t.Errorf("incorrect metric count: got %d want %d", got.Totals.MetricCount, 10)
+	}
+	if len(got.Periods) != 2 {
+		t.Fatalf("incorrect number of periods: got %d want %d", len(got.Periods), 2)
+	}
+	if got.Periods[1].MetricTotal != 10 {
+		t.Errorf("incorrect period metric total: got %d want %d", got.Periods[1].MetricTotal, 10)
+	}
+}
@@ -0,0 +1,131 @@
+package load
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// BatchFailureAbort retries a failed batch and aborts the run once all attempts failed
+	BatchFailureAbort = "abort"
+	// BatchFailureSkip retries a failed batch and skips it once all attempts failed
+	BatchFailureSkip = "skip-batch"
+	// BatchFailureFailFast aborts the run on the first failure without retrying
+	BatchFailureFailFast = "fail-fast"
+
+	defaultBatchMaxAttempts     = 3
+	defaultBatchRetryBackoff    = time.Second
+	defaultBatchRetryMaxBackoff = 30 * time.Second
+
+	errUnknownBatchFailurePolicyFmt = "unknown batch failure policy %q: must be one of %s, %s, %s"
+)
+
+// retryPolicy decides how often and how long to wait before a batch that
+// failed with an error is sent again, and what happens once it gives up.
+type retryPolicy struct {
+	maxAttempts uint
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	onFailure   string
+
+	sleepFn  func(time.Duration)
+	jitterFn func(n int64) int64
+}
+
+// defaultRetryPolicy is used by a BenchmarkRunner with no configured policy:
+// a single attempt and abort on failure, same as a Processor that exits on error.
+var defaultRetryPolicy = &retryPolicy{
+	maxAttempts: 1,
+	onFailure:   BatchFailureAbort,
+	sleepFn:     time.Sleep,
+	jitterFn:    rand.Int63n,
+}
+
+// newRetryPolicy returns a retryPolicy for the given settings, or an error if
+// the failure policy is not known.
+func newRetryPolicy(maxAttempts uint, backoff, maxBackoff time.Duration, onFailure string) (*retryPolicy, error) {
+	switch onFailure {
+	case BatchFailureAbort, BatchFailureSkip:
+	case BatchFailureFailFast:
+		maxAttempts = 1
+	default:
+		return nil, fmt.Errorf(errUnknownBatchFailurePolicyFmt, onFailure, BatchFailureAbort, BatchFailureSkip, BatchFailureFailFast)
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return &retryPolicy{
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		onFailure:   onFailure,
+		sleepFn:     time.Sleep,
+		jitterFn:    rand.Int63n,
+	}, nil
+}
+
+// backoffFor returns how long to wait after the given (1-based) failed
+// attempt. The wait doubles with every attempt up to maxBackoff, and a random
+// jitter of up to half of it is applied so workers do not retry in lockstep.
+func (p *retryPolicy) backoffFor(attempt uint) time.Duration {
+	d := p.backoff
+	for i := uint(1); i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return time.Duration(half + p.jitterFn(half+1))
+}
+
+// processBatch hands the batch to the processor. Batches of a
+// ProcessorWithError are retried according to the runner's retry policy;
+// returns the number of metrics and rows loaded and false if the batch was skipped.
+func (l *BenchmarkRunner) processBatch(proc Processor, b Batch, workerNum int) (uint64, uint64, bool) {
+	ep, ok := proc.(ProcessorWithError)
+	if !ok {
+		metricCnt, rowCnt := proc.ProcessBatch(b, l.DoLoad)
+		return metricCnt, rowCnt, true
+	}
+
+	policy := l.retryPolicy
+	if policy == nil {
+		policy = defaultRetryPolicy
+	}
+
+	var err error
+	for attempt := uint(1); ; attempt++ {
+		var metricCnt, rowCnt uint64
+		metricCnt, rowCnt, err = ep.ProcessBatchWithError(b, l.DoLoad)
+		if err == nil {
+			return metricCnt, rowCnt, true
+		}
+		if attempt >= policy.maxAttempts {
+			break
+		}
+		atomic.AddUint64(&l.retryCnt, 1)
+		wait := policy.backoffFor(attempt)
+		printFn("[worker %d] batch failed (attempt %d of %d), retrying in %v: %v\n", workerNum, attempt, policy.maxAttempts, wait, err)
+		policy.sleepFn(wait)
+	}
+
+	failed := atomic.AddUint64(&l.failedBatchCnt, 1)
+	if policy.onFailure == BatchFailureSkip {
+		printFn("[worker %d] skipping batch after %d attempts: %v\n", workerNum, policy.maxAttempts, err)
+		if dp, ok := proc.(ProcessorWithDiscard); ok {
+			dp.DiscardBatch(b)
+		}
+		return 0, 0, false
+	}
+	fatal("[worker %d] aborting after batch failed %d time(s) (%d failed batches in total): %v", workerNum, policy.maxAttempts, failed, err)
+	return 0, 0, false
+}
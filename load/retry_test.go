@@ -0,0 +1,177 @@
+package load
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testErrorProcessor struct {
+	testProcessor
+	failures  int
+	calls     int
+	discarded int
+}
+
+func (p *testErrorProcessor) ProcessBatchWithError(b Batch, doLoad bool) (uint64, uint64, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return 0, 0, fmt.Errorf("failure %d", p.calls)
+	}
+	return 2, 1, nil
+}
+
+func (p *testErrorProcessor) DiscardBatch(b Batch) {
+	p.discarded++
+}
+
+func noopRetryPolicy(maxAttempts uint, onFailure string) *retryPolicy {
+	p, err := newRetryPolicy(maxAttempts, time.Millisecond, time.Second, onFailure)
+	if err != nil {
+		panic(err)
+	}
+	p.sleepFn = func(time.Duration) {}
+	return p
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	p, err := newRetryPolicy(0, time.Second, time.Millisecond, BatchFailureSkip)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.maxAttempts != 1 {
+		t.Errorf("max attempts not raised to 1: got %d", p.maxAttempts)
+	}
+	if p.maxBackoff != time.Second {
+		t.Errorf("max backoff not raised to backoff: got %v", p.maxBackoff)
+	}
+
+	p, err = newRetryPolicy(5, time.Second, time.Second, BatchFailureFailFast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.maxAttempts != 1 {
+		t.Errorf("fail-fast should only attempt once: got %d", p.maxAttempts)
+	}
+
+	if _, err = newRetryPolicy(5, time.Second, time.Second, "foo"); err == nil {
+		t.Errorf("unknown failure policy did not return an error")
+	}
+}
+
+func TestRetryPolicyBackoffFor(t *testing.T) {
+	p := noopRetryPolicy(10, BatchFailureAbort)
+	p.backoff = 100 * time.Millisecond
+	p.maxBackoff = time.Second
+	cases := []struct {
+		attempt  uint
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{5, 500 * time.Millisecond, time.Second},
+		{50, 500 * time.Millisecond, time.Second},
+	}
+	for _, c := range cases {
+		p.jitterFn = func(n int64) int64 { return 0 }
+		if got := p.backoffFor(c.attempt); got != c.min {
+			t.Errorf("attempt %d: incorrect backoff without jitter: got %v want %v", c.attempt, got, c.min)
+		}
+		p.jitterFn = func(n int64) int64 { return n - 1 }
+		if got := p.backoffFor(c.attempt); got != c.max {
+			t.Errorf("attempt %d: incorrect backoff with max jitter: got %v want %v", c.attempt, got, c.max)
+		}
+	}
+}
+
+func TestProcessBatchRetries(t *testing.T) {
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) { return 0, nil }
+	defer func() { printFn = oldPrintFn }()
+
+	br := &BenchmarkRunner{retryPolicy: noopRetryPolicy(3, BatchFailureAbort)}
+	p := &testErrorProcessor{failures: 2}
+	metrics, rows, ok := br.processBatch(p, &testBatch{}, 0)
+	if !ok {
+		t.Errorf("batch reported as skipped after succeeding")
+	}
+	if metrics != 2 || rows != 1 {
+		t.Errorf("incorrect counts: got %d/%d want %d/%d", metrics, rows, 2, 1)
+	}
+	if p.calls != 3 {
+		t.Errorf("incorrect number of attempts: got %d want %d", p.calls, 3)
+	}
+	if br.retryCnt != 2 {
+		t.Errorf("incorrect retry count: got %d want %d", br.retryCnt, 2)
+	}
+	if br.failedBatchCnt != 0 {
+		t.Errorf("incorrect failed batch count: got %d want %d", br.failedBatchCnt, 0)
+	}
+	if p.discarded != 0 {
+		t.Errorf("batch discarded after succeeding")
+	}
+}
+
+func TestProcessBatchSkip(t *testing.T) {
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) { return 0, nil }
+	defer func() { printFn = oldPrintFn }()
+
+	br := &BenchmarkRunner{retryPolicy: noopRetryPolicy(2, BatchFailureSkip)}
+	p := &testErrorProcessor{failures: 5}
+	metrics, rows, ok := br.processBatch(p, &testBatch{}, 0)
+	if ok {
+		t.Errorf("batch not reported as skipped")
+	}
+	if metrics != 0 || rows != 0 {
+		t.Errorf("skipped batch should not count: got %d/%d", metrics, rows)
+	}
+	if p.calls != 2 {
+		t.Errorf("incorrect number of attempts: got %d want %d", p.calls, 2)
+	}
+	if br.failedBatchCnt != 1 {
+		t.Errorf("incorrect failed batch count: got %d want %d", br.failedBatchCnt, 1)
+	}
+	if p.discarded != 1 {
+		t.Errorf("incorrect number of discarded batches: got %d want %d", p.discarded, 1)
+	}
+}
+
+func TestProcessBatchAbort(t *testing.T) {
+	oldPrintFn := printFn
+	printFn = func(s string, args ...interface{}) (n int, err error) { return 0, nil }
+	oldFatal := fatal
+	fatalCalled := false
+	fatal = func(format string, args ...interface{}) { fatalCalled = true }
+	defer func() {
+		printFn = oldPrintFn
+		fatal = oldFatal
+	}()
+
+	for _, policy := range []string{BatchFailureAbort, BatchFailureFailFast} {
+		fatalCalled = false
+		br := &BenchmarkRunner{retryPolicy: noopRetryPolicy(3, policy)}
+		p := &testErrorProcessor{failures: 5}
+		br.processBatch(p, &testBatch{}, 0)
+		if !fatalCalled {
+			t.Errorf("%s: fatal not called after all attempts failed", policy)
+		}
+		wantCalls := 3
+		if policy == BatchFailureFailFast {
+			wantCalls = 1
+		}
+		if p.calls != wantCalls {
+			t.Errorf("%s: incorrect number of attempts: got %d want %d", policy, p.calls, wantCalls)
+		}
+	}
+}
+
+func TestProcessBatchWithoutError(t *testing.T) {
+	br := &BenchmarkRunner{}
+	p := &testProcessor{}
+	metrics, _, _ := br.processBatch(p, &testBatch{}, 0)
+	if metrics != 1 {
+		t.Errorf("incorrect metric count: got %d want %d", metrics, 1)
+	}
+}
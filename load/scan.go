@@ -87,7 +87,11 @@ type PointDecoder interface {
 // Data is decoded by PointDecoder decoder and then placed into appropriate batches, using the supplied PointIndexer,
 // which are then dispatched to workers (duplexChannel chosen by PointIndexer). Scan does flow control to make sure workers are not left idle for too long
 // and also that the scanning process  does not starve them of CPU.
-func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br *bufio.Reader, decoder PointDecoder, factory BatchFactory, indexer PointIndexer) uint64 {
+// Once stop is closed, scanning ends early: batches not yet handed to a worker are dropped
+// and only the batches already sent are waited for. A nil stop channel never stops the scan.
+// If tracker is not nil, batches are wrapped in trackedBatch and the position of their
+// items is reported to the tracker for checkpointing.
+func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br *bufio.Reader, decoder PointDecoder, factory BatchFactory, indexer PointIndexer, stop <-chan struct{}, tracker *progressTracker) uint64 {
 	var itemsRead uint64
 	numChannels := len(channels)
 
@@ -101,10 +105,22 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 	// 2. unsentBatches contains batches ready to be sent to a worker.
 	//    As soon as a worker's chan is available (i.e., not blocking), the batch is placed onto that worker's chan.
 
+	// newBatch returns a new empty batch, wrapped for progress tracking if needed
+	newBatch := func() Batch {
+		if tracker != nil {
+			return &trackedBatch{Batch: factory.New()}
+		}
+		return factory.New()
+	}
+	var firstItem uint64
+	if tracker != nil {
+		firstItem, _ = tracker.checkpoint()
+	}
+
 	// Current batches (per channel) that are being filled with items from scanner
 	fillingBatches := make([]Batch, numChannels)
 	for i := range fillingBatches {
-		fillingBatches[i] = factory.New()
+		fillingBatches[i] = newBatch()
 	}
 
 	// Batches that are ready to be set when space on a channel opens
@@ -134,6 +150,7 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 	// so we don't go over a limit (olimit), in order to slow down the scanner so it doesn't starve the workers
 	ocnt := 0
 	olimit := numChannels * cap(channels[0].toWorker) * 3
+	stopped := false
 	for {
 
 		// Check whether incoming items limit reached.
@@ -142,6 +159,16 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 			break
 		}
 
+		// Check whether we were asked to stop early
+		select {
+		case <-stop:
+			stopped = true
+		default:
+		}
+		if stopped {
+			break
+		}
+
 		caseLimit := len(cases)
 		if ocnt >= olimit {
 			// We have too many outstanding batches, wait until one finishes (i.e. no default)
@@ -155,6 +182,10 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 		}
 
 		// Prepare new batch - decode new item and append it to batch
+		var offset int64
+		if tracker != nil {
+			offset = tracker.offset()
+		}
 		item := decoder.Decode(br)
 		if item == nil {
 			// Nothing to scan any more - input is empty or failed
@@ -165,6 +196,9 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 
 		// Append new item to batch
 		idx := indexer.GetIndex(item)
+		if tracker != nil && fillingBatches[idx].Len() == 0 {
+			tracker.batchStarted(fillingBatches[idx].(*trackedBatch), firstItem+itemsRead-1, offset)
+		}
 		fillingBatches[idx].Append(item)
 
 		if fillingBatches[idx].Len() >= int(batchSize) {
@@ -172,16 +206,31 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 			// or moved to outstanding, in case no workers available atm.
 			unsentBatches[idx] = sendOrQueueBatch(channels[idx], &ocnt, fillingBatches[idx], unsentBatches[idx])
 			// Place new empty batch
-			fillingBatches[idx] = factory.New()
+			fillingBatches[idx] = newBatch()
+			if tracker != nil {
+				tracker.itemsRead(firstItem+itemsRead, tracker.offset())
+			}
 		}
 	}
 
-	// Finished reading input - no more items to come
-	// Make sure last batch goes out - it may be smaller than batchSize requested - there is not more items
-	for idx, b := range fillingBatches {
-		// Do not enqueue empty batches (with 0 items)
-		if b.Len() > 0 {
-			unsentBatches[idx] = sendOrQueueBatch(channels[idx], &ocnt, fillingBatches[idx], unsentBatches[idx])
+	if stopped {
+		// Stopping early - drop the batches no worker has received yet,
+		// they no longer count as outstanding
+		for idx := range unsentBatches {
+			ocnt -= len(unsentBatches[idx])
+			unsentBatches[idx] = unsentBatches[idx][:0]
+		}
+	} else {
+		// Finished reading input - no more items to come
+		// Make sure last batch goes out - it may be smaller than batchSize requested - there is not more items
+		for idx, b := range fillingBatches {
+			// Do not enqueue empty batches (with 0 items)
+			if b.Len() > 0 {
+				unsentBatches[idx] = sendOrQueueBatch(channels[idx], &ocnt, fillingBatches[idx], unsentBatches[idx])
+			}
+		}
+		if tracker != nil {
+			tracker.itemsRead(firstItem+itemsRead, tracker.offset())
 		}
 	}
 
@@ -253,13 +253,38 @@ func TestScanWithIndexer(t *testing.T) {
 						t.Errorf("%s: did not panic when should", c.desc)
 					}
 				}()
-				scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
+				scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer, nil, nil)
 			}()
 			continue
 		} else {
 			go _boringWorker(channels[0])
-			read := scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
+			read := scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer, nil, nil)
 			_checkScan(t, c.desc, decoder.called, read, c.wantCalls)
 		}
 	}
 }
+
+func TestScanWithIndexerStop(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02}
+	br := bufio.NewReader(bytes.NewReader(data))
+	channels := []*duplexChannel{newDuplexChannel(1)}
+	decoder := &testDecoder{0}
+	stop := make(chan struct{})
+	close(stop)
+
+	batches := 0
+	go func() {
+		for range channels[0].toWorker {
+			batches++
+			channels[0].sendToScanner()
+		}
+	}()
+	read := scanWithIndexer(channels, 1, 0, br, decoder, &testFactory{}, &ConstantIndexer{}, stop, nil)
+	channels[0].close()
+	if read != 0 {
+		t.Errorf("scan read items after being stopped: got %d", read)
+	}
+	if batches != 0 {
+		t.Errorf("scan sent batches after being stopped: got %d", batches)
+	}
+}
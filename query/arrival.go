@@ -0,0 +1,65 @@
+package query
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// ArrivalConstant sends queries at evenly spaced intervals
+	ArrivalConstant = "constant"
+	// ArrivalPoisson sends queries with exponentially distributed gaps, i.e. as a Poisson process
+	ArrivalPoisson = "poisson"
+
+	// arrivalSeed makes the Poisson arrival times the same for every run
+	arrivalSeed = 1
+)
+
+// arrivalSchedule hands out the intended send times of an open-loop run.
+// The times only depend on the arrival rate, not on how fast the database
+// answers, so a query that has to wait for a free worker is late and the
+// wait is part of its response time. This avoids coordinated omission.
+type arrivalSchedule struct {
+	lock     sync.Mutex
+	interval float64 // mean seconds between two queries
+	poisson  bool
+	rand     *rand.Rand
+	next     time.Time
+	nowFn    func() time.Time
+}
+
+// newArrivalSchedule returns an arrivalSchedule for rate queries per second
+// with the given distribution of the gaps between queries
+func newArrivalSchedule(rate float64, distribution string) (*arrivalSchedule, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("arrival rate must be positive, can't be %f", rate)
+	}
+	if distribution != ArrivalConstant && distribution != ArrivalPoisson {
+		return nil, fmt.Errorf("unknown arrival distribution %q: must be %s or %s", distribution, ArrivalConstant, ArrivalPoisson)
+	}
+	return &arrivalSchedule{
+		interval: 1 / rate,
+		poisson:  distribution == ArrivalPoisson,
+		rand:     rand.New(rand.NewSource(arrivalSeed)),
+		nowFn:    time.Now,
+	}, nil
+}
+
+// nextArrival returns the intended send time of the next query. The first
+// query is due right away.
+func (a *arrivalSchedule) nextArrival() time.Time {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.next.IsZero() {
+		a.next = a.nowFn()
+	}
+	intended := a.next
+	gap := a.interval
+	if a.poisson {
+		gap = a.rand.ExpFloat64() * a.interval
+	}
+	a.next = a.next.Add(time.Duration(gap * float64(time.Second)))
+	return intended
+}
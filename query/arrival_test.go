@@ -0,0 +1,65 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewArrivalSchedule(t *testing.T) {
+	cases := []struct {
+		desc         string
+		rate         float64
+		distribution string
+		wantErr      bool
+	}{
+		{desc: "constant", rate: 10, distribution: ArrivalConstant},
+		{desc: "poisson", rate: 10, distribution: ArrivalPoisson},
+		{desc: "zero rate", rate: 0, distribution: ArrivalConstant, wantErr: true},
+		{desc: "unknown distribution", rate: 10, distribution: "uniform", wantErr: true},
+	}
+	for _, c := range cases {
+		_, err := newArrivalSchedule(c.rate, c.distribution)
+		if gotErr := err != nil; gotErr != c.wantErr {
+			t.Errorf("%s: incorrect error: got %v want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestArrivalScheduleConstant(t *testing.T) {
+	a, err := newArrivalSchedule(4, ArrivalConstant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	a.nowFn = func() time.Time { return start }
+	for i := 0; i < 5; i++ {
+		want := start.Add(time.Duration(i) * 250 * time.Millisecond)
+		if got := a.nextArrival(); !got.Equal(want) {
+			t.Errorf("arrival %d: got %v want %v", i, got, want)
+		}
+	}
+}
+
+func TestArrivalSchedulePoisson(t *testing.T) {
+	a, err := newArrivalSchedule(100, ArrivalPoisson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	a.nowFn = func() time.Time { return start }
+	const n = 10000
+	var last time.Time
+	for i := 0; i < n; i++ {
+		got := a.nextArrival()
+		if got.Before(last) {
+			t.Fatalf("arrival %d before the previous one: %v < %v", i, got, last)
+		}
+		last = got
+	}
+	// n arrivals at 100/sec should take about n/100 seconds
+	mean := last.Sub(start).Seconds() / n
+	if math.Abs(mean-0.01) > 0.001 {
+		t.Errorf("incorrect mean gap: got %f want %f", mean, 0.01)
+	}
+}
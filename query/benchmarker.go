@@ -2,14 +2,19 @@ package query
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"runtime/pprof"
 	"sync"
 	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
 	"golang.org/x/time/rate"
 )
 
@@ -28,13 +33,35 @@ type BenchmarkRunnerConfig struct {
 	LimitRPS         uint64 `mapstructure:"max-rps"`
 	MemProfile       string `mapstructure:"memprofile"`
 	HDRLatenciesFile string `mapstructure:"hdr-latencies"`
+	HDRLatenciesDir  string `mapstructure:"hdr-latencies-dir"`
+	Percentiles      string `mapstructure:"percentiles"`
 	Workers          uint   `mapstructure:"workers"`
+	WorkersSweep     string `mapstructure:"workers-sweep"`
 	PrintResponses   bool   `mapstructure:"print-responses"`
 	Debug            int    `mapstructure:"debug"`
 	FileName         string `mapstructure:"file"`
 	BurnIn           uint64 `mapstructure:"burn-in"`
 	PrintInterval    uint64 `mapstructure:"print-interval"`
 	PrewarmQueries   bool   `mapstructure:"prewarm-queries"`
+	ResultsFile      string `mapstructure:"results-file"`
+
+	MaxDuration time.Duration `mapstructure:"max-duration"`
+	Loop        bool          `mapstructure:"loop"`
+
+	QueryTimeout time.Duration `mapstructure:"query-timeout"`
+	OnQueryError string        `mapstructure:"on-query-error"`
+
+	ArrivalRate         float64 `mapstructure:"arrival-rate"`
+	ArrivalDistribution string  `mapstructure:"arrival-distribution"`
+
+	SampleResources       bool          `mapstructure:"sample-resources"`
+	SampleResourcesPeriod time.Duration `mapstructure:"sample-resources-period"`
+
+	FlushCache        bool   `mapstructure:"flush-cache"`
+	FlushCacheCommand string `mapstructure:"flush-cache-command"`
+
+	CaptureResultsFile string `mapstructure:"capture-results"`
+	CapturePrecision   int    `mapstructure:"capture-precision"`
 }
 
 // AddToFlagSet adds command line flags needed by the BenchmarkRunnerConfig to the flag set.
@@ -46,11 +73,27 @@ func (c BenchmarkRunnerConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.Uint64("print-interval", 100, "Print timing stats to stderr after this many queries (0 to disable)")
 	fs.String("memprofile", "", "Write a memory profile to this file.")
 	fs.String("hdr-latencies", "", "Write the High Dynamic Range (HDR) Histogram of Response Latencies to this file.")
+	fs.String("hdr-latencies-dir", "", "Write one High Dynamic Range (HDR) Histogram of Response Latencies per query label to this directory.")
+	fs.String("percentiles", "", "Comma-separated list of latency percentiles to print for every query label, e.g. 50,90,95,99,99.9")
 	fs.Uint("workers", 1, "Number of concurrent requests to make.")
+	fs.String("workers-sweep", "", "Comma-separated list of worker counts, e.g. 1,2,4,8: run all queries once per count and report throughput and latencies per count (overrides --workers)")
 	fs.Bool("prewarm-queries", false, "Run each query twice in a row so the warm query is guaranteed to be a cache hit")
 	fs.Bool("print-responses", false, "Pretty print response bodies for correctness checking (default false).")
 	fs.Int("debug", 0, "Whether to print debug messages.")
-	fs.String("file", "", "File name to read queries from")
+	fs.String("file", "", "File name to read queries from. Accepts a comma-separated list of files and glob patterns read in sequence; gzip and zstd files are decompressed automatically")
+	fs.String("results-file", "", "Write the test results summary json to this file")
+	fs.Duration("max-duration", 0, "Stop sending queries after this much time, even if there are queries left (0 = no limit).")
+	fs.Bool("loop", false, "Start over from the beginning of the queries when all were sent, until --max-queries or --max-duration is reached.")
+	fs.Duration("query-timeout", 0, "Cancel queries that take longer than this (0 = no timeout). Only supported by some databases.")
+	fs.String("on-query-error", QueryErrorAbort, fmt.Sprintf("What to do when a query fails: '%s' the run, or '%s' and count the error per query type.", QueryErrorAbort, QueryErrorContinue))
+	fs.Float64("arrival-rate", 0, "Send queries open-loop at this many queries per second regardless of how fast they are answered, and measure response times from the intended send time (0 = closed loop).")
+	fs.String("arrival-distribution", ArrivalConstant, fmt.Sprintf("Distribution of the gaps between queries with --arrival-rate: '%s' or '%s'.", ArrivalConstant, ArrivalPoisson))
+	fs.Bool("sample-resources", false, "Sample CPU, memory, disk and network use of the query runner and its host and report them in the summary.")
+	fs.Duration("sample-resources-period", utils.DefaultResourceSamplingPeriod, "Period to sample resources with --sample-resources")
+	fs.Bool("flush-cache", false, "Flush the database's caches before every cold query (see --prewarm-queries). Only supported by some databases.")
+	fs.String("flush-cache-command", "", "Shell command to run before every cold query (see --prewarm-queries), e.g. to drop the OS page cache of the database host.")
+	fs.String("capture-results", "", "Write the normalized result of every query to this file, to compare the answers of different databases with tsbs_verify_results. Only supported by some databases.")
+	fs.Int("capture-precision", DefaultCapturePrecision, "Number of decimal places to round values to with --capture-results")
 }
 
 // BenchmarkRunner contains the common components for running a query benchmarking
@@ -61,6 +104,23 @@ type BenchmarkRunner struct {
 	sp      statProcessor
 	scanner *scanner
 	ch      chan Query
+
+	// stopCh is closed to make the scanner stop reading queries early
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// stopReason describes why the scanner was stopped, empty if it read all queries
+	stopReason string
+	// interruptedBy is the signal that stopped the run, nil if it ran to the end
+	interruptedBy os.Signal
+
+	resources *utils.ResourceSampler
+	// arrivals schedules the queries of an open-loop run, nil when running closed-loop
+	arrivals *arrivalSchedule
+	// results writes the captured query results, nil unless --capture-results is set
+	results *resultWriter
+	// stdinQueries holds the queries read from STDIN, so that every level of
+	// a workers sweep can read them again
+	stdinQueries []byte
 }
 
 // NewBenchmarkRunner creates a new instance of BenchmarkRunner which is
@@ -74,6 +134,8 @@ func NewBenchmarkRunner(config BenchmarkRunnerConfig) *BenchmarkRunner {
 		prewarmQueries: runner.PrewarmQueries,
 		burnIn:         runner.BurnIn,
 		hdrLatenciesFile: runner.HDRLatenciesFile,
+		openLoop:         runner.ArrivalRate > 0,
+		hdrLatenciesDir:  runner.HDRLatenciesDir,
 	}
 
 	runner.sp = newStatProcessor(spArgs)
@@ -90,6 +152,22 @@ func (b *BenchmarkRunner) DoPrintResponses() bool {
 	return b.PrintResponses
 }
 
+// DoCaptureResults indicates whether processors should capture the results
+// of queries with CaptureResult
+func (b *BenchmarkRunner) DoCaptureResults() bool {
+	return b.results != nil
+}
+
+// CaptureResult writes the result rows of a query to the --capture-results file
+func (b *BenchmarkRunner) CaptureResult(q Query, rows []ResultRow) {
+	if b.results == nil {
+		return
+	}
+	if err := b.results.write(q, rows); err != nil {
+		log.Fatalf("cannot write result of query %d: %v", q.GetID(), err)
+	}
+}
+
 // DebugLevel returns the level of debug messages for this benchmark
 func (b *BenchmarkRunner) DebugLevel() int {
 	return b.Debug
@@ -112,12 +190,31 @@ type Processor interface {
 	ProcessQuery(q Query, isWarm bool) ([]*Stat, error)
 }
 
+// ProcessorWithContext is a Processor that can cancel a query once its
+// context is done. Only these processors enforce --query-timeout.
+type ProcessorWithContext interface {
+	Processor
+
+	// ProcessQueryWithContext is ProcessQuery, giving up when ctx is done
+	ProcessQueryWithContext(ctx context.Context, q Query, isWarm bool) ([]*Stat, error)
+}
+
+// ProcessorWithCacheFlush is a Processor that can flush the caches of the
+// database, so that the next query is genuinely cold. Only these processors
+// support --flush-cache.
+type ProcessorWithCacheFlush interface {
+	Processor
+
+	// FlushCache drops what the database caches between queries
+	FlushCache() error
+}
+
 // GetBufferedReader returns the buffered Reader that should be used by the loader
 func (b *BenchmarkRunner) GetBufferedReader() *bufio.Reader {
 	if b.br == nil {
 		if len(b.FileName) > 0 {
-			// Read from specified file
-			file, err := os.Open(b.FileName)
+			// Read from specified files, decompressing them if needed
+			file, err := utils.OpenInputFiles(b.FileName)
 			if err != nil {
 				panic(fmt.Sprintf("cannot open file for read %s: %v", b.FileName, err))
 			}
@@ -134,6 +231,13 @@ func (b *BenchmarkRunner) GetBufferedReader() *bufio.Reader {
 // It launches a gorountine to track stats, creates workers to process queries,
 // read in the input, execute the queries, and then does cleanup.
 func (b *BenchmarkRunner) Run(queryPool *sync.Pool, processorCreateFn ProcessorCreate) {
+	sweep, err := parseWorkersSweep(b.WorkersSweep)
+	if err != nil {
+		panic(err.Error())
+	}
+	if len(sweep) > 0 {
+		b.Workers = sweep[0]
+	}
 	if b.Workers == 0 {
 		panic("must have at least one worker")
 	}
@@ -142,28 +246,119 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, processorCreateFn ProcessorC
 	if spArgs.burnIn > b.Limit {
 		panic("burn-in is larger than limit")
 	}
+	if b.Loop && b.Limit == 0 && b.MaxDuration <= 0 {
+		panic("loop needs max-queries or max-duration to end the run")
+	}
+	if len(b.OnQueryError) > 0 && b.OnQueryError != QueryErrorAbort && b.OnQueryError != QueryErrorContinue {
+		panic(fmt.Sprintf("unknown on-query-error %q: must be %s or %s", b.OnQueryError, QueryErrorAbort, QueryErrorContinue))
+	}
+	percentiles, err := parsePercentiles(b.Percentiles)
+	if err != nil {
+		panic(err.Error())
+	}
+	spArgs.percentiles = percentiles
+	if b.ArrivalRate > 0 {
+		if b.LimitRPS > 0 {
+			panic("max-rps can't be combined with arrival-rate")
+		}
+		if spArgs.prewarmQueries {
+			panic("prewarm-queries can't be combined with arrival-rate")
+		}
+		if b.flushesCache() {
+			panic("flush-cache can't be combined with arrival-rate")
+		}
+		b.arrivals, err = newArrivalSchedule(b.ArrivalRate, b.ArrivalDistribution)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+	if len(b.CaptureResultsFile) > 0 {
+		b.results, err = newResultWriter(b.CaptureResultsFile, b.CapturePrecision)
+		if err != nil {
+			panic(fmt.Sprintf("cannot create capture results file %s: %v", b.CaptureResultsFile, err))
+		}
+	}
+
+	if len(sweep) > 0 {
+		b.runSweep(sweep, queryPool, processorCreateFn)
+	} else {
+		wallStart, wallEnd := b.runOnce(queryPool, processorCreateFn)
+		if len(b.ResultsFile) > 0 {
+			b.saveTestResult(wallStart, wallEnd)
+		}
+	}
+	if b.results != nil {
+		b.closeResults()
+	}
+
+	// (Optional) create a memory profile:
+	if len(b.MemProfile) > 0 {
+		f, err := os.Create(b.MemProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pprof.WriteHeapProfile(f)
+		f.Close()
+	}
+}
+
+// runOnce sends all queries to b.Workers workers and prints the summary,
+// returning the wall clock start and end time of the run.
+func (b *BenchmarkRunner) runOnce(queryPool *sync.Pool, processorCreateFn ProcessorCreate) (time.Time, time.Time) {
 	b.ch = make(chan Query, b.Workers)
 
 	// Launch the stats processor:
-	go b.sp.process(b.Workers)
+	b.sp.start(b.Workers)
 
-	rateLimiter := getRateLimiter(b.LimitRPS,b.Workers)
+	rateLimiter := getRateLimiter(b.LimitRPS, b.Workers)
 
 	// Launch query processors
 	var wg sync.WaitGroup
 	for i := 0; i < int(b.Workers); i++ {
+		processor := processorCreateFn()
+		if _, ok := processor.(ProcessorWithContext); i == 0 && b.QueryTimeout > 0 && !ok {
+			fmt.Fprintf(os.Stderr, "WARNING: queries of this database can't be cancelled, --query-timeout has no effect\n")
+		}
+		if _, ok := processor.(ProcessorWithCacheFlush); i == 0 && b.FlushCache && !ok {
+			fmt.Fprintf(os.Stderr, "WARNING: the caches of this database can't be flushed, --flush-cache has no effect\n")
+		}
 		wg.Add(1)
-		go b.processorHandler(&wg, rateLimiter, queryPool, processorCreateFn(), i)
+		go b.processorHandler(&wg, rateLimiter, queryPool, processor, i)
+	}
+
+	if b.SampleResources {
+		b.startResourceSampler()
 	}
 
 	// Read in jobs, closing the job channel when done:
 	// Wall clock start time
 	wallStart := time.Now()
-	b.scanner.setReader(b.GetBufferedReader()).scan(queryPool, b.ch)
+	b.stopCh = make(chan struct{})
+	stopInterrupts := utils.HandleInterrupts(b.interrupt)
+	defer stopInterrupts()
+	if b.MaxDuration > 0 {
+		deadline := time.AfterFunc(b.MaxDuration, func() {
+			b.stop(fmt.Sprintf("reached --max-duration of %v", b.MaxDuration))
+		})
+		defer deadline.Stop()
+	}
+	b.scanner.setReader(b.queryInput()).scan(queryPool, b.ch, b.stopCh)
+	// The scanner is done, a later interrupt has no effect
+	b.stopOnce.Do(func() {})
 	close(b.ch)
 
 	// Block for workers to finish sending requests, closing the stats channel when done:
 	wg.Wait()
+	if b.resources != nil {
+		// Stop sampling and cover the time since the last sample
+		b.resources.Stop()
+		b.resources.Sample()
+	}
+	if b.interruptedBy != nil {
+		fmt.Printf("run interrupted (%v), statistics only cover the queries finished so far\n", b.interruptedBy)
+	} else if len(b.stopReason) > 0 {
+		fmt.Printf("run stopped: %s\n", b.stopReason)
+	}
 	b.sp.CloseAndWait()
 
 	// Wall clock end time
@@ -174,26 +369,111 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, processorCreateFn ProcessorC
 		log.Fatal(err)
 	}
 
-	// (Optional) create a memory profile:
-	if len(b.MemProfile) > 0 {
-		f, err := os.Create(b.MemProfile)
-		if err != nil {
-			log.Fatal(err)
-		}
-		pprof.WriteHeapProfile(f)
-		f.Close()
+	if b.resources != nil {
+		fmt.Print(b.resources.Summary())
+	}
+	return wallStart, wallEnd
+}
+
+// startResourceSampler starts sampling the resources used by the query runner
+// and its host
+func (b *BenchmarkRunner) startResourceSampler() {
+	sampler, err := utils.NewResourceSampler()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: cannot sample resources: %v\n", err)
+		return
+	}
+	period := b.SampleResourcesPeriod
+	if period <= 0 {
+		period = utils.DefaultResourceSamplingPeriod
+	}
+	sampler.Start(period)
+	b.resources = sampler
+}
+
+// closeResults flushes the captured query results to their file
+func (b *BenchmarkRunner) closeResults() {
+	if err := b.results.close(); err != nil {
+		log.Fatalf("cannot write captured results: %v", err)
+	}
+	if b.results.count == 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: no query results were captured, this database may not support --capture-results\n")
+		return
 	}
+	fmt.Printf("Captured the results of %d queries to %s\n", b.results.count, b.CaptureResultsFile)
+}
+
+// queryInput returns the reader the scanner starts with. With --loop the
+// scanner can also start over: files are opened again, queries read from
+// STDIN are kept in memory during the first pass.
+func (b *BenchmarkRunner) queryInput() io.Reader {
+	br := b.GetBufferedReader()
+	if !b.Loop {
+		return br
+	}
+	if len(b.FileName) > 0 {
+		b.scanner.setRewind(func() (io.Reader, error) {
+			file, err := utils.OpenInputFiles(b.FileName)
+			if err != nil {
+				return nil, err
+			}
+			return bufio.NewReaderSize(file, defaultReadSize), nil
+		})
+		return br
+	}
+	var buf bytes.Buffer
+	b.scanner.setRewind(func() (io.Reader, error) {
+		return bytes.NewReader(buf.Bytes()), nil
+	})
+	return io.TeeReader(br, &buf)
+}
+
+// stop makes the scanner stop reading queries, so the run ends once the
+// queries already handed to workers are done. Only the first reason is kept.
+func (b *BenchmarkRunner) stop(reason string) {
+	b.stopOnce.Do(func() {
+		b.stopReason = reason
+		close(b.stopCh)
+	})
+}
+
+// interrupt stops the run on SIGINT or SIGTERM. Queries already handed to
+// workers still run, and the summary covers all queries finished so far.
+func (b *BenchmarkRunner) interrupt(sig os.Signal) {
+	fmt.Fprintf(os.Stderr, "received %v, waiting for the queries in flight (send again to exit immediately)\n", sig)
+	b.stopOnce.Do(func() {
+		b.stopReason = fmt.Sprintf("interrupted (%v)", sig)
+		b.interruptedBy = sig
+		close(b.stopCh)
+	})
 }
 
 func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, rateLimiter *rate.Limiter, queryPool *sync.Pool, processor Processor, workerNum int) {
 	processor.Init(workerNum)
 	for query := range b.ch {
-		r := rateLimiter.Reserve()
-		time.Sleep(r.Delay())
+		var queueDelay time.Duration
+		if b.arrivals != nil {
+			// Open loop: wait for the intended send time. If all workers were
+			// busy it has already passed and the query is late.
+			intended := b.arrivals.nextArrival()
+			time.Sleep(time.Until(intended))
+			queueDelay = time.Since(intended)
+		} else {
+			r := rateLimiter.Reserve()
+			time.Sleep(r.Delay())
+		}
 
-		stats, err := processor.ProcessQuery(query, false)
+		if b.flushesCache() {
+			b.flushCache(processor)
+		}
+		stats, err := b.processQuery(processor, query, false)
 		if err != nil {
-			panic(err)
+			b.queryFailed(query, err)
+			queryPool.Put(query)
+			continue
+		}
+		if b.arrivals != nil {
+			setResponseTimes(stats, queueDelay)
 		}
 		b.sp.send(stats)
 
@@ -203,17 +483,77 @@ func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, rateLimiter *rate
 		spArgs := b.sp.getArgs()
 		if spArgs.prewarmQueries {
 			// Warm run
-			stats, err = processor.ProcessQuery(query, true)
+			stats, err = b.processQuery(processor, query, true)
 			if err != nil {
-				panic(err)
+				b.queryFailed(query, err)
+			} else {
+				b.sp.sendWarm(stats)
 			}
-			b.sp.sendWarm(stats)
 		}
 		queryPool.Put(query)
 	}
 	wg.Done()
 }
 
+// flushesCache tells whether caches are flushed before cold queries
+func (b *BenchmarkRunner) flushesCache() bool {
+	return b.FlushCache || len(b.FlushCacheCommand) > 0
+}
+
+// flushCache flushes the caches of the database with the processor (for
+// --flush-cache) and runs --flush-cache-command, aborting the run if either
+// fails. The time it takes is not part of the query latency.
+func (b *BenchmarkRunner) flushCache(processor Processor) {
+	if pf, ok := processor.(ProcessorWithCacheFlush); ok && b.FlushCache {
+		if err := pf.FlushCache(); err != nil {
+			panic(fmt.Sprintf("cannot flush cache: %v", err))
+		}
+	}
+	if len(b.FlushCacheCommand) > 0 {
+		out, err := exec.Command("sh", "-c", b.FlushCacheCommand).CombinedOutput()
+		if err != nil {
+			panic(fmt.Sprintf("flush-cache-command failed: %v: %s", err, out))
+		}
+	}
+}
+
+// processQuery runs a single query, cancelling it after --query-timeout if
+// the processor supports it
+func (b *BenchmarkRunner) processQuery(processor Processor, q Query, isWarm bool) ([]*Stat, error) {
+	pc, ok := processor.(ProcessorWithContext)
+	if !ok || b.QueryTimeout <= 0 {
+		return processor.ProcessQuery(q, isWarm)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.QueryTimeout)
+	defer cancel()
+	stats, err := pc.ProcessQueryWithContext(ctx, q, isWarm)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = &Error{Kind: ErrorKindTimeout, Err: err}
+	}
+	return stats, err
+}
+
+// queryFailed handles a failed query according to --on-query-error: it either
+// aborts the run or counts the error for the label of the query
+func (b *BenchmarkRunner) queryFailed(q Query, err error) {
+	if b.OnQueryError != QueryErrorContinue {
+		panic(err)
+	}
+	if b.Debug > 0 {
+		fmt.Fprintf(os.Stderr, "query %d (%s) failed: %v\n", q.GetID(), q.HumanLabelName(), err)
+	}
+	b.sp.send([]*Stat{getErrorStat(q.HumanLabelName(), errorKind(err))})
+}
+
+// setResponseTimes sets the response time of stats measured by a processor
+// from the intended send time, queueDelay before the query was actually sent
+func setResponseTimes(stats []*Stat, queueDelay time.Duration) {
+	delayMillis := float64(queueDelay.Nanoseconds()) / 1e6
+	for _, s := range stats {
+		s.responseTime = s.value + delayMillis
+	}
+}
+
 func getRateLimiter(limitRPS uint64, workers uint) *rate.Limiter {
 	var requestRate = rate.Inf
 	var requestBurst = 0
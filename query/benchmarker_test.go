@@ -1,14 +1,21 @@
 package query
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"golang.org/x/time/rate"
 	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type testProcessor struct {
@@ -231,7 +238,7 @@ func TestBenchmarkRunnerRunNoQueries(t *testing.T) {
 	lock := &sync.Mutex{}
 	sp := mockStatProcessor{
 		args: &statProcessorArgs{},
-		onProcess: func(_ uint) {
+		onStart: func(_ uint) {
 			lock.Lock()
 			spStarted = true
 			lock.Unlock()
@@ -298,7 +305,7 @@ func TestBenchmarkRunnerRunNoQueries(t *testing.T) {
 type mockStatProcessor struct {
 	args      *statProcessorArgs
 	onSend    func([]*Stat)
-	onProcess func(uint)
+	onStart func(uint)
 	closed    bool
 	wg        *sync.WaitGroup
 }
@@ -316,15 +323,18 @@ func (m *mockStatProcessor) sendWarm(stats []*Stat) {
 		m.onSend(stats)
 	}
 }
-func (m *mockStatProcessor) process(workers uint) {
-	if m.onProcess != nil {
-		m.onProcess(workers)
+func (m *mockStatProcessor) start(workers uint) {
+	if m.onStart != nil {
+		m.onStart(workers)
 	}
 }
 func (m *mockStatProcessor) CloseAndWait() {
 	m.closed = true
 	m.wg.Done()
 }
+func (m *mockStatProcessor) getSummary() *statSummary {
+	return &statSummary{statGroups: map[string]*statGroup{}}
+}
 
 type mockProcessor struct {
 	processRes []*Stat
@@ -359,4 +369,242 @@ func TestGetRateLimiter(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+type slowProcessor struct {
+	took time.Duration
+}
+
+func (p *slowProcessor) Init(_ int) {}
+
+func (p *slowProcessor) ProcessQuery(_ Query, _ bool) ([]*Stat, error) {
+	time.Sleep(p.took)
+	return []*Stat{GetStat().Init([]byte("slow"), float64(p.took.Nanoseconds())/1e6)}, nil
+}
+
+func TestProcessorHandlerOpenLoop(t *testing.T) {
+	// A single worker taking 10ms per query at 1000 queries/sec falls behind,
+	// so later queries queue up and have response times above their service times
+	qLimit := 5
+	took := 10 * time.Millisecond
+	var stats []*Stat
+	lock := &sync.Mutex{}
+	b := &BenchmarkRunner{
+		sp: &mockStatProcessor{
+			args: &statProcessorArgs{},
+			onSend: func(s []*Stat) {
+				lock.Lock()
+				stats = append(stats, s...)
+				lock.Unlock()
+			},
+		},
+	}
+	var err error
+	b.arrivals, err = newArrivalSchedule(1000, ArrivalConstant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.ch = make(chan Query, qLimit)
+	for i := 0; i < qLimit; i++ {
+		b.ch <- testQueryPool.Get().(*testQuery)
+	}
+	close(b.ch)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	b.processorHandler(&wg, rate.NewLimiter(rate.Inf, 0), &testQueryPool, &slowProcessor{took: took}, 0)
+
+	if len(stats) != qLimit {
+		t.Fatalf("incorrect number of stats: got %d want %d", len(stats), qLimit)
+	}
+	last := stats[qLimit-1]
+	// The last query was due after 4ms but could only start after 40ms
+	if minDelay := float64(30); last.responseTime-last.value < minDelay {
+		t.Errorf("response time does not include queueing: service %fms, response %fms", last.value, last.responseTime)
+	}
+}
+
+type contextProcessor struct {
+	testProcessor
+}
+
+func (p *contextProcessor) ProcessQueryWithContext(ctx context.Context, _ Query, _ bool) ([]*Stat, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestProcessQueryTimeout(t *testing.T) {
+	b := &BenchmarkRunner{}
+	b.QueryTimeout = 5 * time.Millisecond
+	_, err := b.processQuery(&contextProcessor{}, &testQuery{}, false)
+	if err == nil {
+		t.Fatalf("query did not time out")
+	}
+	if got := errorKind(err); got != ErrorKindTimeout {
+		t.Errorf("incorrect error kind: got %s want %s", got, ErrorKindTimeout)
+	}
+
+	// Without a timeout the context is not used
+	b.QueryTimeout = 0
+	if _, err := b.processQuery(&contextProcessor{}, &testQuery{}, false); err != nil {
+		t.Errorf("unexpected error without timeout: %v", err)
+	}
+}
+
+func TestQueryFailed(t *testing.T) {
+	var sent []*Stat
+	b := &BenchmarkRunner{
+		sp: &mockStatProcessor{
+			args:   &statProcessorArgs{},
+			onSend: func(s []*Stat) { sent = append(sent, s...) },
+		},
+	}
+	q := &testQuery{HumanLabel: []byte("label")}
+
+	b.OnQueryError = QueryErrorContinue
+	b.queryFailed(q, ServerError(errors.New("boom")))
+	if len(sent) != 1 {
+		t.Fatalf("incorrect number of stats sent: got %d want %d", len(sent), 1)
+	}
+	if got := sent[0].errorKind; got != ErrorKindServer {
+		t.Errorf("incorrect error kind: got %s want %s", got, ErrorKindServer)
+	}
+	if got := string(sent[0].label); got != "label" {
+		t.Errorf("incorrect label: got %s want %s", got, "label")
+	}
+
+	b.OnQueryError = QueryErrorAbort
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("failed query did not abort the run")
+		}
+	}()
+	b.queryFailed(q, errors.New("boom"))
+}
+
+func TestBenchmarkRunnerRunPanicOnEndlessLoop(t *testing.T) {
+	runner := &BenchmarkRunner{
+		BenchmarkRunnerConfig: BenchmarkRunnerConfig{
+			Workers: 1,
+			Loop:    true,
+		},
+		sp: &defaultStatProcessor{
+			args: &statProcessorArgs{},
+		},
+	}
+	defer func() {
+		if r := recover(); r != "loop needs max-queries or max-duration to end the run" {
+			t.Errorf("wrong panic: %v", r)
+		}
+	}()
+	runner.Run(nil, nil)
+	t.Errorf("the code did not panic")
+}
+
+func TestQueryInputLoopStdin(t *testing.T) {
+	var input bytes.Buffer
+	err := encodeQueries(&input, 2, func(i uint64) Query {
+		return &testQuery{HumanLabel: []byte("label")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := uint64(5)
+	b := &BenchmarkRunner{scanner: newScanner(&limit)}
+	b.Loop = true
+	// The cached reader stands in for STDIN
+	b.br = bufio.NewReader(bytes.NewReader(input.Bytes()))
+	queryChan := make(chan Query, limit)
+	b.scanner.setReader(b.queryInput()).scan(&testQueryPool, queryChan, nil)
+	close(queryChan)
+	if got := len(queryChan); got != int(limit) {
+		t.Errorf("incorrect number of queries: got %d want %d", got, limit)
+	}
+}
+
+func TestStop(t *testing.T) {
+	b := &BenchmarkRunner{stopCh: make(chan struct{})}
+	b.stop("first")
+	b.interrupt(os.Interrupt)
+	select {
+	case <-b.stopCh:
+	default:
+		t.Errorf("stop channel not closed")
+	}
+	if b.stopReason != "first" {
+		t.Errorf("incorrect stop reason: got %s want %s", b.stopReason, "first")
+	}
+	if b.interruptedBy != nil {
+		t.Errorf("late interrupt marked the run as interrupted")
+	}
+}
+
+type flushProcessor struct {
+	testProcessor
+	flushes int
+	// queriesAtFlush records how many queries ran before each flush
+	queriesAtFlush []int
+}
+
+func (p *flushProcessor) FlushCache() error {
+	p.flushes++
+	p.queriesAtFlush = append(p.queriesAtFlush, p.count)
+	return nil
+}
+
+func TestProcessorHandlerFlushCache(t *testing.T) {
+	const qLimit = 5
+	dir, err := ioutil.TempDir("", "flush")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "flushed")
+
+	p := &flushProcessor{}
+	b := &BenchmarkRunner{}
+	b.FlushCache = true
+	b.FlushCacheCommand = "echo >> " + marker
+	b.sp = newStatProcessor(&statProcessorArgs{limit: &b.Limit, prewarmQueries: true})
+	b.sp.(*defaultStatProcessor).c = make(chan *Stat, 4*qLimit)
+	b.ch = make(chan Query, qLimit)
+	for i := 0; i < qLimit; i++ {
+		b.ch <- testQueryPool.Get().(*testQuery)
+	}
+	close(b.ch)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	b.processorHandler(&wg, rate.NewLimiter(rate.Inf, 0), &testQueryPool, p, 0)
+
+	if p.count != 2*qLimit {
+		t.Errorf("incorrect number of queries: got %d want %d", p.count, 2*qLimit)
+	}
+	// only before the cold runs, i.e. after every pair of cold and warm run
+	want := []int{0, 2, 4, 6, 8}
+	if !reflect.DeepEqual(p.queriesAtFlush, want) {
+		t.Errorf("incorrect flushes: got queries %v before flushes, want %v", p.queriesAtFlush, want)
+	}
+	data, err := ioutil.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("flush command did not run: %v", err)
+	}
+	if got := strings.Count(string(data), "\n"); got != qLimit {
+		t.Errorf("incorrect number of flush command runs: got %d want %d", got, qLimit)
+	}
+}
+
+func TestFlushCacheCommandFails(t *testing.T) {
+	b := &BenchmarkRunner{}
+	b.FlushCacheCommand = "echo oops; exit 3"
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("failed flush command did not abort the run")
+		}
+		if msg := fmt.Sprint(r); !strings.Contains(msg, "oops") {
+			t.Errorf("flush command output missing from panic: %s", msg)
+		}
+	}()
+	b.flushCache(&testProcessor{})
+}
@@ -0,0 +1,256 @@
+package query
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapturePrecision is the number of decimal places captured values
+// are rounded to, so that databases aggregating in a different order still
+// give the same result
+const DefaultCapturePrecision = 6
+
+// ResultRow is one row of a query result, normalized so that results of
+// different databases can be compared: the time column, the values of all
+// other non-numeric columns (e.g. tags) and the numeric values in column order.
+type ResultRow struct {
+	Time   string    `json:"time,omitempty"`
+	Tags   []string  `json:"tags,omitempty"`
+	Values []float64 `json:"values,omitempty"`
+}
+
+// CapturedResult is the normalized result of one query, as written to the
+// file given with --capture-results
+type CapturedResult struct {
+	ID    uint64      `json:"id"`
+	Label string      `json:"label"`
+	Rows  []ResultRow `json:"rows"`
+}
+
+// NewResultRow builds a ResultRow from the column values of a result row.
+// Times (also as RFC3339 strings) become the row time, numbers (also as
+// strings) values, and anything else a tag. NULLs are skipped.
+func NewResultRow(columns ...interface{}) ResultRow {
+	var r ResultRow
+	for _, c := range columns {
+		r.add(c)
+	}
+	return r
+}
+
+func (r *ResultRow) add(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case time.Time:
+		r.addTime(v)
+	case *time.Time:
+		if v != nil {
+			r.addTime(*v)
+		}
+	case json.Number:
+		r.addString(string(v))
+	case []byte:
+		r.addString(string(v))
+	case string:
+		r.addString(v)
+	case bool:
+		r.Tags = append(r.Tags, strconv.FormatBool(v))
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			r.Values = append(r.Values, float64(rv.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			r.Values = append(r.Values, float64(rv.Uint()))
+		case reflect.Float32, reflect.Float64:
+			r.Values = append(r.Values, rv.Float())
+		case reflect.Ptr:
+			if !rv.IsNil() {
+				r.add(rv.Elem().Interface())
+			}
+		default:
+			r.Tags = append(r.Tags, fmt.Sprint(v))
+		}
+	}
+}
+
+func (r *ResultRow) addTime(t time.Time) {
+	if len(r.Time) > 0 {
+		// Only the first time column is the row time
+		r.Tags = append(r.Tags, t.UTC().Format(time.RFC3339Nano))
+		return
+	}
+	r.Time = t.UTC().Format(time.RFC3339Nano)
+}
+
+func (r *ResultRow) addString(s string) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		r.addTime(t)
+	} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+		r.Values = append(r.Values, f)
+	} else {
+		r.Tags = append(r.Tags, s)
+	}
+}
+
+// ScanResultRows reads all remaining rows of a SQL result as ResultRows
+func ScanResultRows(rows *sql.Rows) ([]ResultRow, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []ResultRow
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		columns := make([]interface{}, len(cols))
+		for i := range values {
+			columns[i] = *values[i].(*interface{})
+		}
+		result = append(result, NewResultRow(columns...))
+	}
+	return result, rows.Err()
+}
+
+// normalizeRows rounds the values of rows to precision decimal places and
+// sorts the tags within each row and the rows themselves, so that results
+// only differing in order compare equal.
+func normalizeRows(rows []ResultRow, precision int) []ResultRow {
+	scale := math.Pow(10, float64(precision))
+	normalized := make([]ResultRow, len(rows))
+	for i, r := range rows {
+		n := ResultRow{Time: r.Time}
+		if len(r.Tags) > 0 {
+			n.Tags = append([]string(nil), r.Tags...)
+			sort.Strings(n.Tags)
+		}
+		for _, v := range r.Values {
+			v = math.Round(v*scale) / scale
+			if v == 0 {
+				v = 0 // no negative zero
+			}
+			n.Values = append(n.Values, v)
+		}
+		normalized[i] = n
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].key() < normalized[j].key()
+	})
+	return normalized
+}
+
+// key is the string a row is sorted by and compared with
+func (r ResultRow) key() string {
+	values := make([]string, len(r.Values))
+	for i, v := range r.Values {
+		values[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return r.Time + "|" + strings.Join(r.Tags, ",") + "|" + strings.Join(values, ",")
+}
+
+// String describes the row in difference reports
+func (r ResultRow) String() string {
+	var parts []string
+	if len(r.Time) > 0 {
+		parts = append(parts, r.Time)
+	}
+	if len(r.Tags) > 0 {
+		parts = append(parts, fmt.Sprintf("tags %v", r.Tags))
+	}
+	parts = append(parts, fmt.Sprintf("values %v", r.Values))
+	return strings.Join(parts, " ")
+}
+
+// resultWriter writes the captured results of all workers to one file as
+// JSON lines
+type resultWriter struct {
+	lock      sync.Mutex
+	file      *os.File
+	w         *bufio.Writer
+	enc       *json.Encoder
+	precision int
+	count     uint64
+}
+
+func newResultWriter(fileName string, precision int) (*resultWriter, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	return &resultWriter{file: file, w: w, enc: json.NewEncoder(w), precision: precision}, nil
+}
+
+// write normalizes the result rows of q and writes them out
+func (rw *resultWriter) write(q Query, rows []ResultRow) error {
+	result := CapturedResult{
+		ID:    q.GetID(),
+		Label: string(q.HumanLabelName()),
+		Rows:  normalizeRows(rows, rw.precision),
+	}
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.count++
+	return rw.enc.Encode(result)
+}
+
+func (rw *resultWriter) close() error {
+	if err := rw.w.Flush(); err != nil {
+		return err
+	}
+	return rw.file.Close()
+}
+
+// ReadCapturedResults reads a file written with --capture-results. A query
+// captured more than once, e.g. the warm runs with --prewarm-queries, keeps
+// its first result.
+func ReadCapturedResults(r io.Reader) (map[uint64]*CapturedResult, error) {
+	results := make(map[uint64]*CapturedResult)
+	dec := json.NewDecoder(r)
+	for {
+		var result CapturedResult
+		err := dec.Decode(&result)
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := results[result.ID]; !ok {
+			results[result.ID] = &result
+		}
+	}
+}
+
+// DiffResults compares the captured results of the same query, with values
+// rounded to precision decimal places, and describes the first difference.
+// It returns an empty string if the results are the same.
+func DiffResults(a, b *CapturedResult, precision int) string {
+	rowsA := normalizeRows(a.Rows, precision)
+	rowsB := normalizeRows(b.Rows, precision)
+	if len(rowsA) != len(rowsB) {
+		return fmt.Sprintf("%d rows vs %d rows", len(rowsA), len(rowsB))
+	}
+	for i := range rowsA {
+		if ka, kb := rowsA[i].key(), rowsB[i].key(); ka != kb {
+			return fmt.Sprintf("row %d: %s vs %s", i, rowsA[i], rowsB[i])
+		}
+	}
+	return ""
+}
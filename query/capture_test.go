@@ -0,0 +1,136 @@
+package query
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewResultRow(t *testing.T) {
+	ts := time.Date(2016, 1, 1, 12, 0, 0, 0, time.FixedZone("x", 3600))
+	f := 2.5
+	cases := []struct {
+		desc    string
+		columns []interface{}
+		want    ResultRow
+	}{
+		{
+			desc:    "sql types",
+			columns: []interface{}{ts, "host_1", int64(3), 1.5, float32(0.5), nil, &f},
+			want:    ResultRow{Time: "2016-01-01T11:00:00Z", Tags: []string{"host_1"}, Values: []float64{3, 1.5, 0.5, 2.5}},
+		},
+		{
+			desc:    "strings and bytes",
+			columns: []interface{}{"2016-01-01T11:00:00Z", []byte("4.25"), json.Number("7"), "host_2", true},
+			want:    ResultRow{Time: "2016-01-01T11:00:00Z", Tags: []string{"host_2", "true"}, Values: []float64{4.25, 7}},
+		},
+		{
+			desc:    "second time column is a tag",
+			columns: []interface{}{ts, ts},
+			want:    ResultRow{Time: "2016-01-01T11:00:00Z", Tags: []string{"2016-01-01T11:00:00Z"}},
+		},
+	}
+	for _, c := range cases {
+		if got := NewResultRow(c.columns...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect row: got %+v want %+v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestNormalizeRows(t *testing.T) {
+	rows := []ResultRow{
+		{Time: "2016-01-01T11:01:00Z", Tags: []string{"b", "a"}, Values: []float64{1.23456789}},
+		{Time: "2016-01-01T11:00:00Z", Values: []float64{-0.0000001}},
+	}
+	got := normalizeRows(rows, 3)
+	want := []ResultRow{
+		{Time: "2016-01-01T11:00:00Z", Values: []float64{0}},
+		{Time: "2016-01-01T11:01:00Z", Tags: []string{"a", "b"}, Values: []float64{1.235}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect rows: got %+v want %+v", got, want)
+	}
+	// the input is left alone
+	if rows[0].Tags[0] != "b" {
+		t.Errorf("input rows were modified")
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	a := &CapturedResult{Rows: []ResultRow{
+		{Time: "2016-01-01T11:00:00Z", Tags: []string{"host_1"}, Values: []float64{1.0001}},
+		{Time: "2016-01-01T11:01:00Z", Tags: []string{"host_1"}, Values: []float64{2}},
+	}}
+	// same rows in a different order
+	b := &CapturedResult{Rows: []ResultRow{a.Rows[1], a.Rows[0]}}
+	if diff := DiffResults(a, b, 6); diff != "" {
+		t.Errorf("unexpected difference for reordered rows: %s", diff)
+	}
+
+	b.Rows[1] = ResultRow{Time: "2016-01-01T11:00:00Z", Tags: []string{"host_1"}, Values: []float64{1.0002}}
+	if diff := DiffResults(a, b, 6); !strings.HasPrefix(diff, "row 0:") {
+		t.Errorf("incorrect difference for different values: %s", diff)
+	}
+	if diff := DiffResults(a, b, 3); diff != "" {
+		t.Errorf("unexpected difference with lower precision: %s", diff)
+	}
+
+	b.Rows = b.Rows[:1]
+	if diff := DiffResults(a, b, 3); diff != "2 rows vs 1 rows" {
+		t.Errorf("incorrect difference for missing row: %s", diff)
+	}
+}
+
+func TestResultWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "results.json")
+
+	rw, err := newResultWriter(fileName, 2)
+	if err != nil {
+		t.Fatalf("cannot create result writer: %v", err)
+	}
+	q := &testQuery{ID: 1, HumanLabel: []byte("foo")}
+	if err := rw.write(q, []ResultRow{{Values: []float64{1.234}}}); err != nil {
+		t.Fatalf("cannot write result: %v", err)
+	}
+	// a warm run of the same query only keeps the first result
+	if err := rw.write(q, []ResultRow{{Values: []float64{5}}}); err != nil {
+		t.Fatalf("cannot write result: %v", err)
+	}
+	q = &testQuery{ID: 2, HumanLabel: []byte("bar")}
+	if err := rw.write(q, nil); err != nil {
+		t.Fatalf("cannot write result: %v", err)
+	}
+	if err := rw.close(); err != nil {
+		t.Fatalf("cannot close result writer: %v", err)
+	}
+	if rw.count != 3 {
+		t.Errorf("incorrect count: got %d want %d", rw.count, 3)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	results, err := ReadCapturedResults(f)
+	if err != nil {
+		t.Fatalf("cannot read results: %v", err)
+	}
+	want := map[uint64]*CapturedResult{
+		1: {ID: 1, Label: "foo", Rows: []ResultRow{{Values: []float64{1.23}}}},
+		2: {ID: 2, Label: "bar", Rows: []ResultRow{}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("incorrect results: got %+v want %+v", results, want)
+	}
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+const (
+	// QueryErrorAbort makes the first failed query abort the run
+	QueryErrorAbort = "abort"
+	// QueryErrorContinue counts failed queries per label and carries on
+	QueryErrorContinue = "continue"
+
+	// ErrorKindTimeout is a query that did not finish within --query-timeout
+	ErrorKindTimeout = "timeout"
+	// ErrorKindServer is a query the database answered with an error
+	ErrorKindServer = "server"
+	// ErrorKindClient is any other failed query, e.g. one that could not be
+	// sent or was rejected as invalid
+	ErrorKindClient = "client"
+)
+
+// Error is the error of a single query, classified by its kind so that
+// failed queries can be reported per kind.
+type Error struct {
+	Kind string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind + " error: " + e.Err.Error()
+}
+
+// ServerError marks err as an error reported by the database
+func ServerError(err error) error {
+	return &Error{Kind: ErrorKindServer, Err: err}
+}
+
+// ClientError marks err as an error on the side of the query runner
+func ClientError(err error) error {
+	return &Error{Kind: ErrorKindClient, Err: err}
+}
+
+// HTTPError classifies err, caused by a response with the given HTTP status
+// code, as a server error for 5xx codes and as a client error otherwise
+func HTTPError(statusCode int, err error) error {
+	if statusCode >= http.StatusInternalServerError {
+		return ServerError(err)
+	}
+	return ClientError(err)
+}
+
+// errorKind returns the kind of a query error. Errors that were not
+// classified by the processor are timeouts if they say so, client errors
+// otherwise.
+func errorKind(err error) string {
+	if qe, ok := err.(*Error); ok {
+		return qe.Kind
+	}
+	if err == context.DeadlineExceeded {
+		return ErrorKindTimeout
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return ErrorKindTimeout
+	}
+	return ErrorKindClient
+}
@@ -0,0 +1,46 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestErrorKind(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+		want string
+	}{
+		{desc: "server error", err: ServerError(errors.New("syntax")), want: ErrorKindServer},
+		{desc: "client error", err: ClientError(errors.New("refused")), want: ErrorKindClient},
+		{desc: "deadline exceeded", err: context.DeadlineExceeded, want: ErrorKindTimeout},
+		{desc: "net timeout", err: timeoutError{}, want: ErrorKindTimeout},
+		{desc: "unclassified", err: errors.New("oops"), want: ErrorKindClient},
+		{desc: "http 503", err: HTTPError(http.StatusServiceUnavailable, errors.New("busy")), want: ErrorKindServer},
+		{desc: "http 400", err: HTTPError(http.StatusBadRequest, errors.New("bad")), want: ErrorKindClient},
+	}
+	for _, c := range cases {
+		if got := errorKind(c.err); got != c.want {
+			t.Errorf("%s: incorrect kind: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := ServerError(errors.New("relation does not exist"))
+	want := "server error: relation does not exist"
+	if got := err.Error(); got != want {
+		t.Errorf("incorrect message: got %s want %s", got, want)
+	}
+}
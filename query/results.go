@@ -0,0 +1,196 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+const queryResultFormatVersion = "0.1"
+
+// QueryTestResult is the machine-readable summary of a query run that is
+// written to the file given with --results-file.
+type QueryTestResult struct {
+	// ResultFormatVersion allows consumers to detect changes to the layout
+	ResultFormatVersion string `json:"ResultFormatVersion"`
+	// RunnerConfig holds the configuration the run was started with
+	RunnerConfig BenchmarkRunnerConfig `json:"RunnerConfig"`
+
+	// StartTime and EndTime are Unix timestamps in milliseconds of the wall clock
+	StartTime      int64   `json:"StartTime"`
+	EndTime        int64   `json:"EndTime"`
+	DurationMillis int64   `json:"DurationMillis"`
+	WallClockTime  float64 `json:"WallClockTime"`
+	// Interrupted is set if the run was stopped by SIGINT or SIGTERM, so the
+	// results only cover part of the queries
+	Interrupted bool `json:"Interrupted"`
+	// StopReason is set if the run ended before all queries were sent
+	StopReason string `json:"StopReason,omitempty"`
+
+	Totals QueryTotals `json:"Totals"`
+	// Labels maps every query label (including the aggregate groups) to its latency summary
+	Labels map[string]LatencySummary `json:"Labels"`
+	// ResponseTimeLabels holds the same for the response times measured from
+	// the intended send time, only in open-loop runs (see --arrival-rate)
+	ResponseTimeLabels map[string]LatencySummary `json:"ResponseTimeLabels,omitempty"`
+
+	// Resources summarizes the resources used by the query runner and its
+	// host, only set when running with --sample-resources
+	Resources *utils.ResourceSummary `json:"Resources,omitempty"`
+
+	// WorkersSweep holds every level of a --workers-sweep run. The other
+	// fields then describe the last level, apart from the start and end
+	// time, which cover the whole sweep.
+	WorkersSweep []SweepLevel `json:"WorkersSweep,omitempty"`
+}
+
+// SweepLevel summarizes the run with one worker count of a --workers-sweep.
+type SweepLevel struct {
+	Workers       uint    `json:"Workers"`
+	WallClockTime float64 `json:"WallClockTime"`
+	// StopReason is set if the level ended before all queries were sent
+	StopReason string                    `json:"StopReason,omitempty"`
+	Totals     QueryTotals               `json:"Totals"`
+	Labels     map[string]LatencySummary `json:"Labels"`
+}
+
+// QueryTotals holds the overall counts and rates of a query run.
+type QueryTotals struct {
+	QueryCount       uint64  `json:"QueryCount"`
+	OverallQueryRate float64 `json:"OverallQueryRate"`
+	Workers          uint    `json:"Workers"`
+	BurnIn           uint64  `json:"BurnIn"`
+	PrewarmQueries   bool    `json:"PrewarmQueries"`
+	// FailedQueries is the number of queries that failed with --on-query-error=continue
+	FailedQueries uint64 `json:"FailedQueries"`
+	// ArrivalRate and ArrivalDistribution are only set in open-loop runs
+	ArrivalRate         float64 `json:"ArrivalRate,omitempty"`
+	ArrivalDistribution string  `json:"ArrivalDistribution,omitempty"`
+}
+
+// LatencySummary describes the latency distribution of a single label.
+// All values are in milliseconds.
+type LatencySummary struct {
+	Count  int64   `json:"Count"`
+	Min    float64 `json:"Min"`
+	Mean   float64 `json:"Mean"`
+	Median float64 `json:"Median"`
+	Max    float64 `json:"Max"`
+	StdDev float64 `json:"StdDev"`
+	Sum    float64 `json:"Sum"`
+	P50    float64 `json:"P50"`
+	P90    float64 `json:"P90"`
+	P95    float64 `json:"P95"`
+	P99    float64 `json:"P99"`
+	P999   float64 `json:"P99.9"`
+	// Percentiles holds the percentiles given with --percentiles, keyed like
+	// the fixed ones above, e.g. "P99.9"
+	Percentiles map[string]float64 `json:"Percentiles,omitempty"`
+	// Errors counts the failed queries by error kind (timeout, server, client)
+	Errors map[string]int64 `json:"Errors,omitempty"`
+}
+
+// newLatencySummary builds a LatencySummary from a statGroup, including the
+// given percentiles
+func newLatencySummary(s *statGroup, percentiles []float64) LatencySummary {
+	var ps map[string]float64
+	if len(percentiles) > 0 {
+		ps = make(map[string]float64, len(percentiles))
+		for _, p := range percentiles {
+			ps["P"+formatPercentile(p)] = s.Percentile(p)
+		}
+	}
+	return LatencySummary{
+		Count:  s.count,
+		Min:    s.Min(),
+		Mean:   s.Mean(),
+		Median: s.Median(),
+		Max:    s.Max(),
+		StdDev: s.StdDev(),
+		Sum:    s.sum,
+		P50:    s.Percentile(50.0),
+		P90:    s.Percentile(90.0),
+		P95:    s.Percentile(95.0),
+		P99:    s.Percentile(99.0),
+		P999:   s.Percentile(99.9),
+		Errors: s.errors,
+
+		Percentiles: ps,
+	}
+}
+
+// newQueryTestResult builds the QueryTestResult for a run whose wall clock
+// started at start and finished at end.
+func (b *BenchmarkRunner) newQueryTestResult(start, end time.Time) *QueryTestResult {
+	took := end.Sub(start)
+	summary := b.sp.getSummary()
+	spArgs := b.sp.getArgs()
+
+	labels := make(map[string]LatencySummary, len(summary.statGroups))
+	for label, sg := range summary.statGroups {
+		labels[label] = newLatencySummary(sg, spArgs.percentiles)
+	}
+
+	var responseLabels map[string]LatencySummary
+	if summary.responseGroups != nil {
+		responseLabels = make(map[string]LatencySummary, len(summary.responseGroups))
+		for label, sg := range summary.responseGroups {
+			responseLabels[label] = newLatencySummary(sg, spArgs.percentiles)
+		}
+	}
+
+	var resources *utils.ResourceSummary
+	if b.resources != nil {
+		rs := b.resources.Summary()
+		resources = &rs
+	}
+
+	totals := QueryTotals{
+		QueryCount:       summary.queryCount,
+		OverallQueryRate: summary.overallQueryRate,
+		Workers:          b.Workers,
+		BurnIn:           spArgs.burnIn,
+		PrewarmQueries:   spArgs.prewarmQueries,
+		FailedQueries:    summary.failedCount,
+	}
+	if b.ArrivalRate > 0 {
+		totals.ArrivalRate = b.ArrivalRate
+		totals.ArrivalDistribution = b.ArrivalDistribution
+	}
+
+	return &QueryTestResult{
+		ResultFormatVersion: queryResultFormatVersion,
+		RunnerConfig:        b.BenchmarkRunnerConfig,
+		StartTime:           start.UnixNano() / int64(time.Millisecond),
+		EndTime:             end.UnixNano() / int64(time.Millisecond),
+		DurationMillis:      took.Nanoseconds() / int64(time.Millisecond),
+		WallClockTime:       took.Seconds(),
+		Interrupted:         b.interruptedBy != nil,
+		StopReason:          b.stopReason,
+		Totals:              totals,
+		Labels:              labels,
+		ResponseTimeLabels:  responseLabels,
+		Resources:           resources,
+	}
+}
+
+// saveTestResult writes the results of the run as JSON to the --results-file
+func (b *BenchmarkRunner) saveTestResult(start, end time.Time) {
+	b.writeTestResult(b.newQueryTestResult(start, end))
+}
+
+// writeTestResult writes res as JSON to the --results-file
+func (b *BenchmarkRunner) writeTestResult(res *QueryTestResult) {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, _ = fmt.Printf("Saving results json file to %s\n", b.ResultsFile)
+	if err := ioutil.WriteFile(b.ResultsFile, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
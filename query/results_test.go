@@ -0,0 +1,109 @@
+package query
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+func TestNewQueryTestResult(t *testing.T) {
+	b := NewBenchmarkRunner(BenchmarkRunnerConfig{Workers: 2, PrintInterval: 0})
+	b.sp.getArgs().printInterval = 0
+	b.sp.start(b.Workers)
+	// wait for process to create its channel
+	time.Sleep(25 * time.Millisecond)
+	for _, v := range []float64{1.0, 2.0, 3.0, 4.0} {
+		b.sp.send([]*Stat{GetStat().Init([]byte("foo"), v)})
+	}
+	b.sp.CloseAndWait()
+
+	start := time.Unix(100, 0)
+	res := b.newQueryTestResult(start, start.Add(2*time.Second))
+	if got := res.Totals.QueryCount; got != 4 {
+		t.Errorf("incorrect query count: got %d want %d", got, 4)
+	}
+	if got := res.Totals.Workers; got != 2 {
+		t.Errorf("incorrect workers: got %d want %d", got, 2)
+	}
+	if got := res.WallClockTime; got != 2.0 {
+		t.Errorf("incorrect wall clock time: got %f want %f", got, 2.0)
+	}
+	for _, label := range []string{"foo", labelAllQueries} {
+		summary, ok := res.Labels[label]
+		if !ok {
+			t.Fatalf("missing label %s", label)
+		}
+		if summary.Count != 4 {
+			t.Errorf("%s: incorrect count: got %d want %d", label, summary.Count, 4)
+		}
+		if summary.Min != 1.0 || summary.Max != 4.0 {
+			t.Errorf("%s: incorrect min/max: got %f/%f want %f/%f", label, summary.Min, summary.Max, 1.0, 4.0)
+		}
+		if summary.P99 != 4.0 {
+			t.Errorf("%s: incorrect p99: got %f want %f", label, summary.P99, 4.0)
+		}
+	}
+	if res.Labels["foo"].Percentiles != nil {
+		t.Errorf("percentiles set without --percentiles")
+	}
+	if res.Resources != nil {
+		t.Errorf("resources set without --sample-resources")
+	}
+
+	b.sp.getArgs().percentiles = []float64{25, 99.9}
+	res = b.newQueryTestResult(start, start.Add(2*time.Second))
+	want := map[string]float64{"P25": 1.0, "P99.9": 4.0}
+	for k, v := range want {
+		if got := res.Labels["foo"].Percentiles[k]; got != v {
+			t.Errorf("incorrect percentile %s: got %f want %f", k, got, v)
+		}
+	}
+
+	sampler, err := utils.NewResourceSampler()
+	if err != nil {
+		t.Fatalf("could not create sampler: %v", err)
+	}
+	sampler.Sample()
+	b.resources = sampler
+	res = b.newQueryTestResult(start, start.Add(2*time.Second))
+	if res.Resources == nil || res.Resources.Samples != 1 {
+		t.Errorf("incorrect resources: got %+v want %d samples", res.Resources, 1)
+	}
+}
+
+func TestSaveTestResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-query-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := NewBenchmarkRunner(BenchmarkRunnerConfig{Workers: 1})
+	b.ResultsFile = filepath.Join(dir, "results.json")
+	b.sp.start(b.Workers)
+	time.Sleep(25 * time.Millisecond)
+	b.sp.send([]*Stat{GetStat().Init([]byte("foo"), 1.0)})
+	b.sp.CloseAndWait()
+
+	start := time.Now()
+	b.saveTestResult(start, start.Add(time.Second))
+	data, err := ioutil.ReadFile(b.ResultsFile)
+	if err != nil {
+		t.Fatalf("results file not written: %v", err)
+	}
+	var got QueryTestResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("results file is not valid json: %v", err)
+	}
+	if _, ok := got.Labels["foo"]; !ok {
+		t.Errorf("results file missing label foo")
+	}
+	if got.Totals.QueryCount != 1 {
+		t.Errorf("incorrect query count: got %d want %d", got.Totals.QueryCount, 1)
+	}
+}
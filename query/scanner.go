@@ -12,6 +12,8 @@ import (
 type scanner struct {
 	r     io.Reader
 	limit *uint64
+	// rewind returns the input from the start again, nil unless looping over it
+	rewind func() (io.Reader, error)
 }
 
 // newScanner returns a new scanner for a given Reader and its limit
@@ -25,22 +27,49 @@ func (s *scanner) setReader(r io.Reader) *scanner {
 	return s
 }
 
-// scan reads encoded Queries and places them into a channel
-func (s *scanner) scan(pool *sync.Pool, c chan Query) {
+// setRewind makes the scanner start over with the reader returned by rewind
+// whenever it reaches the end of the input
+func (s *scanner) setRewind(rewind func() (io.Reader, error)) *scanner {
+	s.rewind = rewind
+	return s
+}
+
+// scan reads encoded Queries and places them into a channel until the input
+// is exhausted, the limit is reached or stop is closed
+func (s *scanner) scan(pool *sync.Pool, c chan Query, stop <-chan struct{}) {
 	decoder := gob.NewDecoder(s.r)
 
 	n := uint64(0)
+	// passStart is the number of queries read before the current pass over the input
+	passStart := uint64(0)
 	for {
 		if *s.limit > 0 && n >= *s.limit {
 			// request queries limit reached, time to quit
 			break
 		}
 
+		select {
+		case <-stop:
+			// run was stopped early, time to quit
+			return
+		default:
+		}
+
 		q := pool.Get().(Query)
 		err := decoder.Decode(q)
 		if err == io.EOF {
-			// EOF, all done
-			break
+			pool.Put(q)
+			// EOF, all done unless looping over a non-empty input
+			if s.rewind == nil || n == passStart {
+				break
+			}
+			r, err := s.rewind()
+			if err != nil {
+				log.Fatalf("cannot rewind query input: %v", err)
+			}
+			decoder = gob.NewDecoder(r)
+			passStart = n
+			continue
 		}
 		if err != nil {
 			// Can't read, time to quit
@@ -49,7 +78,12 @@ func (s *scanner) scan(pool *sync.Pool, c chan Query) {
 
 		// We have a query, send it to the runner
 		q.SetID(n)
-		c <- q
+		select {
+		case c <- q:
+		case <-stop:
+			pool.Put(q)
+			return
+		}
 
 		// Queries counter
 		n++
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"sync"
 	"testing"
+	"time"
 )
 
 type testQuery struct {
@@ -66,7 +68,7 @@ func runScan(t *testing.T, b *bytes.Buffer, limit, numQueries uint64, pool *sync
 		wg.Done()
 	}()
 	input := bufio.NewReaderSize(bytes.NewReader(b.Bytes()), 1<<20)
-	scanner.setReader(input).scan(pool, queryChan)
+	scanner.setReader(input).scan(pool, queryChan, nil)
 	close(queryChan)
 	wg.Wait()
 	if got != numQueries {
@@ -150,3 +152,84 @@ func TestScanTimescaleDB(t *testing.T) {
 		return nil
 	})
 }
+
+func TestScannerStop(t *testing.T) {
+	var b bytes.Buffer
+	err := encodeQueries(&b, 7, func(i uint64) Query {
+		return &testQuery{
+			HumanLabel:       []byte("testlabel"),
+			HumanDescription: []byte("testDesc"),
+		}
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Nobody reads the queries, so the scanner blocks on the first one
+	// until it is stopped
+	limit := uint64(0)
+	queryChan := make(chan Query)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		newScanner(&limit).setReader(bytes.NewReader(b.Bytes())).scan(&testQueryPool, queryChan, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("scanner did not stop")
+	}
+}
+
+func TestScannerLoop(t *testing.T) {
+	var b bytes.Buffer
+	err := encodeQueries(&b, 3, func(i uint64) Query {
+		return &testQuery{
+			HumanLabel:       []byte(fmt.Sprintf("label%d", i)),
+			HumanDescription: []byte("testDesc"),
+		}
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	limit := uint64(7)
+	rewinds := 0
+	s := newScanner(&limit).setReader(bytes.NewReader(b.Bytes())).setRewind(func() (io.Reader, error) {
+		rewinds++
+		return bytes.NewReader(b.Bytes()), nil
+	})
+	queryChan := make(chan Query, limit)
+	s.scan(&testQueryPool, queryChan, nil)
+	close(queryChan)
+
+	i := 0
+	for q := range queryChan {
+		want := fmt.Sprintf("label%d", i%3)
+		if got := string(q.HumanLabelName()); got != want {
+			t.Errorf("query %d: incorrect label: got %s want %s", i, got, want)
+		}
+		if got := q.GetID(); got != uint64(i) {
+			t.Errorf("query %d: incorrect id: got %d", i, got)
+		}
+		i++
+	}
+	if i != int(limit) {
+		t.Errorf("incorrect number of queries: got %d want %d", i, limit)
+	}
+	if rewinds != 2 {
+		t.Errorf("incorrect number of rewinds: got %d want %d", rewinds, 2)
+	}
+}
+
+func TestScannerLoopEmptyInput(t *testing.T) {
+	limit := uint64(0)
+	s := newScanner(&limit).setReader(bytes.NewReader(nil)).setRewind(func() (io.Reader, error) {
+		t.Fatalf("empty input rewound")
+		return nil, nil
+	})
+	queryChan := make(chan Query, 1)
+	s.scan(&testQueryPool, queryChan, nil)
+}
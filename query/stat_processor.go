@@ -2,12 +2,17 @@ package query
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 // statProcessor is used to collect, analyze, and print query execution statistics.
@@ -15,8 +20,18 @@ type statProcessor interface {
 	getArgs() *statProcessorArgs
 	send(stats []*Stat)
 	sendWarm(stats []*Stat)
-	process(workers uint)
+	start(workers uint)
 	CloseAndWait()
+	getSummary() *statSummary
+}
+
+// statSummary holds the aggregated statistics of a finished run
+type statSummary struct {
+	queryCount       uint64                // queryCount is the number of queries counted after burn-in
+	overallQueryRate float64               // overallQueryRate is the number of queries per second over the whole run
+	statGroups       map[string]*statGroup // statGroups maps each label to its statistics
+	responseGroups   map[string]*statGroup // responseGroups maps each label to its response times, only in open-loop runs
+	failedCount      uint64                // failedCount is the number of failed queries
 }
 
 type statProcessorArgs struct {
@@ -25,6 +40,9 @@ type statProcessorArgs struct {
 	burnIn         uint64  // burnIn is the number of statistics to ignore before analyzing
 	printInterval  uint64  // printInterval is how often print intermediate stats (number of queries)
 	hdrLatenciesFile string // hdrLatenciesFile is the filename to Write the High Dynamic Range (HDR) Histogram of Response Latencies to
+	openLoop         bool   // openLoop tells the StatProcessor to also collect response times measured from the intended send time
+	percentiles      []float64 // percentiles are printed for every label in addition to the default statistics
+	hdrLatenciesDir  string    // hdrLatenciesDir is the directory to write one HDR Histogram of Response Latencies per label to
 
 }
 
@@ -34,6 +52,7 @@ type defaultStatProcessor struct {
 	wg   sync.WaitGroup
 	c    chan *Stat // c is the channel for Stats to be sent for processing
 	opsCount 	uint64
+	summary  *statSummary // summary is set once processing has finished
 }
 
 func newStatProcessor(args *statProcessorArgs) statProcessor {
@@ -68,11 +87,18 @@ func (sp *defaultStatProcessor) sendWarm(stats []*Stat) {
 	sp.send(stats)
 }
 
+// start creates the channel Stats are sent to and processes them in the
+// background until CloseAndWait is called. The channel exists once it
+// returns, so workers can send to it right away.
+func (sp *defaultStatProcessor) start(workers uint) {
+	sp.c = make(chan *Stat, workers)
+	sp.wg.Add(1)
+	go sp.process(workers)
+}
+
 // process collects latency results, aggregating them into summary
 // statistics. Optionally, they are printed to stderr at regular intervals.
 func (sp *defaultStatProcessor) process(workers uint) {
-	sp.c = make(chan *Stat, workers)
-	sp.wg.Add(1)
 	const allQueriesLabel = labelAllQueries
 	statMapping := map[string]*statGroup{
 		allQueriesLabel: newStatGroup(*sp.args.limit),
@@ -82,13 +108,32 @@ func (sp *defaultStatProcessor) process(workers uint) {
 		statMapping[labelColdQueries] = newStatGroup(*sp.args.limit)
 		statMapping[labelWarmQueries] = newStatGroup(*sp.args.limit)
 	}
+	// Only needed in open-loop runs, where response and service times differ
+	var responseMapping map[string]*statGroup
+	if sp.args.openLoop {
+		responseMapping = map[string]*statGroup{
+			allQueriesLabel: newStatGroup(*sp.args.limit),
+		}
+	}
 
 	i := uint64(0)
+	failed := uint64(0)
 	start := time.Now()
 	prevTime := start
 	prevRequestCount := uint64(0)
 
 	for stat := range sp.c {
+		// Failed queries are only counted, they have no latency
+		if len(stat.errorKind) > 0 {
+			if _, ok := statMapping[string(stat.label)]; !ok {
+				statMapping[string(stat.label)] = newStatGroup(*sp.args.limit)
+			}
+			statMapping[string(stat.label)].pushError(stat.errorKind)
+			statMapping[allQueriesLabel].pushError(stat.errorKind)
+			failed++
+			statPool.Put(stat)
+			continue
+		}
 		atomic.AddUint64(&sp.opsCount, 1)
 		if i < sp.args.burnIn {
 			i++
@@ -105,9 +150,18 @@ func (sp *defaultStatProcessor) process(workers uint) {
 		}
 
 		statMapping[string(stat.label)].push(stat.value)
+		if responseMapping != nil {
+			if _, ok := responseMapping[string(stat.label)]; !ok {
+				responseMapping[string(stat.label)] = newStatGroup(*sp.args.limit)
+			}
+			responseMapping[string(stat.label)].push(stat.responseTime)
+		}
 
 		if !stat.isPartial {
 			statMapping[allQueriesLabel].push(stat.value)
+			if responseMapping != nil {
+				responseMapping[allQueriesLabel].push(stat.responseTime)
+			}
 
 			// Only needed when differentiating between cold & warm
 			if sp.args.prewarmQueries {
@@ -144,7 +198,7 @@ func (sp *defaultStatProcessor) process(workers uint) {
 			if err != nil {
 				log.Fatal(err)
 			}
-			err = writeStatGroupMap(os.Stderr, statMapping)
+			err = writeStatGroupMap(os.Stderr, statMapping, sp.args.percentiles)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -163,25 +217,132 @@ func (sp *defaultStatProcessor) process(workers uint) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = writeStatGroupMap(os.Stdout, statMapping)
+	if failed > 0 {
+		_, err = fmt.Printf("%d queries failed\n", failed)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = writeStatGroupMap(os.Stdout, statMapping, sp.args.percentiles)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if responseMapping != nil {
+		_, err = fmt.Printf("Response times (from the intended send time, including queueing):\n")
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = writeStatGroupMap(os.Stdout, responseMapping, sp.args.percentiles)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	sp.summary = &statSummary{
+		queryCount:       i - sp.args.burnIn,
+		overallQueryRate: overallQueryRate,
+		statGroups:       statMapping,
+		responseGroups:   responseMapping,
+		failedCount:      failed,
+	}
 
 	if len(sp.args.hdrLatenciesFile) > 0  {
 		_, _ = fmt.Printf("Saving High Dynamic Range (HDR) Histogram of Response Latencies to %s\n", sp.args.hdrLatenciesFile)
 
-		d1 := []byte(statMapping[allQueriesLabel].latencyHDRHistogram.PercentilesPrint(10, 1000.0))
+		// Open-loop runs save the response times, which include queueing
+		hdrGroup := statMapping[allQueriesLabel]
+		if responseMapping != nil {
+			hdrGroup = responseMapping[allQueriesLabel]
+		}
+		d1 := []byte(hdrGroup.latencyHDRHistogram.PercentilesPrint(10, 1000.0))
 		err = ioutil.WriteFile(sp.args.hdrLatenciesFile, d1, 0644)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 	}
+	if len(sp.args.hdrLatenciesDir) > 0 {
+		_, _ = fmt.Printf("Saving High Dynamic Range (HDR) Histograms of Response Latencies per label to %s\n", sp.args.hdrLatenciesDir)
+		hdrGroups := statMapping
+		if responseMapping != nil {
+			hdrGroups = responseMapping
+		}
+		err = writeHDRLatenciesDir(sp.args.hdrLatenciesDir, hdrGroups)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	sp.wg.Done()
 }
 
+// writeHDRLatenciesDir writes the HDR Histogram percentiles of every label
+// to its own file in dir, named after the label (see hdrFileNames)
+func writeHDRLatenciesDir(dir string, statGroups map[string]*statGroup) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	labels := make([]string, 0, len(statGroups))
+	for label := range statGroups {
+		labels = append(labels, label)
+	}
+	names := hdrFileNames(labels)
+	for label, sg := range statGroups {
+		d := []byte(sg.latencyHDRHistogram.PercentilesPrint(10, 1000.0))
+		if err := ioutil.WriteFile(filepath.Join(dir, names[label]), d, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hdrFileNames returns the file name of every label. Labels whose names
+// collide, also on a case insensitive file system, get a short hash of the
+// label appended to their name.
+func hdrFileNames(labels []string) map[string]string {
+	byName := make(map[string][]string)
+	for _, label := range labels {
+		name := strings.ToLower(hdrFileName(label))
+		byName[name] = append(byName[name], label)
+	}
+	names := make(map[string]string, len(labels))
+	for _, colliding := range byName {
+		sort.Strings(colliding)
+		for _, label := range colliding {
+			name := hdrFileName(label)
+			if len(colliding) > 1 {
+				h := fnv.New32a()
+				h.Write([]byte(label))
+				name = fmt.Sprintf("%s-%08x.txt", strings.TrimSuffix(name, ".txt"), h.Sum32())
+			}
+			names[label] = name
+		}
+	}
+	return names
+}
+
+// hdrFileName turns a label into a file name, replacing runs of characters
+// other than letters, digits, '-' and '.' with '_'
+func hdrFileName(label string) string {
+	var b strings.Builder
+	replaced := false
+	for _, r := range label {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.') {
+			b.WriteRune(r)
+			replaced = false
+		} else if !replaced {
+			b.WriteRune('_')
+			replaced = true
+		}
+	}
+	return strings.Trim(b.String(), "_") + ".txt"
+}
+
+// getSummary returns the aggregated statistics. It is only valid after CloseAndWait has returned.
+func (sp *defaultStatProcessor) getSummary() *statSummary {
+	return sp.summary
+}
+
 // CloseAndWait closes the stats channel and blocks until the StatProcessor has finished all the stats on its channel.
 func (sp *defaultStatProcessor) CloseAndWait() {
 	close(sp.c)
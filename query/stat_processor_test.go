@@ -1,6 +1,10 @@
 package query
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -77,3 +81,125 @@ func TestStatProcessorSendWarm(t *testing.T) {
 		t.Errorf("empty stat array changed channel length: got %d want %d", got, wantLen)
 	}
 }
+
+func TestStatProcessorOpenLoop(t *testing.T) {
+	limit := uint64(0)
+	sp := newStatProcessor(&statProcessorArgs{limit: &limit, openLoop: true})
+	sp.start(1)
+	// wait for process to create its channel
+	time.Sleep(25 * time.Millisecond)
+	for _, v := range []float64{1.0, 2.0} {
+		s := GetStat().Init([]byte("foo"), v)
+		s.responseTime = v + 10
+		sp.send([]*Stat{s})
+	}
+	sp.CloseAndWait()
+
+	summary := sp.getSummary()
+	for _, label := range []string{"foo", labelAllQueries} {
+		if got := summary.statGroups[label].Max(); got != 2.0 {
+			t.Errorf("%s: incorrect max service time: got %f want %f", label, got, 2.0)
+		}
+		rg, ok := summary.responseGroups[label]
+		if !ok {
+			t.Fatalf("%s: missing response times", label)
+		}
+		if got := rg.Max(); got != 12.0 {
+			t.Errorf("%s: incorrect max response time: got %f want %f", label, got, 12.0)
+		}
+	}
+}
+
+func TestStatProcessorErrors(t *testing.T) {
+	limit := uint64(0)
+	sp := newStatProcessor(&statProcessorArgs{limit: &limit})
+	sp.start(1)
+	// wait for process to create its channel
+	time.Sleep(25 * time.Millisecond)
+	sp.send([]*Stat{GetStat().Init([]byte("foo"), 1.0)})
+	sp.send([]*Stat{getErrorStat([]byte("foo"), ErrorKindTimeout)})
+	sp.send([]*Stat{getErrorStat([]byte("bar"), ErrorKindServer)})
+	sp.CloseAndWait()
+
+	summary := sp.getSummary()
+	if summary.queryCount != 1 {
+		t.Errorf("failed queries counted as queries: got %d want %d", summary.queryCount, 1)
+	}
+	if summary.failedCount != 2 {
+		t.Errorf("incorrect failed count: got %d want %d", summary.failedCount, 2)
+	}
+	all := summary.statGroups[labelAllQueries]
+	if all.count != 1 || all.errorCount() != 2 {
+		t.Errorf("incorrect counts for all queries: got %d queries, %d errors", all.count, all.errorCount())
+	}
+	if got := summary.statGroups["bar"].errors[ErrorKindServer]; got != 1 {
+		t.Errorf("incorrect server errors for bar: got %d want %d", got, 1)
+	}
+}
+
+func TestStatProcessorHDRLatenciesDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	limit := uint64(0)
+	sp := newStatProcessor(&statProcessorArgs{limit: &limit, hdrLatenciesDir: dir, percentiles: []float64{99}})
+	sp.start(1)
+	// wait for process to create its channel
+	time.Sleep(25 * time.Millisecond)
+	sp.send([]*Stat{GetStat().Init([]byte("foo bar"), 1.0)})
+	sp.send([]*Stat{GetStat().Init([]byte("baz"), 2.0)})
+	sp.CloseAndWait()
+
+	for _, name := range []string{"foo_bar.txt", "baz.txt", "all_queries.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing HDR file %s: %v", name, err)
+		}
+	}
+}
+
+func TestHDRFileName(t *testing.T) {
+	cases := map[string]string{
+		"all queries": "all_queries.txt",
+		"TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m": "TimescaleDB_1_cpu_metric_s_random_1_hosts_random_1h0m0s_by_1m.txt",
+		"cpu-max-all-8": "cpu-max-all-8.txt",
+		"a/b":           "a_b.txt",
+	}
+	for label, want := range cases {
+		if got := hdrFileName(label); got != want {
+			t.Errorf("incorrect file name for %q: got %s want %s", label, got, want)
+		}
+	}
+}
+
+func TestHDRFileNames(t *testing.T) {
+	labels := []string{"cpu max/all", "cpu max, all", "CPU-max-all", "cpu-max-all", "lastpoint"}
+	names := hdrFileNames(labels)
+	if got := names["lastpoint"]; got != "lastpoint.txt" {
+		t.Errorf("incorrect file name of a label without collision: got %s want lastpoint.txt", got)
+	}
+	seen := make(map[string]string)
+	for _, label := range labels {
+		name := strings.ToLower(names[label])
+		if other, ok := seen[name]; ok {
+			t.Errorf("labels %q and %q share file name %s", label, other, names[label])
+		}
+		seen[name] = label
+	}
+	if got := names["cpu max/all"]; !strings.HasPrefix(got, "cpu_max_all-") {
+		t.Errorf("incorrect file name of a colliding label: got %s", got)
+	}
+
+	// The names do not depend on the order of the labels
+	reversed := make([]string, len(labels))
+	for i, label := range labels {
+		reversed[len(labels)-1-i] = label
+	}
+	for label, name := range hdrFileNames(reversed) {
+		if name != names[label] {
+			t.Errorf("file name of %q depends on the order: got %s want %s", label, name, names[label])
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"github.com/filipecosta90/hdrhistogram"
 )
@@ -19,6 +21,11 @@ type Stat struct {
 	value     float64
 	isWarm    bool
 	isPartial bool
+	// responseTime is the time from the intended send time until the
+	// query finished, only set in open-loop runs (see --arrival-rate)
+	responseTime float64
+	// errorKind is set if the query failed, the value is then meaningless
+	errorKind string
 }
 
 var statPool = &sync.Pool{
@@ -42,12 +49,21 @@ func GetPartialStat() *Stat {
 	return s
 }
 
+// getErrorStat returns a Stat from the pool recording a failed query
+func getErrorStat(label []byte, kind string) *Stat {
+	s := GetStat().Init(label, 0.0)
+	s.errorKind = kind
+	return s
+}
+
 // Init safely initializes a Stat while minimizing heap allocations.
 func (s *Stat) Init(label []byte, value float64) *Stat {
 	s.label = s.label[:0] // clear
 	s.label = append(s.label, label...)
 	s.value = value
 	s.isWarm = false
+	s.responseTime = 0.0
+	s.errorKind = ""
 	return s
 }
 
@@ -56,6 +72,8 @@ func (s *Stat) reset() *Stat {
 	s.value = 0.0
 	s.isWarm = false
 	s.isPartial = false
+	s.responseTime = 0.0
+	s.errorKind = ""
 	return s
 }
 
@@ -64,6 +82,8 @@ type statGroup struct {
 	latencyHDRHistogram *hdrhistogram.Histogram
 	sum    float64
 	count int64
+	// errors counts the failed queries by error kind, nil if none failed
+	errors map[string]int64
 }
 
 // newStatGroup returns a new StatGroup with an initial size
@@ -89,9 +109,26 @@ func (s *statGroup) push(n float64) {
 	s.count++
 }
 
+// pushError counts a failed query of the given error kind.
+func (s *statGroup) pushError(kind string) {
+	if s.errors == nil {
+		s.errors = make(map[string]int64)
+	}
+	s.errors[kind]++
+}
+
+// errorCount returns the number of failed queries of all kinds.
+func (s *statGroup) errorCount() int64 {
+	var n int64
+	for _, c := range s.errors {
+		n += c
+	}
+	return n
+}
+
 // string makes a simple description of a statGroup.
 func (s *statGroup) string() string {
-	return fmt.Sprintf("min: %8.2fms, med: %8.2fms, mean: %8.2fms, max: %7.2fms, stddev: %8.2fms, sum: %5.1fsec, count: %d",
+	str := fmt.Sprintf("min: %8.2fms, med: %8.2fms, mean: %8.2fms, max: %7.2fms, stddev: %8.2fms, sum: %5.1fsec, count: %d",
 		s.Min(),
 		s.Median(),
 		s.Mean(),
@@ -99,13 +136,62 @@ func (s *statGroup) string() string {
 		s.StdDev(),
 		s.sum/hdrScaleFactor,
 		s.count)
+	if len(s.errors) > 0 {
+		kinds := make([]string, 0, len(s.errors))
+		for kind := range s.errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		str += ", errors:"
+		for _, kind := range kinds {
+			str += fmt.Sprintf(" %s %d", kind, s.errors[kind])
+		}
+	}
+	return str
 }
 
-func (s *statGroup) write(w io.Writer) error {
+// percentilesString describes the given percentiles of a statGroup.
+func (s *statGroup) percentilesString(percentiles []float64) string {
+	parts := make([]string, len(percentiles))
+	for i, p := range percentiles {
+		parts[i] = fmt.Sprintf("p%s: %8.2fms", formatPercentile(p), s.Percentile(p))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// write writes the description of a statGroup, followed by a line with the
+// given percentiles, if any.
+func (s *statGroup) write(w io.Writer, percentiles []float64) error {
 	_, err := fmt.Fprintln(w, s.string())
+	if err != nil || len(percentiles) == 0 {
+		return err
+	}
+	_, err = fmt.Fprintln(w, s.percentilesString(percentiles))
 	return err
 }
 
+// formatPercentile formats a percentile without trailing zeros, e.g. 99.9
+func formatPercentile(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// parsePercentiles parses a comma-separated list of percentiles in (0, 100],
+// e.g. "50,90,95,99,99.9".
+func parsePercentiles(s string) ([]float64, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return nil, nil
+	}
+	var percentiles []float64
+	for _, part := range strings.Split(s, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q: must be a number in (0, 100]", part)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
 // Median returns the Median value of the StatGroup in milliseconds
 func (s *statGroup) Median() float64 {
 	return float64(s.latencyHDRHistogram.ValueAtQuantile(50.0))/ hdrScaleFactor
@@ -116,6 +202,11 @@ func (s *statGroup) Mean() float64 {
 	return float64(s.latencyHDRHistogram.Mean())/ hdrScaleFactor
 }
 
+// Percentile returns the value at the given percentile (0-100] of the StatGroup in milliseconds
+func (s *statGroup) Percentile(p float64) float64 {
+	return float64(s.latencyHDRHistogram.ValueAtQuantile(p)) / hdrScaleFactor
+}
+
 // Max returns the Max value of the StatGroup in milliseconds
 func (s *statGroup) Max() float64 {
 	return float64(s.latencyHDRHistogram.Max())/ hdrScaleFactor
@@ -132,8 +223,8 @@ func (s *statGroup) StdDev() float64 {
 }
 
 // writeStatGroupMap writes a map of StatGroups in an ordered fashion by
-// key that they are stored by
-func writeStatGroupMap(w io.Writer, statGroups map[string]*statGroup) error {
+// key that they are stored by, each with the given percentiles
+func writeStatGroupMap(w io.Writer, statGroups map[string]*statGroup, percentiles []float64) error {
 	maxKeyLength := 0
 	keys := make([]string, 0, len(statGroups))
 	for k := range statGroups {
@@ -155,7 +246,7 @@ func writeStatGroupMap(w io.Writer, statGroups map[string]*statGroup) error {
 			return err
 		}
 
-		err = v.write(w)
+		err = v.write(w, percentiles)
 		if err != nil {
 			return err
 		}
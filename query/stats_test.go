@@ -264,7 +264,7 @@ func (w *errWriter) Write(p []byte) (int, error) {
 func TestWrite(t *testing.T) {
 	var buf bytes.Buffer
 	sg := newStatGroup(0)
-	err := sg.write(&buf)
+	err := sg.write(&buf, nil)
 	if err != nil {
 		t.Errorf("unexpected error for write: %v", err)
 	}
@@ -275,12 +275,76 @@ func TestWrite(t *testing.T) {
 	}
 
 	// Test error case
-	err = sg.write(&errWriter{})
+	err = sg.write(&errWriter{}, nil)
 	if err == nil {
 		t.Errorf("expected error but did not get one")
 	}
 }
 
+func TestWritePercentiles(t *testing.T) {
+	sg := newStatGroup(0)
+	for i := 1; i <= 100; i++ {
+		sg.push(float64(i))
+	}
+	var buf bytes.Buffer
+	if err := sg.write(&buf, []float64{50, 99, 99.9}); err != nil {
+		t.Fatalf("unexpected error for write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("incorrect number of lines: got %d want %d", len(lines), 2)
+	}
+	want := "p50:    50.00ms, p99:    99.00ms, p99.9:   100.00ms"
+	if lines[1] != want {
+		t.Errorf("incorrect percentiles:\ngot\n%s\nwant\n%s", lines[1], want)
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	got, err := parsePercentiles("50, 90,99.9")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []float64{50, 90, 99.9}
+	if len(got) != len(want) {
+		t.Fatalf("incorrect percentiles: got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("incorrect percentile %d: got %f want %f", i, got[i], want[i])
+		}
+	}
+
+	if got, err := parsePercentiles(""); err != nil || got != nil {
+		t.Errorf("unexpected result for no percentiles: %v, %v", got, err)
+	}
+	for _, s := range []string{"0", "101", "p99", "50,"} {
+		if _, err := parsePercentiles(s); err == nil {
+			t.Errorf("unexpected lack of error for %q", s)
+		}
+	}
+}
+
+func TestStatGroupErrors(t *testing.T) {
+	sg := newStatGroup(0)
+	if strings.Contains(sg.string(), "errors") {
+		t.Errorf("errors shown without failed queries: %s", sg.string())
+	}
+	sg.pushError(ErrorKindTimeout)
+	sg.pushError(ErrorKindServer)
+	sg.pushError(ErrorKindTimeout)
+	if got := sg.errorCount(); got != 3 {
+		t.Errorf("incorrect error count: got %d want %d", got, 3)
+	}
+	if sg.count != 0 {
+		t.Errorf("errors counted as queries: got %d want %d", sg.count, 0)
+	}
+	want := ", errors: server 1 timeout 2"
+	if got := sg.string(); !strings.HasSuffix(got, want) {
+		t.Errorf("incorrect errors in description: got %s want suffix %s", got, want)
+	}
+}
+
 func TestWriteStatGroupMap(t *testing.T) {
 	cases := []struct {
 		desc           string
@@ -340,7 +404,7 @@ func TestWriteStatGroupMap(t *testing.T) {
 		} else {
 			w = bytes.NewBuffer([]byte{})
 		}
-		err := writeStatGroupMap(w, m)
+		err := writeStatGroupMap(w, m, nil)
 		if shouldErr {
 			ew := w.(*errWriter)
 			if err == nil {
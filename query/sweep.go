@@ -0,0 +1,118 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// parseWorkersSweep parses the comma-separated worker counts of
+// --workers-sweep, returning nil if s is empty
+func parseWorkersSweep(s string) ([]uint, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return nil, nil
+	}
+	var levels []uint
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid workers-sweep %q: worker counts must be positive integers", s)
+		}
+		levels = append(levels, uint(n))
+	}
+	return levels, nil
+}
+
+// runSweep runs all queries once for every worker count of the sweep, each
+// time reading the input from the start, and prints a summary table of all
+// levels at the end. An interrupt ends the sweep after the current level.
+func (b *BenchmarkRunner) runSweep(sweep []uint, queryPool *sync.Pool, processorCreateFn ProcessorCreate) {
+	if len(b.FileName) == 0 {
+		data, err := ioutil.ReadAll(b.GetBufferedReader())
+		if err != nil {
+			panic(fmt.Sprintf("cannot read queries from STDIN: %v", err))
+		}
+		b.stdinQueries = data
+	}
+
+	var levels []SweepLevel
+	var sweepStart, sweepEnd time.Time
+	for i, workers := range sweep {
+		if i > 0 {
+			b.resetForLevel()
+		}
+		b.Workers = workers
+		if len(b.FileName) == 0 {
+			b.br = bufio.NewReaderSize(bytes.NewReader(b.stdinQueries), defaultReadSize)
+		}
+		fmt.Printf("Workers sweep: running with %d workers\n", workers)
+		start, end := b.runOnce(queryPool, processorCreateFn)
+		if i == 0 {
+			sweepStart = start
+		}
+		sweepEnd = end
+		levels = append(levels, b.newSweepLevel(start, end))
+		if b.interruptedBy != nil {
+			break
+		}
+	}
+
+	fmt.Println("Workers sweep summary:")
+	if err := writeSweepTable(os.Stdout, levels); err != nil {
+		panic(err.Error())
+	}
+
+	if len(b.ResultsFile) > 0 {
+		res := b.newQueryTestResult(sweepStart, sweepEnd)
+		res.WorkersSweep = levels
+		b.writeTestResult(res)
+	}
+}
+
+// resetForLevel prepares the runner for the next level of a workers sweep:
+// the queries are read again from the start and statistics start over.
+func (b *BenchmarkRunner) resetForLevel() {
+	b.sp = newStatProcessor(b.sp.getArgs())
+	b.scanner = newScanner(&b.Limit)
+	b.br = nil
+	b.stopOnce = sync.Once{}
+	b.stopReason = ""
+	b.resources = nil
+	if b.ArrivalRate > 0 {
+		// The schedule was validated by Run and starts at the first query
+		b.arrivals, _ = newArrivalSchedule(b.ArrivalRate, b.ArrivalDistribution)
+	}
+}
+
+// newSweepLevel summarizes the level of a workers sweep that just finished
+func (b *BenchmarkRunner) newSweepLevel(start, end time.Time) SweepLevel {
+	res := b.newQueryTestResult(start, end)
+	return SweepLevel{
+		Workers:       b.Workers,
+		WallClockTime: res.WallClockTime,
+		StopReason:    res.StopReason,
+		Totals:        res.Totals,
+		Labels:        res.Labels,
+	}
+}
+
+// writeSweepTable writes the throughput and the latencies of all queries for
+// every level of a workers sweep as a table
+func writeSweepTable(w io.Writer, levels []SweepLevel) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workers\tqueries/sec\tmean ms\tp50 ms\tp95 ms\tp99 ms\tp99.9 ms\tfailed\t")
+	for _, l := range levels {
+		all := l.Labels[labelAllQueries]
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%d\t\n",
+			l.Workers, l.Totals.OverallQueryRate, all.Mean, all.P50, all.P95, all.P99, all.P999, l.Totals.FailedQueries)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseWorkersSweep(t *testing.T) {
+	levels, err := parseWorkersSweep("1, 2,4,8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []uint{1, 2, 4, 8}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("incorrect levels: got %v want %v", levels, want)
+	}
+	if levels, err := parseWorkersSweep(""); err != nil || levels != nil {
+		t.Errorf("unexpected levels for empty sweep: %v, %v", levels, err)
+	}
+	for _, s := range []string{"1,0", "1,,2", "two", "-1"} {
+		if _, err := parseWorkersSweep(s); err == nil {
+			t.Errorf("unexpected lack of error for %q", s)
+		}
+	}
+}
+
+// sweepProcessor records the workers it was started as and returns one stat
+// of 1ms for every query
+type sweepProcessor struct {
+	mu      *sync.Mutex
+	workers map[int]bool
+}
+
+func (p *sweepProcessor) Init(workerNum int) {
+	p.mu.Lock()
+	p.workers[workerNum] = true
+	p.mu.Unlock()
+}
+
+func (p *sweepProcessor) ProcessQuery(q Query, _ bool) ([]*Stat, error) {
+	return []*Stat{GetStat().Init(q.HumanLabelName(), 1)}, nil
+}
+
+func TestRunWorkersSweep(t *testing.T) {
+	const queries = 10
+	var input bytes.Buffer
+	err := encodeQueries(&input, queries, func(i uint64) Query {
+		return &testQuery{HumanLabel: []byte("label")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultsFile, err := ioutil.TempFile("", "sweep_results*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultsFile.Close()
+	defer os.Remove(resultsFile.Name())
+
+	b := NewBenchmarkRunner(BenchmarkRunnerConfig{
+		Workers:      1,
+		WorkersSweep: "1,3",
+		ResultsFile:  resultsFile.Name(),
+	})
+	// The cached reader stands in for STDIN, which is read only once
+	b.br = bufio.NewReader(bytes.NewReader(input.Bytes()))
+	p := &sweepProcessor{mu: &sync.Mutex{}, workers: make(map[int]bool)}
+	b.Run(&testQueryPool, func() Processor { return p })
+
+	if len(p.workers) != 3 {
+		t.Errorf("incorrect number of workers started: got %d want %d", len(p.workers), 3)
+	}
+	if b.Workers != 3 {
+		t.Errorf("incorrect workers after sweep: got %d want %d", b.Workers, 3)
+	}
+
+	data, err := ioutil.ReadFile(resultsFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res QueryTestResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.WorkersSweep) != 2 {
+		t.Fatalf("incorrect number of sweep levels: got %d want %d", len(res.WorkersSweep), 2)
+	}
+	for i, workers := range []uint{1, 3} {
+		level := res.WorkersSweep[i]
+		if level.Workers != workers || level.Totals.Workers != workers {
+			t.Errorf("level %d: incorrect workers: got %d/%d want %d", i, level.Workers, level.Totals.Workers, workers)
+		}
+		if level.Totals.QueryCount != queries {
+			t.Errorf("level %d: incorrect query count: got %d want %d", i, level.Totals.QueryCount, queries)
+		}
+		if got := level.Labels["label"].Count; got != queries {
+			t.Errorf("level %d: incorrect label count: got %d want %d", i, got, queries)
+		}
+	}
+	if res.Totals.Workers != 3 {
+		t.Errorf("incorrect workers of the last level: got %d want %d", res.Totals.Workers, 3)
+	}
+}
+
+func TestBenchmarkRunnerRunPanicOnBadWorkersSweep(t *testing.T) {
+	runner := &BenchmarkRunner{
+		BenchmarkRunnerConfig: BenchmarkRunnerConfig{
+			Workers:      1,
+			WorkersSweep: "1,0",
+		},
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "workers-sweep") {
+			t.Errorf("wrong panic: %v", r)
+		}
+	}()
+	runner.Run(nil, nil)
+	t.Errorf("the code did not panic")
+}
+
+func TestWriteSweepTable(t *testing.T) {
+	levels := []SweepLevel{
+		{
+			Workers: 1,
+			Totals:  QueryTotals{OverallQueryRate: 100},
+			Labels:  map[string]LatencySummary{labelAllQueries: {Mean: 10, P50: 9, P95: 15, P99: 20, P999: 30}},
+		},
+		{
+			Workers: 16,
+			Totals:  QueryTotals{OverallQueryRate: 1200.5, FailedQueries: 2},
+			Labels:  map[string]LatencySummary{labelAllQueries: {Mean: 13.25, P50: 12, P95: 25, P99: 40, P999: 80}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeSweepTable(&buf, levels); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "  workers  queries/sec  mean ms  p50 ms  p95 ms  p99 ms  p99.9 ms  failed\n" +
+		"        1       100.00    10.00    9.00   15.00   20.00     30.00       0\n" +
+		"       16      1200.50    13.25   12.00   25.00   40.00     80.00       2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect table:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -21,7 +21,8 @@ until curl http://${DATABASE_HOST}:${QUERY_PORT}/api/stats 2>/dev/null; do
 done
 
 # Load new data
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --workers=${NUM_WORKERS} \
                                 --batch-size=${BATCH_SIZE} \
                                 --endpoint=${DATABASE_HOST}:${INGESTION_PORT}
@@ -25,7 +25,8 @@ while ! nc -z ${DATABASE_HOST} ${DATABASE_PORT}; do
 done
 
 cqlsh -e 'drop keyspace measurements;'
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --workers=${NUM_WORKERS} \
                                 --batch-size=${BATCH_SIZE} \
                                 --reporting-period=${REPORTING_PERIOD} \
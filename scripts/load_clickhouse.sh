@@ -19,7 +19,8 @@ HASH_WORKERS=${HASH_WORKERS:-false}
 EXE_DIR=${EXE_DIR:-$(dirname $0)}
 source ${EXE_DIR}/load_common.sh
 
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --host=${DATABASE_HOST} \
                                 --port=${DATABASE_PORT} \
                                 --user=${DATABASE_USER} \
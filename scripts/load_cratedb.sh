@@ -26,7 +26,8 @@ while ! nc -z ${DATABASE_HOST} ${DATABASE_PORT}; do
     sleep 1
 done
 
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --hosts=${DATABASE_HOST} \
                                 --port=${DATABASE_PORT} \
                                 --user=${USER} \
@@ -22,7 +22,8 @@ done
 # Remove previous database
 curl -X POST http://${DATABASE_HOST}:${DATABASE_PORT}/query?q=drop%20database%20${DATABASE_NAME}
 # Load new data
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --db-name=${DATABASE_NAME} \
                                 --backoff=${BACKOFF_SECS} \
                                 --workers=${NUM_WORKERS} \
@@ -48,7 +48,8 @@ until nc -z ${DATABASE_HOST} ${DATABASE_PORT}; do
     sleep 1
 done
 
-cat ${DATA_FILE} | gunzip | $EXE_FILE_NAME \
+$EXE_FILE_NAME \
+                                --file=${DATA_FILE} \
                                 --db-name=${DATABASE_NAME} \
                                 --hosts=${DATABASE_HOST}:${DATABASE_PORT} \
                                 --dbuser=${DATABASE_USER} \